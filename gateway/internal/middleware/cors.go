@@ -20,6 +20,8 @@ func CORS() gin.HandlerFunc {
 		"Authorization",
 		"X-Requested-With",
 		"X-Gateway",
+		"If-Match",
+		"Accept-Language",
 	}
 	
 	
@@ -39,6 +41,8 @@ func CORS() gin.HandlerFunc {
 		"Content-Length",
 		"X-Gateway",
 		"X-Forwarded-By",
+		"ETag",
+		"Content-Language",
 	}
 	
 	return cors.New(config)
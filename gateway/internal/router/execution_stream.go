@@ -0,0 +1,169 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "gateway/proto/compiled"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// sseHeartbeatInterval keeps idle event streams open through proxies.
+const sseHeartbeatInterval = 15 * time.Second
+
+// handleExecutionEvents bridges the tours-service execution stream to Server-Sent
+// Events. Positions are reported with POST /execution/position/:tour_id; this stream
+// only pushes the events. Event IDs are "<executionId>-<eventId>", so a reconnecting
+// EventSource resumes through its Last-Event-ID header (or ?lastEventId=).
+func (r *Router) handleExecutionEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, userIDExists := c.Get("user_id")
+		userRole, userRoleExists := c.Get("role")
+
+		if !userIDExists || !userRoleExists {
+			log.Error().Msg("User data missing in context after JWT authentication")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication data not found"})
+			return
+		}
+
+		if userRole != "Tourist" {
+			log.Warn().Str("user_id", fmt.Sprintf("%v", userID)).Msg("Unauthorized access: user is not a TOURIST")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only tourists can follow tour executions"})
+			return
+		}
+
+		uidInt, err := strconv.Atoi(fmt.Sprintf("%v", userID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user_id format"})
+			return
+		}
+
+		tourID, err := strconv.Atoi(c.Param("tour_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tour ID format"})
+			return
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("lastEventId")
+		}
+		lastExecutionID, lastSeq := parseExecutionEventID(lastEventID)
+
+		stream, err := r.toursClient.StreamExecution(c.Request.Context())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to open StreamExecution via gRPC")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to open execution stream"})
+			return
+		}
+		err = stream.Send(&pb.ExecutionStreamRequest{Payload: &pb.ExecutionStreamRequest_Subscribe{Subscribe: &pb.ExecutionSubscribe{
+			TourId:          int32(tourID),
+			UserId:          int32(uidInt),
+			LastExecutionId: int32(lastExecutionID),
+			LastEventId:     lastSeq,
+		}}})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to subscribe to execution stream")
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to open execution stream"})
+			return
+		}
+
+		// The server write timeout would cut the stream off
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn().Err(err).Msg("Failed to clear write deadline for event stream")
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		events := make(chan *pb.ExecutionEvent)
+		recvErr := make(chan error, 1)
+		go func() {
+			for {
+				event, err := stream.Recv()
+				if err != nil {
+					recvErr <- err
+					return
+				}
+				select {
+				case events <- event:
+				case <-c.Request.Context().Done():
+					return
+				}
+			}
+		}()
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case err := <-recvErr:
+				if err != io.EOF {
+					log.Warn().Err(err).Int("tour_id", tourID).Msg("Execution stream ended")
+					writeSSE(c, "", "error", gin.H{"message": err.Error()})
+				}
+				return
+			case event := <-events:
+				id := ""
+				if event.Id != 0 {
+					id = fmt.Sprintf("%d-%d", event.ExecutionId, event.Id)
+				}
+				writeSSE(c, id, event.Type, gin.H{
+					"executionId":  event.ExecutionId,
+					"keypointId":   event.KeypointId,
+					"keypointName": event.KeypointName,
+					"distance":     event.Distance,
+					"message":      event.Message,
+					"createdAt":    event.CreatedAt,
+				})
+			case <-heartbeat.C:
+				fmt.Fprint(c.Writer, ": heartbeat\n\n")
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+func writeSSE(c *gin.Context, id, event string, data gin.H) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	if id != "" {
+		fmt.Fprintf(c.Writer, "id: %s\n", id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	c.Writer.Flush()
+}
+
+// parseExecutionEventID splits an SSE event ID of the form "<executionId>-<eventId>".
+// Anything else means no event was received yet.
+func parseExecutionEventID(id string) (int, int64) {
+	executionPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0
+	}
+	executionID, err := strconv.Atoi(executionPart)
+	if err != nil {
+		return 0, 0
+	}
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	return executionID, seq
+}
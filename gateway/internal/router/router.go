@@ -86,32 +86,79 @@ func (r *Router) setupRoutes() {
 		toursGroup := api.Group("/tours")
 		{
 			toursGroup.POST("/create", r.handleServiceRequest("tours"))
+			toursGroup.POST("/import", r.handleServiceRequest("tours"))
 			toursGroup.GET("/my-tours", r.handleServiceRequest("tours"))
+			toursGroup.GET("/my-tours/deleted", r.handleServiceRequest("tours"))
 			toursGroup.GET("/:tourId", r.handleServiceRequest("tours"))
 			toursGroup.GET("/get-published", r.handleServiceRequest("tours"))
 			toursGroup.PUT("/:tourId", r.handleServiceRequest("tours"))
+			toursGroup.PATCH("/:tourId", r.handleServiceRequest("tours"))
 			toursGroup.DELETE("/:tourId", r.handleServiceRequest("tours"))
 			toursGroup.POST("/:tourId/publish", r.handleServiceRequest("tours"))
 			toursGroup.POST("/:tourId/archive", r.handleServiceRequest("tours"))
+			toursGroup.POST("/:tourId/restore", r.handleServiceRequest("tours"))
+			toursGroup.POST("/:tourId/clone", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/:tourId/schedule", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/translations", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/:tourId/translations/:lang", r.handleServiceRequest("tours"))
+			toursGroup.DELETE("/:tourId/translations/:lang", r.handleServiceRequest("tours"))
 			toursGroup.POST("/:tourId/set-price", r.handleServiceRequest("tours"))
 			toursGroup.GET("/:tourId/tourist-view", r.handleServiceRequest("tours"))
 			toursGroup.GET("/:tourId/purchased-keypoints", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/export", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/route", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/analytics", r.handleServiceRequest("tours"))
+			toursGroup.POST("/:tourId/purchased-keypoints/upgrade", r.handleServiceRequest("tours"))
+
+			toursGroup.GET("/:tourId/revisions", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/revisions/diff", r.handleServiceRequest("tours"))
+			toursGroup.GET("/:tourId/revisions/:revision", r.handleServiceRequest("tours"))
+			toursGroup.POST("/:tourId/revisions/:revision/restore", r.handleServiceRequest("tours"))
 
 			toursGroup.POST("/:tourId/create-keypoint", r.handleServiceRequest("tours"))
 			toursGroup.GET("/:tourId/keypoints", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/:tourId/keypoints/order", r.handleServiceRequest("tours"))
 			toursGroup.GET("/keypoints/:keypointId", r.handleServiceRequest("tours"))
 			toursGroup.PUT("/keypoints/:keypointId", r.handleServiceRequest("tours"))
+			toursGroup.PATCH("/keypoints/:keypointId", r.handleServiceRequest("tours"))
 			toursGroup.DELETE("/keypoints/:keypointId", r.handleServiceRequest("tours"))
 			toursGroup.POST("/keypoints/:keypointId/upload-image", r.handleServiceRequest("tours"))
+			toursGroup.GET("/keypoints/:keypointId/media", r.handleServiceRequest("tours"))
+			toursGroup.POST("/keypoints/:keypointId/media", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/keypoints/:keypointId/media/order", r.handleServiceRequest("tours"))
+			toursGroup.DELETE("/keypoints/:keypointId/media/:mediaId", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/keypoints/:keypointId/challenge", r.handleServiceRequest("tours"))
+			toursGroup.DELETE("/keypoints/:keypointId/challenge", r.handleServiceRequest("tours"))
 
 			toursGroup.POST("/reviews", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/reviews/:reviewId", r.handleServiceRequest("tours"))
+			toursGroup.DELETE("/reviews/:reviewId", r.handleServiceRequest("tours"))
+			toursGroup.PUT("/reviews/:reviewId/reply", r.handleServiceRequest("tours"))
+			toursGroup.DELETE("/reviews/:reviewId/reply", r.handleServiceRequest("tours"))
+			toursGroup.POST("/reviews/:reviewId/report", r.handleServiceRequest("tours"))
 			toursGroup.GET("/:tourId/reviews", r.handleServiceRequest("tours"))
 
 			toursGroup.POST("/execution/start/:tour_id", r.handleServiceRequest("tours"))
 			toursGroup.POST("/execution/abort/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.POST("/execution/resume/:tour_id", r.handleServiceRequest("tours"))
 			toursGroup.GET("/execution/tour/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.GET("/execution/history/:tour_id", r.handleServiceRequest("tours"))
 			toursGroup.GET("/execution/my-executions", r.handleServiceRequest("tours"))
 			toursGroup.POST("/execution/is-keypoint-reached/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.POST("/execution/challenge/:tour_id/:keypoint_id", r.handleServiceRequest("tours"))
+			toursGroup.GET("/execution/on-route/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.GET("/execution/by-tour/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.GET("/execution/trail/:execution_id", r.handleServiceRequest("tours"))
+			toursGroup.POST("/execution/position/:tour_id", r.handleServiceRequest("tours"))
+			toursGroup.GET("/execution/events/:tour_id", r.handleExecutionEvents())
+
+			toursGroup.GET("/admin/jobs", r.handleServiceRequest("tours"))
+			toursGroup.GET("/admin/jobs/:jobId", r.handleServiceRequest("tours"))
+			toursGroup.POST("/admin/jobs/:jobId/retry", r.handleServiceRequest("tours"))
+			toursGroup.GET("/admin/reviews", r.handleServiceRequest("tours"))
+			toursGroup.POST("/admin/reviews/:reviewId/hide", r.handleServiceRequest("tours"))
+			toursGroup.POST("/admin/reviews/:reviewId/restore", r.handleServiceRequest("tours"))
+			toursGroup.POST("/admin/reviews/:reviewId/dismiss", r.handleServiceRequest("tours"))
 		}
 
 		// Purchase service routes - IZVAN toursGroup!
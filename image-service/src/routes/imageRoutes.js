@@ -8,13 +8,19 @@ const router = express.Router();
 const uploadDir = path.join(__dirname, '../../uploads/ProfilePictures');
 const reviewUploadDir = path.join(__dirname, '../../uploads/TourReviewPictures');
 const keypointUploadDir = path.join(__dirname, '../../uploads/KeypointPictures');
+const keypointMediaDir = path.join(__dirname, '../../uploads/KeypointMedia');
+const challengeUploadDir = path.join(__dirname, '../../uploads/ChallengePhotos');
 //const uploadDir = path.join(__dirname, '../../uploads/images');
 
 if (!fs.existsSync(uploadDir)) fs.mkdirSync(uploadDir, { recursive: true });
 if (!fs.existsSync(reviewUploadDir)) fs.mkdirSync(reviewUploadDir, { recursive: true });
 if (!fs.existsSync(keypointUploadDir)) fs.mkdirSync(keypointUploadDir, { recursive: true });
+if (!fs.existsSync(keypointMediaDir)) fs.mkdirSync(keypointMediaDir, { recursive: true });
+if (!fs.existsSync(challengeUploadDir)) fs.mkdirSync(challengeUploadDir, { recursive: true });
 
 const upload = multer({ storage: multer.memoryStorage() });
+// Keypoint media includes video, tours-service enforces the limits per media type
+const mediaUpload = multer({ storage: multer.memoryStorage(), limits: { fileSize: 100 * 1024 * 1024 } });
 
 router.post('/save-image', upload.single('image'), (req, res) => {
     if (!req.file) {
@@ -113,4 +119,73 @@ router.get('/img/keypoint/:filename', (req, res) => {
     res.sendFile(filePath);
 });
 
+// Keypoint gallery upload endpoint (images, audio and video)
+router.post('/saveKeypointMedia', mediaUpload.single('file'), (req, res) => {
+    if (!req.file) {
+        return res.status(400).json({ error: 'No file uploaded' });
+    }
+
+    const tourId = req.body.tourId || 'unknown_tour';
+    const keypointId = req.body.keypointId || 'unknown_keypoint';
+    const type = req.body.type || 'media';
+    const ext = path.extname(req.file.originalname);
+
+    const filename = `tour-${tourId}-keypoint-${keypointId}-${type}-${uuidv4()}${ext}`;
+    const filePath = path.join(keypointMediaDir, filename);
+
+    fs.writeFileSync(filePath, req.file.buffer);
+
+    res.status(201).json({
+        message: 'Keypoint media saved successfully',
+        mediaURL: `http://localhost:3001/api/media/keypoint/${filename}`,
+        mediaName: filename
+    });
+});
+
+// Keypoint media retrieval endpoint, sendFile supports range requests for audio and video seeking
+router.get('/media/keypoint/:filename', (req, res) => {
+    const filename = path.basename(req.params.filename);
+    const filePath = path.join(keypointMediaDir, filename);
+
+    if (!fs.existsSync(filePath)) {
+        return res.status(404).json({ error: 'File not found' });
+    }
+
+    res.sendFile(filePath);
+});
+
+// Photo taken by a tourist to solve a keypoint challenge
+router.post('/saveChallengePhoto', upload.single('image'), (req, res) => {
+    if (!req.file) {
+        return res.status(400).json({ error: 'No file uploaded' });
+    }
+
+    const keypointId = req.body.keypointId || 'unknown_keypoint';
+    const userId = req.body.userId || 'unknown_user';
+    const ext = path.extname(req.file.originalname);
+
+    const filename = `keypoint-${keypointId}-user-${userId}-${uuidv4()}${ext}`;
+    const filePath = path.join(challengeUploadDir, filename);
+
+    fs.writeFileSync(filePath, req.file.buffer);
+
+    res.status(201).json({
+        message: 'Challenge photo saved successfully',
+        photoURL: `http://localhost:3001/api/img/challenge/${filename}`,
+        photoName: filename
+    });
+});
+
+// Challenge photo retrieval endpoint
+router.get('/img/challenge/:filename', (req, res) => {
+    const filename = path.basename(req.params.filename);
+    const filePath = path.join(challengeUploadDir, filename);
+
+    if (!fs.existsSync(filePath)) {
+        return res.status(404).json({ error: 'File not found' });
+    }
+
+    res.sendFile(filePath);
+});
+
 module.exports = router;
\ No newline at end of file
@@ -1,8 +1,12 @@
 const axios = require('axios');
+const polyline = require('../utils/polyline');
 const API_KEY = process.env.MAP_API_KEY;
 
 const VALID_PROFILES = ['driving-car', 'foot-walking', 'cycling-regular'];
 
+// OpenRouteService accepts at most 50 waypoints per directions request
+const MAX_WAYPOINTS = 50;
+
 const getDistance = async (req, res) => {
   try {
     const { originLat, originLng, destLat, destLng } = req.query;
@@ -13,16 +17,16 @@ const getDistance = async (req, res) => {
 
     const results = {};
 
+    // The directions endpoint also returns the route itself as an encoded polyline (precision 5)
     for (const profile of VALID_PROFILES) {
-      const url = `https://api.openrouteservice.org/v2/matrix/${profile}`;
+      const url = `https://api.openrouteservice.org/v2/directions/${profile}`;
       const response = await axios.post(
         url,
         {
-          locations: [
+          coordinates: [
             [parseFloat(originLng), parseFloat(originLat)],
             [parseFloat(destLng), parseFloat(destLat)]
-          ],
-          metrics: ['distance', 'duration']
+          ]
         },
         {
           headers: {
@@ -32,10 +36,11 @@ const getDistance = async (req, res) => {
         }
       );
 
-      const distance = response.data.distances[0][1]; // meters
-      const duration = response.data.durations[0][1]; // seconds
+      const route = response.data.routes[0];
+      const distance = route.summary.distance ?? 0; // meters
+      const duration = route.summary.duration ?? 0; // seconds
 
-      results[profile] = { distance, duration };
+      results[profile] = { distance, duration, geometry: route.geometry };
     }
 
     res.json(results);
@@ -45,4 +50,62 @@ const getDistance = async (req, res) => {
   }
 };
 
-module.exports = { getDistance };
\ No newline at end of file
+// getDistancesBatch resolves a whole path of waypoints with one directions request per
+// profile and returns one leg per consecutive pair: { profile: [{ distance, duration, geometry }] }
+const getDistancesBatch = async (req, res) => {
+  try {
+    const { coordinates } = req.body || {};
+
+    if (!Array.isArray(coordinates) || coordinates.length < 2) {
+      return res.status(400).json({ error: 'At least two coordinates are required' });
+    }
+    if (coordinates.length > MAX_WAYPOINTS) {
+      return res.status(400).json({ error: `At most ${MAX_WAYPOINTS} coordinates are allowed` });
+    }
+    const invalid = coordinates.some(
+      (c) => !Array.isArray(c) || c.length < 2 || !Number.isFinite(c[0]) || !Number.isFinite(c[1])
+    );
+    if (invalid) {
+      return res.status(400).json({ error: 'Coordinates must be [lng, lat] pairs' });
+    }
+
+    const results = {};
+
+    for (const profile of VALID_PROFILES) {
+      const url = `https://api.openrouteservice.org/v2/directions/${profile}`;
+      const response = await axios.post(
+        url,
+        { coordinates },
+        {
+          headers: {
+            Authorization: API_KEY,
+            'Content-Type': 'application/json'
+          }
+        }
+      );
+
+      const route = response.data.routes[0];
+      const points = polyline.decode(route.geometry);
+      const wayPoints = route.way_points || [];
+      const segments = route.segments || [];
+
+      // way_points holds the index in the geometry of every requested coordinate
+      results[profile] = coordinates.slice(1).map((_, i) => {
+        const start = wayPoints[i] ?? 0;
+        const end = wayPoints[i + 1] ?? points.length - 1;
+        return {
+          distance: segments[i]?.distance ?? 0, // meters
+          duration: segments[i]?.duration ?? 0, // seconds
+          geometry: polyline.encode(points.slice(start, end + 1))
+        };
+      });
+    }
+
+    res.json(results);
+  } catch (err) {
+    console.error(err.response?.data || err.message);
+    res.status(500).json({ error: 'Failed to calculate distances' });
+  }
+};
+
+module.exports = { getDistance, getDistancesBatch };
\ No newline at end of file
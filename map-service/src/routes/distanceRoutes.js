@@ -1,8 +1,9 @@
 const express = require('express');
-const { getDistance } = require('../controllers/distanceController');
+const { getDistance, getDistancesBatch } = require('../controllers/distanceController');
 
 const router = express.Router();
 
 router.get('/api/getdistances', getDistance);
+router.post('/api/getdistances/batch', getDistancesBatch);
 
 module.exports = router;
@@ -0,0 +1,56 @@
+// Google encoded polyline helpers (precision 5), coordinates as [lng, lat]
+
+const decode = (encoded) => {
+  const coordinates = [];
+  let index = 0;
+  let lat = 0;
+  let lng = 0;
+
+  while (index < encoded.length) {
+    const deltas = [0, 0];
+    for (let i = 0; i < 2; i++) {
+      let result = 0;
+      let shift = 0;
+      let b;
+      do {
+        b = encoded.charCodeAt(index++) - 63;
+        result |= (b & 0x1f) << shift;
+        shift += 5;
+      } while (b >= 0x20);
+      deltas[i] = result & 1 ? ~(result >> 1) : result >> 1;
+    }
+    lat += deltas[0];
+    lng += deltas[1];
+    coordinates.push([lng / 1e5, lat / 1e5]);
+  }
+
+  return coordinates;
+};
+
+const encodeValue = (value) => {
+  let v = value < 0 ? ~(value << 1) : value << 1;
+  let output = '';
+  while (v >= 0x20) {
+    output += String.fromCharCode((0x20 | (v & 0x1f)) + 63);
+    v >>= 5;
+  }
+  return output + String.fromCharCode(v + 63);
+};
+
+const encode = (coordinates) => {
+  let output = '';
+  let prevLat = 0;
+  let prevLng = 0;
+
+  for (const [lng, lat] of coordinates) {
+    const latE5 = Math.round(lat * 1e5);
+    const lngE5 = Math.round(lng * 1e5);
+    output += encodeValue(latE5 - prevLat) + encodeValue(lngE5 - prevLng);
+    prevLat = latE5;
+    prevLng = lngE5;
+  }
+
+  return output;
+};
+
+module.exports = { decode, encode };
@@ -27,9 +27,10 @@ func main() {
 	cartService := services.NewCartService(cartRepo, itemRepo)
 	checkoutService := services.NewCheckoutService(cartRepo, itemRepo, tokenRepo)
 	authService := services.NewAuthService()
+	tourService := services.NewTourService()
 
 	cartHandler := handlers.NewCartHandler(cartService, authService)
-	checkoutHandler := handlers.NewCheckoutHandler(checkoutService, authService)
+	checkoutHandler := handlers.NewCheckoutHandler(checkoutService, authService, tourService)
 
 	router := mux.NewRouter()
 
@@ -41,6 +42,7 @@ func main() {
 	router.HandleFunc("/purchases", checkoutHandler.GetPurchaseHistory).Methods("GET")
 	router.HandleFunc("/validate-token", checkoutHandler.ValidateToken).Methods("GET")
 	router.HandleFunc("/check-is-purchased/{tourId}", checkoutHandler.CheckIsPurchased).Methods("GET")
+	router.HandleFunc("/tours/{tourId}/purchase-count", checkoutHandler.CountTourPurchases).Methods("GET")
 
 	port := os.Getenv("SERVICE_PORT")
 	if port == "" {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -14,12 +15,14 @@ import (
 type CheckoutHandler struct {
 	checkoutService *services.CheckoutService
 	authService     *services.AuthService
+	tourService     *services.TourService
 }
 
-func NewCheckoutHandler(checkoutService *services.CheckoutService, authService *services.AuthService) *CheckoutHandler {
+func NewCheckoutHandler(checkoutService *services.CheckoutService, authService *services.AuthService, tourService *services.TourService) *CheckoutHandler {
 	return &CheckoutHandler{
 		checkoutService: checkoutService,
 		authService:     authService,
+		tourService:     tourService,
 	}
 }
 
@@ -132,3 +135,45 @@ func (h *CheckoutHandler) CheckIsPurchased(w http.ResponseWriter, r *http.Reques
 		"tourist_id": touristID,
 	})
 }
+
+// CountTourPurchases tells the author of a tour how many times it was bought, tours-service
+// asks before it lets a tour be deleted.
+func (h *CheckoutHandler) CountTourPurchases(w http.ResponseWriter, r *http.Request) {
+	guideID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tourID := 0
+	if _, err := fmt.Sscanf(mux.Vars(r)["tourId"], "%d", &tourID); err != nil {
+		http.Error(w, "Invalid tourId parameter", http.StatusBadRequest)
+		return
+	}
+
+	authorID, err := h.tourService.GetTourAuthorID(tourID)
+	if err != nil {
+		if errors.Is(err, services.ErrTourNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+	if authorID != guideID {
+		http.Error(w, "Only the tour author can see its purchases", http.StatusForbidden)
+		return
+	}
+
+	count, err := h.checkoutService.CountPurchasesOfTour(tourID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tour_id":   tourID,
+		"purchases": count,
+	})
+}
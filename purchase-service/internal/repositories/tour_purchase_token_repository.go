@@ -98,3 +98,17 @@ func (r *TourPurchaseTokenRepository) CheckIsPurchased(touristID int, tourID int
 
 	return count > 0, nil
 }
+
+func (r *TourPurchaseTokenRepository) CountPurchasesOfTour(tourID int) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM tour_purchase_tokens
+		WHERE tour_id = $1`
+
+	var count int
+	err := r.db.DB.QueryRow(query, tourID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
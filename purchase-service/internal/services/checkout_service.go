@@ -107,3 +107,7 @@ func (s *CheckoutService) ValidateToken(tokenValue string, tourID int) (bool, er
 func (s *CheckoutService) CheckIsPurchased(touristID int, tourID int) (bool, error) {
 	return s.tokenRepo.CheckIsPurchased(touristID, tourID)
 }
+
+func (s *CheckoutService) CountPurchasesOfTour(tourID int) (int, error) {
+	return s.tokenRepo.CountPurchasesOfTour(tourID)
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// ErrTourNotFound is returned when tours-service doesn't know the tour.
+var ErrTourNotFound = errors.New("tour not found")
+
+type TourService struct {
+}
+
+func NewTourService() *TourService {
+	return &TourService{}
+}
+
+// GetTourAuthorID asks tours-service which guide wrote the tour.
+func (s *TourService) GetTourAuthorID(tourID int) (int, error) {
+	tourURL := os.Getenv("TOURS_SERVICE_URL") + "/api/" + strconv.Itoa(tourID)
+	req, err := http.NewRequest("GET", tourURL, nil)
+	if err != nil {
+		return 0, errors.New("failed to create tour request")
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, errors.New("failed to contact tours service")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrTourNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorBody bytes.Buffer
+		errorBody.ReadFrom(resp.Body)
+		return 0, fmt.Errorf("tours service responded with error: %s", errorBody.String())
+	}
+
+	var tour struct {
+		AuthorID int `json:"authorId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tour); err != nil {
+		return 0, errors.New("failed to decode tour response")
+	}
+
+	return tour.AuthorID, nil
+}
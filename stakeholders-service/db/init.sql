@@ -16,5 +16,10 @@ CREATE TABLE IF NOT EXISTS positions (
     id SERIAL PRIMARY KEY,
     user_id INT UNIQUE NOT NULL,
     longitude FLOAT NOT NULL,
-    latitude FLOAT NOT NULL
-);
\ No newline at end of file
+    latitude FLOAT NOT NULL,
+    accuracy FLOAT,
+    recorded_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE positions ADD COLUMN IF NOT EXISTS accuracy FLOAT;
+ALTER TABLE positions ADD COLUMN IF NOT EXISTS recorded_at TIMESTAMP NOT NULL DEFAULT NOW();
\ No newline at end of file
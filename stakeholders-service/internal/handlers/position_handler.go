@@ -51,6 +51,11 @@ func (h *PositionHandler) CreatePosition(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.Accuracy != nil && *req.Accuracy < 0 {
+		http.Error(w, "Accuracy must not be negative", http.StatusBadRequest)
+		return
+	}
+
 	req.UserId = user.ID
 
 	createdPosition, err := h.positionRepo.CreatePosition(&req)
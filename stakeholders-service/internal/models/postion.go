@@ -1,8 +1,12 @@
 package models
 
+import "time"
+
 type Position struct {
-	ID        int     `json:"id"`
-	UserId    int     `json:"user_id"`
-	Longitude float64 `json:"longitude"`
-	Latitude  float64 `json:"latitude"`
+	ID         int       `json:"id"`
+	UserId     int       `json:"user_id"`
+	Longitude  float64   `json:"longitude"`
+	Latitude   float64   `json:"latitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"` // horizontal accuracy in meters, as reported by the device
+	RecordedAt time.Time `json:"recorded_at"`
 }
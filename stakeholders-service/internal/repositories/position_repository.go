@@ -25,14 +25,15 @@ func (p *PositionRepository) CreatePosition(position *models.Position) (*models.
         return position, err
     }
 
-    query := `INSERT INTO positions (user_id, longitude, latitude) 
-              VALUES ($1, $2, $3) RETURNING id`
+    query := `INSERT INTO positions (user_id, longitude, latitude, accuracy, recorded_at) 
+              VALUES ($1, $2, $3, $4, NOW()) RETURNING id, recorded_at`
 
     err = p.DB.QueryRow(query,
         position.UserId,
         position.Longitude,
         position.Latitude,
-    ).Scan(&position.ID)
+        position.Accuracy,
+    ).Scan(&position.ID, &position.RecordedAt)
     if err != nil {
         return nil, fmt.Errorf("failed to create position of user: %w", err)
     }
@@ -43,13 +44,15 @@ func (p *PositionRepository) CreatePosition(position *models.Position) (*models.
 func (p *PositionRepository) GetPositionByUserID(userId int) (*models.Position, error) {
 	var position models.Position
 
-	query := `SELECT id, user_id, longitude, latitude FROM positions WHERE user_id = $1`
+	query := `SELECT id, user_id, longitude, latitude, accuracy, recorded_at FROM positions WHERE user_id = $1`
 
 	err := p.DB.QueryRow(query, userId).Scan(
 		&position.ID,
 		&position.UserId,
 		&position.Longitude,
 		&position.Latitude,
+		&position.Accuracy,
+		&position.RecordedAt,
 	)
 
 	if err != nil {
@@ -75,9 +78,11 @@ func (p *PositionRepository) PositionExists(userId int) (bool, error) {
 }
 
 func (p *PositionRepository) UpdatePosition(position *models.Position) error {
-	query := `UPDATE positions SET longitude = $1, latitude = $2 WHERE user_id = $3`
+	query := `UPDATE positions SET longitude = $1, latitude = $2, accuracy = $3, recorded_at = NOW()
+              WHERE user_id = $4 RETURNING id, recorded_at`
 	
-	_, err := p.DB.Exec(query, position.Longitude, position.Latitude, position.UserId)
+	err := p.DB.QueryRow(query, position.Longitude, position.Latitude, position.Accuracy, position.UserId).
+		Scan(&position.ID, &position.RecordedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update position: %w", err)
 	}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"tours-service/db"
 	"tours-service/internal/grpc_handlers"
 	"tours-service/internal/handlers"
+	"tours-service/internal/models"
 	"tours-service/internal/repositories"
 	"tours-service/internal/services"
 
@@ -36,64 +39,197 @@ func main() {
 		}
 	}()
 
+	if err := db.RequireReplicaSet(client); err != nil {
+		log.Fatalf("MongoDB can't run transactions: %v", err)
+	}
+
 	toursDB := client.Database(os.Getenv("DB_NAME"))
 
+	// Before serving, so position_trails is created as a time-series collection and the
+	// unique indexes guard the first requests; the periodic job picks up later changes
+	if err := db.EnsureIndexes(toursDB); err != nil {
+		log.Printf("Warning: Some indexes could not be ensured at startup: %v", err)
+	}
+
 	// --- Repositories ---
 	tourRepo := repositories.NewTourRepository(toursDB)
 	keypointRepo := repositories.NewKeypointRepository(toursDB)
 	reviewRepo := repositories.NewTourReviewRepository(toursDB)
 	tourExecutionRepo := repositories.NewTourExecutionRepository(toursDB)
+	positionTrailRepo := repositories.NewPositionTrailRepository(toursDB)
+	executionEventRepo := repositories.NewExecutionEventRepository(toursDB)
+	revisionRepo := repositories.NewTourRevisionRepository(toursDB)
+	distanceCacheRepo := repositories.NewDistanceCacheRepository(toursDB)
+	jobRepo := repositories.NewJobRepository(toursDB)
 
 	// --- Services ---
-	mapService := services.NewMapService(os.Getenv("MAP_SERVICE_URL"))
-	tourService := services.NewTourService(tourRepo, keypointRepo, mapService)
-	tourReviewService := services.NewTourReviewService(reviewRepo)
-	keypointService := services.NewKeypointService(keypointRepo)
+	distanceCacheSize, err := strconv.Atoi(os.Getenv("DISTANCE_CACHE_SIZE"))
+	if err != nil || distanceCacheSize <= 0 {
+		distanceCacheSize = 10000
+	}
+	distanceCache := services.NewDistanceCache(distanceCacheSize, distanceCacheRepo)
+	mapService := services.NewMapService(os.Getenv("MAP_SERVICE_URL"), distanceCache)
+	revisionService := services.NewTourRevisionService(revisionRepo, tourRepo, keypointRepo)
+	tourService := services.NewTourService(tourRepo, keypointRepo, mapService, revisionService)
+	tourReviewService := services.NewTourReviewService(reviewRepo, tourExecutionRepo, keypointRepo, tourRepo)
+	jobService := services.NewJobService(jobRepo)
+	keypointService := services.NewKeypointService(keypointRepo, tourService, jobService, revisionService)
 	authService := services.NewAuthService()
 	purchaseService := services.NewPurchaseService()
-	tourExecutionService := services.NewTourExecutionService(tourExecutionRepo, tourService, keypointService)
+	tourFileService := services.NewTourFileService(tourService)
+	keypointMediaService := services.NewKeypointMediaService(keypointRepo, tourExecutionRepo)
+	dwellFixes, err := strconv.Atoi(os.Getenv("KEYPOINT_DWELL_FIXES"))
+	if err != nil || dwellFixes <= 0 {
+		dwellFixes = 2
+	}
+	positionTrailService := services.NewPositionTrailService(positionTrailRepo, tourExecutionRepo)
+	executionEventService := services.NewExecutionEventService(executionEventRepo)
+	tourExecutionService := services.NewTourExecutionService(tourExecutionRepo, tourService, keypointService, positionTrailService, executionEventService,
+		envDuration("EXECUTION_IDLE_TIMEOUT", 2*time.Hour),
+		envDuration("EXECUTION_RESUME_GRACE_PERIOD", 24*time.Hour),
+		dwellFixes)
+
+	deletedTourRetention := envDuration("DELETED_TOUR_RETENTION", 30*24*time.Hour)
 
 	// --- HTTP Handlers ---
-	tourHandler := handlers.NewTourHandler(tourService, keypointService, tourReviewService, authService)
-	keypointHandler := handlers.NewKeypointHandler(keypointService, tourService, authService)
-	reviewHandler := handlers.NewTourReviewHandler(tourReviewService, tourService, authService)
+	tourHandler := handlers.NewTourHandler(tourService, keypointService, tourReviewService, revisionService, keypointMediaService, authService, purchaseService)
+	keypointHandler := handlers.NewKeypointHandler(keypointService, tourService, revisionService, keypointMediaService, authService, purchaseService)
+	reviewHandler := handlers.NewTourReviewHandler(tourReviewService, tourService, authService, purchaseService)
 	TourExecutionHandler := handlers.NewTourExecutionHandler(tourExecutionService, authService, purchaseService)
+	revisionHandler := handlers.NewTourRevisionHandler(revisionService, tourService, authService)
+	tourFileHandler := handlers.NewTourFileHandler(tourFileService, tourService, keypointService, revisionService, authService, purchaseService)
+	jobHandler := handlers.NewJobHandler(jobService, authService)
 
 	router := mux.NewRouter()
 	api := router.PathPrefix("/api").Subrouter()
 
 	// --- Review routes ---
 	api.HandleFunc("/reviews", reviewHandler.CreateTourReview).Methods("POST")
-	api.HandleFunc("/{tourId}/reviews", reviewHandler.GetReviewsByTourID).Methods("GET")
+	api.HandleFunc("/reviews/{reviewId:[0-9]+}", reviewHandler.UpdateTourReview).Methods("PUT")
+	api.HandleFunc("/reviews/{reviewId:[0-9]+}", reviewHandler.DeleteTourReview).Methods("DELETE")
+	api.HandleFunc("/reviews/{reviewId:[0-9]+}/reply", reviewHandler.ReplyToReview).Methods("PUT")
+	api.HandleFunc("/reviews/{reviewId:[0-9]+}/reply", reviewHandler.DeleteReply).Methods("DELETE")
+	api.HandleFunc("/reviews/{reviewId:[0-9]+}/report", reviewHandler.ReportReview).Methods("POST")
+	api.HandleFunc("/{tourId:[0-9]+}/reviews", reviewHandler.GetReviewsByTourID).Methods("GET")
 
 	// --- Tour routes ---
 	api.HandleFunc("/create", tourHandler.CreateTour).Methods("POST")
+	api.HandleFunc("/import", tourFileHandler.ImportTour).Methods("POST")
 	api.HandleFunc("/my-tours", tourHandler.GetToursByAuthor).Methods("GET")
+	api.HandleFunc("/my-tours/deleted", tourHandler.GetDeletedTours).Methods("GET")
 	api.HandleFunc("/get-published", tourHandler.GetPublishedToursWithFirstKeypoint).Methods("GET")
 	api.HandleFunc("/{tourId}", tourHandler.GetTourByID).Methods("GET")
-	api.HandleFunc("/{tourId}", tourHandler.UpdateTour).Methods("PUT")
+	api.HandleFunc("/{tourId}", tourHandler.UpdateTour).Methods("PUT", "PATCH")
 	api.HandleFunc("/{tourId}", tourHandler.DeleteTour).Methods("DELETE")
 	api.HandleFunc("/{tourId}/publish", tourHandler.PublishTour).Methods("POST")
 	api.HandleFunc("/{tourId}/archive", tourHandler.ArchiveTour).Methods("POST")
+	api.HandleFunc("/{tourId}/restore", tourHandler.RestoreDeletedTour).Methods("POST")
+	api.HandleFunc("/{tourId}/clone", tourHandler.CloneTour).Methods("POST")
+	api.HandleFunc("/{tourId}/schedule", tourHandler.ScheduleTour).Methods("PUT")
+	api.HandleFunc("/{tourId}/translations", tourHandler.GetTranslations).Methods("GET")
+	api.HandleFunc("/{tourId}/translations/{lang}", tourHandler.SetTranslation).Methods("PUT")
+	api.HandleFunc("/{tourId}/translations/{lang}", tourHandler.DeleteTranslation).Methods("DELETE")
 	api.HandleFunc("/{tourId}/set-price", tourHandler.SetTourPrice).Methods("POST")
 	api.HandleFunc("/{tourId}/tourist-view", tourHandler.GetTourForTourist).Methods("GET")
 	api.HandleFunc("/{tourId}/purchased-keypoints", tourHandler.GetPurchasedKeypoints).Methods("GET")
+	api.HandleFunc("/{tourId}/export", tourFileHandler.ExportTour).Methods("GET")
+	api.HandleFunc("/{tourId}/route", tourFileHandler.GetRoute).Methods("GET")
+	api.HandleFunc("/{tourId}/analytics", TourExecutionHandler.GetTourAnalytics).Methods("GET")
+	api.HandleFunc("/{tourId}/purchased-keypoints/upgrade", tourHandler.UpgradePurchasedRevision).Methods("POST")
+
+	// --- Revision routes ---
+	api.HandleFunc("/{tourId}/revisions", revisionHandler.GetRevisions).Methods("GET")
+	api.HandleFunc("/{tourId}/revisions/diff", revisionHandler.GetRevisionDiff).Methods("GET")
+	api.HandleFunc("/{tourId}/revisions/{revision:[0-9]+}", revisionHandler.GetRevision).Methods("GET")
+	api.HandleFunc("/{tourId}/revisions/{revision:[0-9]+}/restore", revisionHandler.RestoreRevision).Methods("POST")
 
 	// --- Keypoint routes ---
 	api.HandleFunc("/{tourId}/create-keypoint", keypointHandler.CreateKeypoint).Methods("POST")
 	api.HandleFunc("/{tourId}/keypoints", keypointHandler.GetKeypointsByTourID).Methods("GET")
+	api.HandleFunc("/{tourId}/keypoints/order", keypointHandler.ReorderKeypoints).Methods("PUT")
 	api.HandleFunc("/keypoints/{keypointId}", keypointHandler.GetKeypointByID).Methods("GET")
-	api.HandleFunc("/keypoints/{keypointId}", keypointHandler.UpdateKeypoint).Methods("PUT")
+	api.HandleFunc("/keypoints/{keypointId}", keypointHandler.UpdateKeypoint).Methods("PUT", "PATCH")
 	api.HandleFunc("/keypoints/{keypointId}", keypointHandler.DeleteKeypoint).Methods("DELETE")
 	api.HandleFunc("/keypoints/{keypointId}/upload-image", keypointHandler.UploadKeypointImage).Methods("POST")
+	api.HandleFunc("/keypoints/{keypointId}/media", keypointHandler.GetKeypointMedia).Methods("GET")
+	api.HandleFunc("/keypoints/{keypointId}/media", keypointHandler.UploadKeypointMedia).Methods("POST")
+	api.HandleFunc("/keypoints/{keypointId}/media/order", keypointHandler.ReorderKeypointMedia).Methods("PUT")
+	api.HandleFunc("/keypoints/{keypointId}/media/{mediaId:[0-9]+}", keypointHandler.DeleteKeypointMedia).Methods("DELETE")
+	api.HandleFunc("/keypoints/{keypointId}/challenge", keypointHandler.SetKeypointChallenge).Methods("PUT")
+	api.HandleFunc("/keypoints/{keypointId}/challenge", keypointHandler.DeleteKeypointChallenge).Methods("DELETE")
 
 	// -- Execution routes --
 	executionRouter := api.PathPrefix("/execution").Subrouter()
 	executionRouter.HandleFunc("/my-executions", TourExecutionHandler.GetExecutionsByUser).Methods("GET")
 	executionRouter.HandleFunc("/tour/{tour_id}", TourExecutionHandler.GetMyExecutionByTourID).Methods("GET")
+	executionRouter.HandleFunc("/history/{tour_id}", TourExecutionHandler.GetMyExecutionHistoryByTourID).Methods("GET")
 	executionRouter.HandleFunc("/start/{tour_id}", TourExecutionHandler.StartTourExecution).Methods("POST")
 	executionRouter.HandleFunc("/abort/{tour_id}", TourExecutionHandler.AbortExecution).Methods("POST")
+	executionRouter.HandleFunc("/resume/{tour_id}", TourExecutionHandler.ResumeExecution).Methods("POST")
 	executionRouter.HandleFunc("/is-keypoint-reached/{tour_id}", TourExecutionHandler.CheckIsKeyPointReached).Methods("POST")
+	executionRouter.HandleFunc("/challenge/{tour_id}/{keypoint_id}", TourExecutionHandler.SubmitChallengeAnswer).Methods("POST")
+	executionRouter.HandleFunc("/on-route/{tour_id}", TourExecutionHandler.CheckIsOnRoute).Methods("GET")
+	executionRouter.HandleFunc("/position/{tour_id}", TourExecutionHandler.ReportPosition).Methods("POST")
+	executionRouter.HandleFunc("/by-tour/{tour_id}", TourExecutionHandler.GetExecutionsByTour).Methods("GET")
+	executionRouter.HandleFunc("/trail/{execution_id}", TourExecutionHandler.GetExecutionTrail).Methods("GET")
+
+	// -- Admin routes --
+	adminRouter := api.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/jobs", jobHandler.GetJobs).Methods("GET")
+	adminRouter.HandleFunc("/jobs/{jobId:[0-9]+}", jobHandler.GetJob).Methods("GET")
+	adminRouter.HandleFunc("/jobs/{jobId:[0-9]+}/retry", jobHandler.RetryJob).Methods("POST")
+	adminRouter.HandleFunc("/reviews", reviewHandler.GetModerationQueue).Methods("GET")
+	adminRouter.HandleFunc("/reviews/{reviewId:[0-9]+}/hide", reviewHandler.HideReview).Methods("POST")
+	adminRouter.HandleFunc("/reviews/{reviewId:[0-9]+}/restore", reviewHandler.RestoreReview).Methods("POST")
+	adminRouter.HandleFunc("/reviews/{reviewId:[0-9]+}/dismiss", reviewHandler.DismissReviewReports).Methods("POST")
+
+	// --- Background jobs ---
+	jobService.Register(services.JobRecalculateTourLength, 0, func(ctx context.Context, job *models.Job) error {
+		tourID, err := services.PayloadInt(job, "tourId")
+		if err != nil {
+			return err
+		}
+		return tourService.RecalculateTourLength(ctx, tourID)
+	})
+	periodicJobs := []struct {
+		jobType  string
+		interval time.Duration
+		handler  services.JobHandler
+	}{
+		{services.JobResolveEstimatedStats, 5 * time.Minute, func(ctx context.Context, job *models.Job) error {
+			tourIDs, err := tourService.EstimatedStatsTourIDs(ctx)
+			if err != nil {
+				return err
+			}
+			var errs []error
+			for _, tourID := range tourIDs {
+				errs = append(errs, jobService.EnqueueTourRecalculation(tourID))
+			}
+			return errors.Join(errs...)
+		}},
+		{services.JobEnsureIndexes, 24 * time.Hour, func(ctx context.Context, job *models.Job) error {
+			return db.EnsureIndexes(toursDB)
+		}},
+		{services.JobSweepIdleExecutions, time.Minute, func(ctx context.Context, job *models.Job) error {
+			_, err := tourExecutionService.AbandonIdleExecutions()
+			return err
+		}},
+		{services.JobRecalculateRatings, 24 * time.Hour, func(ctx context.Context, job *models.Job) error {
+			return tourReviewService.RecalculateRatings(ctx)
+		}},
+		{services.JobPurgeDeletedTours, time.Hour, func(ctx context.Context, job *models.Job) error {
+			return tourService.PurgeDeletedTours(ctx, deletedTourRetention)
+		}},
+		{services.JobApplyTourSchedules, time.Minute, func(ctx context.Context, job *models.Job) error {
+			return tourService.ApplyTourSchedules(ctx)
+		}},
+	}
+	for _, periodic := range periodicJobs {
+		if err := jobService.RegisterPeriodic(periodic.jobType, periodic.interval, periodic.handler); err != nil {
+			log.Printf("Warning: Failed to schedule %s job: %v", periodic.jobType, err)
+		}
+	}
+	jobService.Start(context.Background(), 2)
 
 	// --- Start gRPC Server ---
 	grpcLis, err := net.Listen("tcp", ":50051")
@@ -102,7 +238,7 @@ func main() {
 	}
 
 	grpcServer := grpc.NewServer()
-	tourGRPCServer := grpc_handlers.NewTourGRPCServer(tourService)
+	tourGRPCServer := grpc_handlers.NewTourGRPCServer(tourService, tourExecutionService, executionEventService)
 	pb.RegisterTourServiceServer(grpcServer, tourGRPCServer)
 
 	go func() {
@@ -121,3 +257,12 @@ func main() {
 	fmt.Printf("HTTP server running on port %s...\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, router))
 }
+
+// envDuration reads a duration such as "90m" or "2h" from the environment.
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
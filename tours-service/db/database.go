@@ -2,10 +2,12 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -35,4 +37,23 @@ func InitDB() (*mongo.Client, error) {
 
 	fmt.Println("Successfully connected to MongoDB!")
 	return client, nil
-}
\ No newline at end of file
+}
+
+// RequireReplicaSet fails unless the server is a replica set member. Writes that span
+// several documents, such as a review and the rating summary of its tour, run in
+// transactions, which a standalone server does not support.
+func RequireReplicaSet(client *mongo.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var hello struct {
+		SetName string `bson:"setName"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return fmt.Errorf("failed to check MongoDB topology: %w", err)
+	}
+	if hello.SetName == "" {
+		return errors.New("MongoDB is a standalone server, start it with --replSet and run rs.initiate()")
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexes mirrors the indexes created by init.js, plus the ones that only the
+// application relies on. CreateMany is a no-op for indexes that already exist.
+var indexes = map[string][]mongo.IndexModel{
+	"tours": {
+		{Keys: bson.D{{Key: "authorId", Value: 1}}},
+		// deletedAt is missing on active tours, so names are unique among them and soft
+		// deleted tours don't hold on to theirs
+		{Keys: bson.D{{Key: "authorId", Value: 1}, {Key: "name", Value: 1}, {Key: "deletedAt", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "walkingStats.source", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Scheduled publishing and archiving
+		{Keys: bson.D{{Key: "scheduledPublishAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "scheduledArchiveAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Purge of soft deleted tours
+		{Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+		// Published listing sorted or filtered by rating
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "rating.average", Value: -1}, {Key: "rating.count", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "rating.count", Value: -1}, {Key: "rating.average", Value: -1}}},
+	},
+	"keypoints": {
+		{Keys: bson.D{{Key: "tourId", Value: 1}}},
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "ordinal", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "latitude", Value: 1}, {Key: "longitude", Value: 1}}},
+	},
+	"tourExecution": {
+		// A tour can be walked many times, but only one execution per user and tour is active
+		{Keys: bson.D{{Key: "tour_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().
+			SetName("active_execution").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": "in_progress"})},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tour_id", Value: 1}, {Key: "started_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "last_activity", Value: 1}}},
+		// Per-tour analytics by start date
+		{Keys: bson.D{{Key: "tour_id", Value: 1}, {Key: "started_at", Value: 1}}},
+	},
+	"tour_revisions": {
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "number", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "createdAt", Value: 1}}},
+	},
+	"tour_revision_pins": {
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "touristId", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"distance_cache": {
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	},
+	"position_trails": {
+		{Keys: bson.D{{Key: "meta.executionId", Value: 1}, {Key: "recordedAt", Value: 1}}},
+	},
+	"execution_events": {
+		{Keys: bson.D{{Key: "executionId", Value: 1}, {Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Events only matter while a client may still resume, a month is plenty
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	},
+	"tour_reviews": {
+		// One review per tourist per tour, resubmitting edits it
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "touristId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "commentDate", Value: -1}}},
+		{Keys: bson.D{{Key: "tourId", Value: 1}, {Key: "rating", Value: -1}, {Key: "commentDate", Value: -1}}},
+		// Moderation queue
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "reports.createdAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	"jobs": {
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "runAt", Value: 1}}},
+		{Keys: bson.D{{Key: "uniqueKey", Value: 1}}, Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": "pending", "uniqueKey": bson.M{"$exists": true}})},
+		// Finished jobs are kept for a week for inspection
+		{Keys: bson.D{{Key: "finishedAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+	},
+}
+
+// timeSeriesCollections are created as time-series collections by EnsureIndexes when
+// they don't exist yet, a regular collection can't be turned into one later.
+var timeSeriesCollections = map[string]*options.TimeSeriesOptions{
+	"position_trails": options.TimeSeries().SetTimeField("recordedAt").SetMetaField("meta").SetGranularity("seconds"),
+}
+
+// Position trails are kept for 180 days
+const positionTrailRetention = 180 * 24 * 60 * 60
+
+// obsoleteIndexes are dropped by EnsureIndexes, by collection and index name.
+var obsoleteIndexes = map[string][]string{
+	// Unique on every execution, replaced by active_execution
+	"tourExecution": {"tour_id_1_user_id_1"},
+	// Also held the names of soft deleted tours, replaced by authorId_1_name_1_deletedAt_1
+	"tours": {"authorId_1_name_1"},
+}
+
+// repairs fix up documents that would keep an index of their collection from being
+// created, they run right before the collection's indexes are ensured.
+var repairs = map[string]func(ctx context.Context, collection *mongo.Collection) error{
+	"tour_reviews": removeDuplicateReviews,
+	"keypoints":    renumberKeypoints,
+}
+
+// removeDuplicateReviews keeps the latest review of every tourist for a tour and deletes
+// the older ones, written before reviews were unique. The rating summaries of the
+// affected tours are corrected by the next recalculate-ratings job.
+func removeDuplicateReviews(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "commentDate", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"tourId": "$tourId", "touristId": "$touristId"},
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []int
+	for cursor.Next(ctx) {
+		var group struct {
+			IDs []int `bson:"ids"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return err
+		}
+		duplicates = append(duplicates, group.IDs[1:]...)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates}})
+	if err != nil {
+		return err
+	}
+	log.Printf("Removed %d duplicate reviews from %s", result.DeletedCount, collection.Name())
+	return nil
+}
+
+// renumberKeypoints gives the keypoints of every tour whose ordinals are not 1..n the
+// ordinals 1..n in their current order, for tours edited before ordinals were unique.
+func renumberKeypoints(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "tourId", Value: 1}, {Key: "ordinal", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$tourId",
+			"ids":      bson.M{"$push": "$_id"},
+			"ordinals": bson.M{"$push": "$ordinal"},
+		}}},
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{
+			"$ne": bson.A{"$ordinals", bson.M{"$range": bson.A{1, bson.M{"$add": bson.A{bson.M{"$size": "$ordinals"}, 1}}}}},
+		}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	tours := 0
+	for cursor.Next(ctx) {
+		var tour struct {
+			IDs []int `bson:"ids"`
+		}
+		if err := cursor.Decode(&tour); err != nil {
+			return err
+		}
+
+		// Parked on negative ordinals first, the unique index may already exist
+		park := make([]mongo.WriteModel, 0, len(tour.IDs))
+		place := make([]mongo.WriteModel, 0, len(tour.IDs))
+		for i, id := range tour.IDs {
+			park = append(park, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$set": bson.M{"ordinal": -(i + 1)}}))
+			place = append(place, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetUpdate(bson.M{"$set": bson.M{"ordinal": i + 1}, "$inc": bson.M{"version": 1}}))
+		}
+		if _, err := collection.BulkWrite(ctx, park); err != nil {
+			return err
+		}
+		if _, err := collection.BulkWrite(ctx, place); err != nil {
+			return err
+		}
+		tours++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if tours > 0 {
+		log.Printf("Renumbered the keypoints of %d tours in %s", tours, collection.Name())
+	}
+	return nil
+}
+
+// EnsureIndexes creates missing time-series collections, drops obsolete indexes and
+// creates any missing index of the tours database. A collection that fails is logged and
+// skipped so the others still get their indexes, the errors are returned together.
+func EnsureIndexes(database *mongo.Database) error {
+	var errs []error
+	fail := func(err error) {
+		log.Printf("Warning: %v", err)
+		errs = append(errs, err)
+	}
+
+	for collection, timeSeries := range timeSeriesCollections {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		opts := options.CreateCollection().SetTimeSeriesOptions(timeSeries).SetExpireAfterSeconds(positionTrailRetention)
+		err := database.CreateCollection(ctx, collection, opts)
+		cancel()
+		var commandErr mongo.CommandError
+		if err != nil && !(errors.As(err, &commandErr) && commandErr.Name == "NamespaceExists") {
+			fail(fmt.Errorf("failed to create time-series collection %s: %w", collection, err))
+		}
+	}
+
+	for collection, names := range obsoleteIndexes {
+		for _, name := range names {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			_, err := database.Collection(collection).Indexes().DropOne(ctx, name)
+			cancel()
+			var commandErr mongo.CommandError
+			if err != nil && !(errors.As(err, &commandErr) && (commandErr.Name == "IndexNotFound" || commandErr.Name == "NamespaceNotFound")) {
+				fail(fmt.Errorf("failed to drop index %s of %s: %w", name, collection, err))
+			}
+		}
+	}
+
+	for collection, models := range indexes {
+		if repair, ok := repairs[collection]; ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			err := repair(ctx, database.Collection(collection))
+			cancel()
+			if err != nil {
+				fail(fmt.Errorf("failed to repair %s before ensuring its indexes: %w", collection, err))
+				continue
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_, err := database.Collection(collection).Indexes().CreateMany(ctx, models)
+		cancel()
+		if err != nil {
+			fail(fmt.Errorf("failed to ensure indexes of %s: %w", collection, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -57,7 +57,17 @@ if (collectionNames.includes('tours')) {
     console.log("Collection 'tours' created with validation rules.");
 
     db.tours.createIndex({ "authorId": 1 });
-    db.tours.createIndex({ "authorId": 1, "name": 1 }, { unique: true });
+    // deletedAt is missing on active tours, so names are unique among them and soft
+    // deleted tours don't hold on to theirs
+    db.tours.createIndex({ "authorId": 1, "name": 1, "deletedAt": 1 }, { unique: true });
+    // Scheduled publishing and archiving
+    db.tours.createIndex({ "scheduledPublishAt": 1 }, { sparse: true });
+    db.tours.createIndex({ "scheduledArchiveAt": 1 }, { sparse: true });
+    // Purge of soft deleted tours
+    db.tours.createIndex({ "deletedAt": 1 }, { sparse: true });
+    // Published listing sorted or filtered by rating
+    db.tours.createIndex({ "status": 1, "rating.average": -1, "rating.count": -1 });
+    db.tours.createIndex({ "status": 1, "rating.count": -1, "rating.average": -1 });
 
     console.log("Indexes for 'tours' collection created/ensured.");
 }
@@ -116,7 +126,7 @@ if (collectionNames.includes('keypoints')) {
 
     
     db.keypoints.createIndex({ "tourId": 1 });
-    db.keypoints.createIndex({ "tourId": 1, "ordinal": 1 });
+    db.keypoints.createIndex({ "tourId": 1, "ordinal": 1 }, { unique: true });
     db.keypoints.createIndex({ "latitude": 1, "longitude": 1 });
 
     console.log("Indexes for 'keypoints' collection created/ensured.");
@@ -185,9 +195,97 @@ if (collectionNames.includes('tourExecution')) {
 
     console.log("Collection 'tourExecution' created with validation rules.");
 
-    db.tourExecution.createIndex({ "tour_id": 1, "user_id": 1 }, { unique: true });
+    // A tour can be walked many times, but only one execution per user and tour is active
+    db.tourExecution.createIndex({ "tour_id": 1, "user_id": 1 }, { name: "active_execution", unique: true, partialFilterExpression: { "status": "in_progress" } });
+    db.tourExecution.createIndex({ "user_id": 1, "tour_id": 1, "started_at": -1 });
+    db.tourExecution.createIndex({ "status": 1, "last_activity": 1 });
+    // Per-tour analytics by start date
+    db.tourExecution.createIndex({ "tour_id": 1, "started_at": 1 });
 
     console.log("Indexes for 'tourExecution' collection created/ensured.");
 }
 
+if (collectionNames.includes('tour_revisions')) {
+    console.log("'tour_revisions' collection already exists. Skipping creation.");
+} else {
+    console.log("'tour_revisions' collection does not exist. Creating now...");
+    db.createCollection('tour_revisions');
+
+    db.tour_revisions.createIndex({ "tourId": 1, "number": 1 }, { unique: true });
+    db.tour_revisions.createIndex({ "tourId": 1, "createdAt": 1 });
+    db.tour_revision_pins.createIndex({ "tourId": 1, "touristId": 1 }, { unique: true });
+
+    console.log("Indexes for 'tour_revisions' collection created/ensured.");
+}
+
+if (collectionNames.includes('distance_cache')) {
+    console.log("'distance_cache' collection already exists. Skipping creation.");
+} else {
+    console.log("'distance_cache' collection does not exist. Creating now...");
+    db.createCollection('distance_cache');
+
+    // Roads change, cached routes are refreshed after 30 days
+    db.distance_cache.createIndex({ "createdAt": 1 }, { expireAfterSeconds: 30 * 24 * 60 * 60 });
+
+    console.log("Indexes for 'distance_cache' collection created/ensured.");
+}
+
+if (collectionNames.includes('jobs')) {
+    console.log("'jobs' collection already exists. Skipping creation.");
+} else {
+    console.log("'jobs' collection does not exist. Creating now...");
+    db.createCollection('jobs');
+
+    db.jobs.createIndex({ "status": 1, "runAt": 1 });
+    db.jobs.createIndex({ "uniqueKey": 1 }, { unique: true, partialFilterExpression: { "status": "pending", "uniqueKey": { $exists: true } } });
+    // Finished jobs are kept for a week for inspection
+    db.jobs.createIndex({ "finishedAt": 1 }, { expireAfterSeconds: 7 * 24 * 60 * 60 });
+
+    console.log("Indexes for 'jobs' collection created/ensured.");
+}
+
+if (collectionNames.includes('position_trails')) {
+    console.log("'position_trails' collection already exists. Skipping creation.");
+} else {
+    console.log("'position_trails' collection does not exist. Creating now...");
+    // One document per position fix, bucketed per execution; trails are kept for 180 days
+    db.createCollection('position_trails', {
+        timeseries: { timeField: "recordedAt", metaField: "meta", granularity: "seconds" },
+        expireAfterSeconds: 180 * 24 * 60 * 60
+    });
+
+    db.position_trails.createIndex({ "meta.executionId": 1, "recordedAt": 1 });
+
+    console.log("Indexes for 'position_trails' collection created/ensured.");
+}
+
+if (collectionNames.includes('execution_events')) {
+    console.log("'execution_events' collection already exists. Skipping creation.");
+} else {
+    console.log("'execution_events' collection does not exist. Creating now...");
+    db.createCollection('execution_events');
+
+    db.execution_events.createIndex({ "executionId": 1, "seq": 1 }, { unique: true });
+    // Events only matter while a client may still resume, a month is plenty
+    db.execution_events.createIndex({ "createdAt": 1 }, { expireAfterSeconds: 30 * 24 * 60 * 60 });
+
+    console.log("Indexes for 'execution_events' collection created/ensured.");
+}
+
+if (collectionNames.includes('tour_reviews')) {
+    console.log("'tour_reviews' collection already exists. Skipping creation.");
+} else {
+    console.log("'tour_reviews' collection does not exist. Creating now...");
+    db.createCollection('tour_reviews');
+
+    // One review per tourist per tour, resubmitting edits it
+    db.tour_reviews.createIndex({ "tourId": 1, "touristId": 1 }, { unique: true });
+    db.tour_reviews.createIndex({ "tourId": 1, "commentDate": -1 });
+    db.tour_reviews.createIndex({ "tourId": 1, "rating": -1, "commentDate": -1 });
+    // Moderation queue
+    db.tour_reviews.createIndex({ "status": 1, "reports.createdAt": 1 }, { sparse: true });
+
+    console.log("Indexes for 'tour_reviews' collection created/ensured.");
+}
+
 console.log("Database initialization script finished.");
\ No newline at end of file
@@ -0,0 +1,180 @@
+package grpc_handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+	"tours-service/internal/models"
+	pb "tours-service/proto/compiled"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamPollInterval is how often a stream looks for stored events published by
+// another instance.
+const streamPollInterval = 5 * time.Second
+
+// StreamExecution is the live version of the execution endpoints. The client subscribes
+// to the execution of a tour, optionally resuming after the last event it received, and
+// then streams its positions. The server replays missed events and pushes new ones:
+// distance to the next keypoint after every position, keypoint reached, tour completed
+// and execution abandoned. The stream ends after the tour is completed.
+func (s *TourGRPCServer) StreamExecution(stream pb.TourService_StreamExecutionServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	subscribe := first.GetSubscribe()
+	if subscribe == nil {
+		return status.Error(codes.InvalidArgument, "The first message must subscribe to a tour execution.")
+	}
+	tourID, userID := int(subscribe.TourId), int(subscribe.UserId)
+
+	execution, httpStatus, err := s.tourExecutionService.StreamExecution(tourID, userID)
+	if err != nil {
+		return status.Error(grpcCode(httpStatus), err.Error())
+	}
+
+	lastSeq := int64(0)
+	if int(subscribe.LastExecutionId) == execution.ID {
+		lastSeq = subscribe.LastEventId
+	}
+
+	// Subscribe before replaying, so nothing published in between is lost
+	live, unsubscribe := s.eventService.Subscribe(execution.ID)
+	defer unsubscribe()
+
+	send := func(event models.ExecutionEvent) (bool, error) {
+		if event.Seq != 0 {
+			if event.Seq <= lastSeq {
+				return false, nil
+			}
+			lastSeq = event.Seq
+		}
+		if err := stream.Send(toPBEvent(event)); err != nil {
+			return false, err
+		}
+		return event.Type == models.EventTourCompleted, nil
+	}
+	replay := func() (bool, error) {
+		events, err := s.eventService.EventsAfter(execution.ID, lastSeq)
+		if err != nil {
+			return false, status.Error(codes.Internal, "Failed to read execution events: "+err.Error())
+		}
+		for _, event := range events {
+			if done, err := send(event); done || err != nil {
+				return done, err
+			}
+		}
+		return false, nil
+	}
+
+	if done, err := replay(); done || err != nil {
+		return err
+	}
+
+	// Positions are received on their own goroutine, everything is sent from this one
+	positions := make(chan *pb.PositionUpdate)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if position := req.GetPosition(); position != nil {
+				select {
+				case positions <- position:
+				case <-stream.Context().Done():
+					return
+				}
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var done bool
+		var err error
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case event := <-live:
+			// A gap means events were published elsewhere, or raced here, first. Events are
+			// numbered and stored in one transaction, so the store already holds them all
+			if event.Seq > lastSeq+1 {
+				done, err = replay()
+			} else {
+				done, err = send(event)
+			}
+		case <-ticker.C:
+			done, err = replay()
+		case position := <-positions:
+			// The resulting events come back through the live subscription
+			_, _, processErr := s.tourExecutionService.ProcessPosition(tourID, userID, fromPBPosition(position))
+			if processErr != nil {
+				done, err = send(models.ExecutionEvent{
+					ExecutionID: execution.ID,
+					Type:        models.EventError,
+					Message:     processErr.Error(),
+					CreatedAt:   time.Now(),
+				})
+			}
+		}
+		if done || err != nil {
+			return err
+		}
+	}
+}
+
+func fromPBPosition(position *pb.PositionUpdate) *models.PositionFix {
+	fix := &models.PositionFix{
+		Longitude: position.Longitude,
+		Latitude:  position.Latitude,
+		Accuracy:  position.Accuracy,
+	}
+	if position.RecordedAt > 0 {
+		fix.RecordedAt = time.UnixMilli(position.RecordedAt)
+	}
+	return fix
+}
+
+func toPBEvent(event models.ExecutionEvent) *pb.ExecutionEvent {
+	return &pb.ExecutionEvent{
+		Id:           event.Seq,
+		ExecutionId:  int32(event.ExecutionID),
+		Type:         event.Type,
+		KeypointId:   int32(event.KeypointID),
+		KeypointName: event.KeypointName,
+		Distance:     event.Distance,
+		Message:      event.Message,
+		CreatedAt:    event.CreatedAt.UnixMilli(),
+	}
+}
+
+// grpcCode maps the HTTP statuses returned by the execution service to gRPC codes.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	default:
+		return codes.Internal
+	}
+}
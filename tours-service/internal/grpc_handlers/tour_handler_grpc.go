@@ -9,17 +9,22 @@ import (
 	pb "tours-service/proto/compiled"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 type TourGRPCServer struct {
 	pb.UnimplementedTourServiceServer
-	tourService *services.TourService
+	tourService          *services.TourService
+	tourExecutionService *services.TourExecutionService
+	eventService         *services.ExecutionEventService
 }
 
-func NewTourGRPCServer(tourService *services.TourService) *TourGRPCServer {
+func NewTourGRPCServer(tourService *services.TourService, tourExecutionService *services.TourExecutionService, eventService *services.ExecutionEventService) *TourGRPCServer {
 	return &TourGRPCServer{
-		tourService: tourService,
+		tourService:          tourService,
+		tourExecutionService: tourExecutionService,
+		eventService:         eventService,
 	}
 }
 
@@ -65,17 +70,21 @@ func (s *TourGRPCServer) CreateTour(ctx context.Context, req *pb.CreateTourReque
 	}
 
 	res := &pb.TourResponse{
-		Id:           int32(tour.ID),
-		AuthorId:     int32(tour.AuthorID),
-		Name:         tour.Name,
-		Description:  tour.Description,
-		Difficulty:   string(tour.Difficulty),
-		Tags:         tour.Tags,
-		Status:       string(tour.Status),
-		Price:        tour.Price,
-		DrivingStats: &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
-		WalkingStats: &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
-		CyclingStats: &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+		Id:            int32(tour.ID),
+		AuthorId:      int32(tour.AuthorID),
+		Name:          tour.Name,
+		Description:   tour.Description,
+		Difficulty:    string(tour.Difficulty),
+		Tags:          tour.Tags,
+		Status:        string(tour.Status),
+		Price:         tour.Price,
+		DrivingStats:  &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
+		WalkingStats:  &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
+		CyclingStats:  &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+		Version:       int32(tour.Version),
+		RatingAverage: tour.Rating.Average,
+		ReviewCount:   int32(tour.Rating.Count),
+		Language:      services.TourLanguage(tour),
 	}
 
 	if tour.TimePublished != nil {
@@ -87,10 +96,18 @@ func (s *TourGRPCServer) CreateTour(ctx context.Context, req *pb.CreateTourReque
 	if tour.TimeDrafted != nil {
 		res.TimeDrafted = tour.TimeDrafted.Format(time.RFC3339)
 	}
+	if tour.ScheduledPublishAt != nil {
+		res.ScheduledPublishAt = tour.ScheduledPublishAt.Format(time.RFC3339)
+	}
+	if tour.ScheduledArchiveAt != nil {
+		res.ScheduledArchiveAt = tour.ScheduledArchiveAt.Format(time.RFC3339)
+	}
 
 	return res, nil
 }
 
+// GetToursByAuthorID lists a guide's own tours. Like the REST listing they are not
+// localized, guides edit them in the language they were written in.
 func (s *TourGRPCServer) GetToursByAuthorID(ctx context.Context, req *pb.GetToursByAuthorIDRequest) (*pb.GetToursByAuthorIDResponse, error) {
 	tours, err := s.tourService.GetToursByAuthorID(int(req.UserId))
 	if err != nil {
@@ -100,17 +117,21 @@ func (s *TourGRPCServer) GetToursByAuthorID(ctx context.Context, req *pb.GetTour
 	var pbTours []*pb.TourResponse
 	for _, tour := range tours {
 		pbTour := &pb.TourResponse{
-			Id:           int32(tour.ID),
-			AuthorId:     int32(tour.AuthorID),
-			Name:         tour.Name,
-			Description:  tour.Description,
-			Difficulty:   string(tour.Difficulty),
-			Tags:         tour.Tags,
-			Status:       string(tour.Status),
-			Price:        tour.Price,
-			DrivingStats: &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
-			WalkingStats: &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
-			CyclingStats: &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+			Id:            int32(tour.ID),
+			AuthorId:      int32(tour.AuthorID),
+			Name:          tour.Name,
+			Description:   tour.Description,
+			Difficulty:    string(tour.Difficulty),
+			Tags:          tour.Tags,
+			Status:        string(tour.Status),
+			Price:         tour.Price,
+			DrivingStats:  &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
+			WalkingStats:  &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
+			CyclingStats:  &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+			Version:       int32(tour.Version),
+			RatingAverage: tour.Rating.Average,
+			ReviewCount:   int32(tour.Rating.Count),
+			Language:      tour.Language,
 		}
 
 		if tour.TimePublished != nil {
@@ -122,6 +143,12 @@ func (s *TourGRPCServer) GetToursByAuthorID(ctx context.Context, req *pb.GetTour
 		if tour.TimeDrafted != nil {
 			pbTour.TimeDrafted = tour.TimeDrafted.Format(time.RFC3339)
 		}
+		if tour.ScheduledPublishAt != nil {
+			pbTour.ScheduledPublishAt = tour.ScheduledPublishAt.Format(time.RFC3339)
+		}
+		if tour.ScheduledArchiveAt != nil {
+			pbTour.ScheduledArchiveAt = tour.ScheduledArchiveAt.Format(time.RFC3339)
+		}
 
 		pbTours = append(pbTours, pbTour)
 	}
@@ -139,19 +166,24 @@ func (s *TourGRPCServer) GetTourByID(ctx context.Context, req *pb.GetTourByIDReq
 		}
 		return nil, status.Error(codes.Internal, "Failed to retrieve tour: "+err.Error())
 	}
+	services.LocalizeTour(tour, nil, preferredLanguages(ctx))
 
 	res := &pb.TourResponse{
-		Id:           int32(tour.ID),
-		AuthorId:     int32(tour.AuthorID),
-		Name:         tour.Name,
-		Description:  tour.Description,
-		Difficulty:   string(tour.Difficulty),
-		Tags:         tour.Tags,
-		Status:       string(tour.Status),
-		Price:        tour.Price,
-		DrivingStats: &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
-		WalkingStats: &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
-		CyclingStats: &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+		Id:            int32(tour.ID),
+		AuthorId:      int32(tour.AuthorID),
+		Name:          tour.Name,
+		Description:   tour.Description,
+		Difficulty:    string(tour.Difficulty),
+		Tags:          tour.Tags,
+		Status:        string(tour.Status),
+		Price:         tour.Price,
+		DrivingStats:  &pb.DistanceAndDuration{Distance: tour.DrivingStats.Distance, Duration: tour.DrivingStats.Duration},
+		WalkingStats:  &pb.DistanceAndDuration{Distance: tour.WalkingStats.Distance, Duration: tour.WalkingStats.Duration},
+		CyclingStats:  &pb.DistanceAndDuration{Distance: tour.CyclingStats.Distance, Duration: tour.CyclingStats.Duration},
+		Version:       int32(tour.Version),
+		RatingAverage: tour.Rating.Average,
+		ReviewCount:   int32(tour.Rating.Count),
+		Language:      services.TourLanguage(tour),
 	}
 
 	if tour.TimePublished != nil {
@@ -163,13 +195,38 @@ func (s *TourGRPCServer) GetTourByID(ctx context.Context, req *pb.GetTourByIDReq
 	if tour.TimeDrafted != nil {
 		res.TimeDrafted = tour.TimeDrafted.Format(time.RFC3339)
 	}
+	if tour.ScheduledPublishAt != nil {
+		res.ScheduledPublishAt = tour.ScheduledPublishAt.Format(time.RFC3339)
+	}
+	if tour.ScheduledArchiveAt != nil {
+		res.ScheduledArchiveAt = tour.ScheduledArchiveAt.Format(time.RFC3339)
+	}
 
 	return res, nil
 }
 
+// preferredLanguages reads the caller's languages from the accept-language metadata,
+// the gRPC counterpart of the Accept-Language header.
+func preferredLanguages(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	return services.ParseAcceptLanguage(strings.Join(md.Get("accept-language"), ","))
+}
+
 func (s *TourGRPCServer) SetTourPrice(ctx context.Context, req *pb.SetTourPriceRequest) (*pb.SetTourPriceResponse, error) {
-	err := s.tourService.SetTourPrice(int(req.TourId), req.Price, int(req.UserId))
+	var expectedVersion *int
+	if req.ExpectedVersion != nil {
+		version := int(*req.ExpectedVersion)
+		expectedVersion = &version
+	}
+
+	err := s.tourService.SetTourPrice(int(req.TourId), req.Price, int(req.UserId), expectedVersion)
 	if err != nil {
+		if strings.Contains(err.Error(), "version conflict") {
+			return nil, status.Error(codes.Aborted, "Tour was modified by another request")
+		}
 		if strings.Contains(err.Error(), "not found or you are not the author") {
 			return nil, status.Error(codes.PermissionDenied, "Tour not found or you are not the author")
 		}
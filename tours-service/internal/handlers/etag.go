@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag exposes the entity version so clients can send it back in If-Match.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// expectedVersion returns the version the client based its change on, taken from
+// the If-Match header or, if there is none, from the version field of the body.
+func expectedVersion(r *http.Request, bodyVersion *int) (int, bool, error) {
+	if match := strings.TrimSpace(r.Header.Get("If-Match")); match != "" {
+		match = strings.Trim(strings.TrimPrefix(match, "W/"), `"`)
+		version, err := strconv.Atoi(match)
+		if err != nil {
+			return 0, false, errors.New("invalid If-Match header")
+		}
+		return version, true, nil
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true, nil
+	}
+	return 0, false, nil
+}
+
+// writeConflict answers a stale write with 409 and the current state of the entity.
+func writeConflict(w http.ResponseWriter, message string, current interface{}, version int) {
+	setETag(w, version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   message,
+		"current": current,
+	})
+}
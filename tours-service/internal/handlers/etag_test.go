@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	bodyVersion := 4
+
+	tests := []struct {
+		name        string
+		ifMatch     string
+		bodyVersion *int
+		want        int
+		wantOK      bool
+		wantErr     bool
+	}{
+		{name: "nothing sent", want: 0, wantOK: false},
+		{name: "quoted ETag", ifMatch: `"7"`, want: 7, wantOK: true},
+		{name: "bare version", ifMatch: "7", want: 7, wantOK: true},
+		{name: "weak ETag", ifMatch: `W/"12"`, want: 12, wantOK: true},
+		{name: "surrounding spaces", ifMatch: `  "3" `, want: 3, wantOK: true},
+		{name: "zero for documents without a version", ifMatch: `"0"`, want: 0, wantOK: true},
+		{name: "body version", bodyVersion: &bodyVersion, want: 4, wantOK: true},
+		{name: "header wins over body", ifMatch: `"9"`, bodyVersion: &bodyVersion, want: 9, wantOK: true},
+		{name: "wildcard is not a version", ifMatch: "*", wantErr: true},
+		{name: "list of ETags", ifMatch: `"1", "2"`, wantErr: true},
+		{name: "not a number", ifMatch: `"abc"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			got, ok, err := expectedVersion(r, tt.bodyVersion)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expectedVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("expectedVersion() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestWriteConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int
+		wantETag string
+	}{
+		{name: "current version", version: 5, wantETag: `"5"`},
+		{name: "unversioned document", version: 0, wantETag: `"0"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeConflict(w, "Tour was modified by another request", map[string]int{"version": tt.version}, tt.version)
+
+			if w.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+			}
+			if etag := w.Header().Get("ETag"); etag != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", etag, tt.wantETag)
+			}
+			var body struct {
+				Error   string         `json:"error"`
+				Current map[string]int `json:"current"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if body.Error != "Tour was modified by another request" || body.Current["version"] != tt.version {
+				t.Errorf("body = %+v", body)
+			}
+		})
+	}
+}
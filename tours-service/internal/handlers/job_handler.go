@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultJobListLimit = 50
+
+type JobHandler struct {
+	jobService  *services.JobService
+	authService *services.AuthService
+}
+
+func NewJobHandler(jobService *services.JobService, authService *services.AuthService) *JobHandler {
+	return &JobHandler{
+		jobService:  jobService,
+		authService: authService,
+	}
+}
+
+// GetJobs lists background jobs for admins, filtered by ?status= and ?type=, newest
+// first, together with the number of jobs in every status.
+func (h *JobHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authService.ValidateAndGetUserID(r, "Admin"); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	limit := defaultJobListLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			http.Error(w, "Invalid limit, must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	jobs, err := h.jobService.GetJobs(models.JobStatus(r.URL.Query().Get("status")), r.URL.Query().Get("type"), limit)
+	if err != nil {
+		http.Error(w, "Failed to retrieve jobs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(jobs)
+}
+
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authService.ValidateAndGetUserID(r, "Admin"); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(mux.Vars(r)["jobId"])
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobService.GetJobByID(jobID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Job not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve job", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+// RetryJob puts a dead-lettered job back in the queue.
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authService.ValidateAndGetUserID(r, "Admin"); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jobID, err := strconv.Atoi(mux.Vars(r)["jobId"])
+	if err != nil {
+		http.Error(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobService.RetryJob(jobID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Job not found", http.StatusNotFound)
+		} else if strings.Contains(err.Error(), "only dead jobs") || strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to retry job", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+)
+
+// SetKeypointChallenge adds or replaces the quiz, code or photo challenge a tourist has to
+// solve at a keypoint, on top of reaching it, to complete it.
+func (h *KeypointHandler) SetKeypointChallenge(w http.ResponseWriter, r *http.Request) {
+	var challenge models.KeypointChallenge
+	if err := json.NewDecoder(r.Body).Decode(&challenge); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.writeChallenge(w, r, &challenge)
+}
+
+// DeleteKeypointChallenge lets the keypoint complete on proximity alone again.
+func (h *KeypointHandler) DeleteKeypointChallenge(w http.ResponseWriter, r *http.Request) {
+	h.writeChallenge(w, r, nil)
+}
+
+// writeChallenge stores a challenge, or removes it when nil, honouring If-Match.
+func (h *KeypointHandler) writeChallenge(w http.ResponseWriter, r *http.Request, challenge *models.KeypointChallenge) {
+	keypoint, userID, ok := h.authorKeypoint(w, r)
+	if !ok {
+		return
+	}
+	if challenge == nil && keypoint.Challenge == nil {
+		http.Error(w, "Keypoint has no challenge", http.StatusNotFound)
+		return
+	}
+
+	version, ok, err := expectedVersion(r, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		version = keypoint.Version
+	}
+
+	updated, err := h.keypointService.SetChallenge(keypoint.ID, version, userID, challenge)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidChallenge) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.keypointService.GetKeypointByID(keypoint.ID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve keypoint", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Keypoint was modified by another request", current, current.Version)
+		} else {
+			http.Error(w, "Failed to save challenge", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, updated.Version)
+	if challenge == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
@@ -20,14 +20,20 @@ import (
 type KeypointHandler struct {
 	keypointService *services.KeypointService
 	tourService     *services.TourService
+	revisionService *services.TourRevisionService
+	mediaService    *services.KeypointMediaService
 	authService     *services.AuthService 
+	purchaseService *services.PurchaseService
 }
 
-func NewKeypointHandler(keypointService *services.KeypointService, tourService *services.TourService, authService *services.AuthService) *KeypointHandler {
+func NewKeypointHandler(keypointService *services.KeypointService, tourService *services.TourService, revisionService *services.TourRevisionService, mediaService *services.KeypointMediaService, authService *services.AuthService, purchaseService *services.PurchaseService) *KeypointHandler {
 	return &KeypointHandler{
 		keypointService: keypointService,
 		tourService:     tourService,
+		revisionService: revisionService,
+		mediaService:    mediaService,
 		authService:     authService,
+		purchaseService: purchaseService,
 	}
 }
 
@@ -44,6 +50,17 @@ func (h *KeypointHandler) CreateKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.Radius != 0 && !validRadius(req.Radius) {
+		http.Error(w, radiusRangeMessage, http.StatusBadRequest)
+		return
+	}
+	if req.Challenge != nil {
+		if err := services.ValidateChallenge(req.Challenge); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	tour, err := h.tourService.GetTourByID(req.TourID)
 	if err != nil {
 		http.Error(w, "Tour not found", http.StatusNotFound)
@@ -63,9 +80,11 @@ func (h *KeypointHandler) CreateKeypoint(w http.ResponseWriter, r *http.Request)
 		Latitude:    req.Latitude,
 		Longitude:   req.Longitude,
 		Ordinal:     req.Ordinal,
+		Radius:      req.Radius,
+		Challenge:   req.Challenge,
 	}
 
-	err = h.keypointService.CreateKeypoint(keypoint)
+	err = h.keypointService.CreateKeypoint(r.Context(), keypoint, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			http.Error(w, err.Error(), http.StatusConflict)
@@ -75,8 +94,6 @@ func (h *KeypointHandler) CreateKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_ = h.tourService.RecalculateTourLength(r.Context(), keypoint.TourID)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(keypoint)
@@ -92,11 +109,28 @@ func (h *KeypointHandler) GetKeypointsByTourID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	tour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	keypoints, err := h.keypointService.GetKeypointsByTourID(tourID)
 	if err != nil {
 		http.Error(w, "Failed to retrieve keypoints", http.StatusInternalServerError)
 		return
 	}
+	// The author edits the keypoints in the tour's own language, others get them localized.
+	// Media is served through the purchase-gated media endpoint
+	if !isTourAuthor(h.authService, r, tour) {
+		localizeTour(w, r, tour, keypoints)
+		services.GateMedia(keypoints, nil)
+		services.HideChallengeAnswers(keypoints)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -123,11 +157,31 @@ func (h *KeypointHandler) GetKeypointByID(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Keypoints are served in the language negotiated for their tour, except to its author
+	tour, err := h.tourService.GetTourByID(keypoint.TourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Keypoint not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return
+	}
+	localized := []models.Keypoint{*keypoint}
+	if !isTourAuthor(h.authService, r, tour) {
+		localizeTour(w, r, tour, localized)
+		services.GateMedia(localized, nil)
+		services.HideChallengeAnswers(localized)
+	}
+	keypoint = &localized[0]
+
+	setETag(w, keypoint.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(keypoint)
 }
 
+// UpdateKeypoint partially updates a keypoint, guarded by its version like UpdateTour.
 func (h *KeypointHandler) UpdateKeypoint(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	keypointIDStr := vars["keypointId"]
@@ -138,12 +192,17 @@ func (h *KeypointHandler) UpdateKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var keypointUpdate models.CreateKeypointRequest
+	var keypointUpdate models.KeypointUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&keypointUpdate); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if keypointUpdate.Radius != nil && *keypointUpdate.Radius != 0 && !validRadius(*keypointUpdate.Radius) {
+		http.Error(w, radiusRangeMessage, http.StatusBadRequest)
+		return
+	}
+
 	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -167,28 +226,37 @@ func (h *KeypointHandler) UpdateKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	keypointToUpdate := &models.Keypoint{
-		ID:          keypointID,
-		TourID:      existingKeypoint.TourID,
-		Name:        keypointUpdate.Name,
-		Description: keypointUpdate.Description,
-		ImageURL:    keypointUpdate.ImageURL,
-		Latitude:    keypointUpdate.Latitude,
-		Longitude:   keypointUpdate.Longitude,
-		Ordinal:     keypointUpdate.Ordinal,
+	version, ok, err := expectedVersion(r, keypointUpdate.Version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "If-Match header or version is required", http.StatusPreconditionRequired)
+		return
 	}
 
-	err = h.keypointService.UpdateKeypoint(keypointToUpdate)
+	updatedKeypoint, err := h.keypointService.UpdateKeypoint(r.Context(), keypointID, version, userID, &keypointUpdate)
 	if err != nil {
-		http.Error(w, "Failed to update keypoint", http.StatusInternalServerError)
+		if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.keypointService.GetKeypointByID(keypointID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve keypoint", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Keypoint was modified by another request", current, current.Version)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Keypoint not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update keypoint", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	_ = h.tourService.RecalculateTourLength(r.Context(), keypointToUpdate.TourID)
-
+	setETag(w, updatedKeypoint.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(keypointToUpdate)
+	json.NewEncoder(w).Encode(updatedKeypoint)
 }
 
 func (h *KeypointHandler) DeleteKeypoint(w http.ResponseWriter, r *http.Request) {
@@ -224,7 +292,7 @@ func (h *KeypointHandler) DeleteKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.keypointService.DeleteKeypoint(keypointID)
+	err = h.keypointService.DeleteKeypoint(r.Context(), keypointID, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Keypoint not found", http.StatusNotFound)
@@ -234,8 +302,6 @@ func (h *KeypointHandler) DeleteKeypoint(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_ = h.tourService.RecalculateTourLength(r.Context(), existingKeypoint.TourID)
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -243,6 +309,61 @@ func (h *KeypointHandler) DeleteKeypoint(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// ReorderKeypoints takes the full ordered list of keypoint IDs and renumbers the tour's keypoints.
+func (h *KeypointHandler) ReorderKeypoints(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourIDStr := vars["tourId"]
+
+	tourID, err := strconv.Atoi(tourIDStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReorderKeypointsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		http.Error(w, "Tour not found", http.StatusNotFound)
+		return
+	}
+
+	if tour.AuthorID != userID {
+		http.Error(w, "Only tour author can reorder keypoints", http.StatusForbidden)
+		return
+	}
+
+	keypoints, err := h.keypointService.ReorderKeypoints(r.Context(), tourID, userID, req.KeypointIDs)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not match") {
+			current, getErr := h.keypointService.GetKeypointsByTourID(tourID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve keypoints", http.StatusInternalServerError)
+				return
+			}
+			// The order belongs to the tour, so its version tags the keypoint list
+			writeConflict(w, "Keypoints were modified by another request", current, tour.Version)
+		} else {
+			http.Error(w, "Failed to reorder keypoints", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keypoints)
+}
+
 // UploadKeypointImage handles image upload for a specific keypoint
 func (h *KeypointHandler) UploadKeypointImage(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseMultipartForm(32 << 20); err != nil {
@@ -295,8 +416,7 @@ func (h *KeypointHandler) UploadKeypointImage(w http.ResponseWriter, r *http.Req
 	}
 
 	// Update keypoint with new image URL
-	keypoint.ImageURL = photoURL
-	err = h.keypointService.UpdateKeypoint(keypoint)
+	err = h.keypointService.SetKeypointImage(keypoint, photoURL, userID)
 	if err != nil {
 		http.Error(w, "Failed to update keypoint with image URL", http.StatusInternalServerError)
 		return
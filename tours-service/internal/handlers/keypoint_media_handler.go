@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// maxMediaUpload caps the request body of a media upload: the largest file allowed for
+// any media type, plus room for the other form fields.
+var maxMediaUpload = func() int64 {
+	var largest int64
+	for _, limit := range models.MediaLimits {
+		if limit.MaxSize > largest {
+			largest = limit.MaxSize
+		}
+	}
+	return largest + 1<<20
+}()
+
+// loadKeypointAndTour reads the keypoint of the request path together with its tour.
+func (h *KeypointHandler) loadKeypointAndTour(w http.ResponseWriter, r *http.Request) (*models.Keypoint, *models.Tour, bool) {
+	keypointID, err := strconv.Atoi(mux.Vars(r)["keypointId"])
+	if err != nil {
+		http.Error(w, "Invalid keypoint ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	keypoint, err := h.keypointService.GetKeypointByID(keypointID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Keypoint not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve keypoint", http.StatusInternalServerError)
+		}
+		return nil, nil, false
+	}
+
+	tour, err := h.tourService.GetTourByID(keypoint.TourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Keypoint not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return nil, nil, false
+	}
+	return keypoint, tour, true
+}
+
+// authorKeypoint is loadKeypointAndTour for changes, which only the tour's guide may make.
+func (h *KeypointHandler) authorKeypoint(w http.ResponseWriter, r *http.Request) (*models.Keypoint, int, bool) {
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, 0, false
+	}
+
+	keypoint, tour, ok := h.loadKeypointAndTour(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+	if tour.AuthorID != userID {
+		http.Error(w, "Only tour author can modify keypoints", http.StatusForbidden)
+		return nil, 0, false
+	}
+	return keypoint, userID, true
+}
+
+// UploadKeypointMedia adds an image, audio narration or video clip to the end of a
+// keypoint's gallery. The multipart form holds the file, its type and, for audio and
+// video, its duration in seconds, plus an optional caption.
+func (h *KeypointHandler) UploadKeypointMedia(w http.ResponseWriter, r *http.Request) {
+	// Only the author's uploads are worth reading the body of
+	keypoint, userID, ok := h.authorKeypoint(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Media file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Error parsing form data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediaType := models.MediaType(r.FormValue("type"))
+	var duration float64
+	if value := r.FormValue("duration"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			http.Error(w, "Invalid duration, use seconds", http.StatusBadRequest)
+			return
+		}
+		duration = parsed
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No media file uploaded", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if err := services.ValidateMedia(mediaType, header.Size, duration); err != nil {
+		writeMediaError(w, err)
+		return
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	mimeType, err := services.DetectMimeType(mediaType, head[:n])
+	if err != nil {
+		writeMediaError(w, err)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read media file", http.StatusInternalServerError)
+		return
+	}
+
+	// Refuse early when the gallery is full, AddMedia checks again atomically
+	limit, _ := services.MediaLimit(mediaType)
+	count := 0
+	for _, media := range keypoint.Media {
+		if media.Type == mediaType {
+			count++
+		}
+	}
+	if count >= limit.MaxCount {
+		writeMediaError(w, fmt.Errorf("%w: a keypoint can hold at most %d %s items", services.ErrInvalidMedia, limit.MaxCount, mediaType))
+		return
+	}
+
+	mediaURL, err := h.uploadKeypointMediaToService(file, header.Filename, mediaType, keypoint.ID, keypoint.TourID)
+	if err != nil {
+		http.Error(w, "Failed to upload media: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	media := &models.KeypointMedia{
+		Type:     mediaType,
+		URL:      mediaURL,
+		MimeType: mimeType,
+		Size:     header.Size,
+		Duration: duration,
+		Caption:  strings.TrimSpace(r.FormValue("caption")),
+	}
+	if err := h.keypointService.AddMedia(keypoint, media, userID); err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(media)
+}
+
+// GetKeypointMedia serves the gallery of a keypoint to the tour's guide, and to tourists
+// who purchased the tour and reached the keypoint during one of their executions.
+// Tourists get the gallery of the revision they bought, like the purchased keypoints.
+func (h *KeypointHandler) GetKeypointMedia(w http.ResponseWriter, r *http.Request) {
+	keypoint, tour, ok := h.loadKeypointAndTour(w, r)
+	if !ok {
+		return
+	}
+	media := keypoint.Media
+
+	if !isTourAuthor(h.authService, r, tour) {
+		touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		purchasedAt, err := h.purchaseService.GetPurchaseTime(r, tour.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if purchasedAt == nil {
+			http.Error(w, "You must purchase the tour to see keypoint media", http.StatusForbidden)
+			return
+		}
+		unlocked, err := h.mediaService.UnlockedKeypoints(tour.ID, touristID)
+		if err != nil {
+			http.Error(w, "Failed to check unlocked keypoints", http.StatusInternalServerError)
+			return
+		}
+		if !unlocked[keypoint.ID] {
+			http.Error(w, "Reach this keypoint during the tour to unlock its media", http.StatusForbidden)
+			return
+		}
+
+		revision, err := h.revisionService.GetRevisionForTourist(tour.ID, touristID, *purchasedAt)
+		if err != nil {
+			http.Error(w, "Failed to retrieve purchased revision", http.StatusInternalServerError)
+			return
+		}
+		if revision != nil && revision.Snapshot != nil {
+			pinned := false
+			for _, snapshotKeypoint := range revision.Snapshot.Keypoints {
+				if snapshotKeypoint.ID == keypoint.ID {
+					media, pinned = snapshotKeypoint.Media, true
+				}
+			}
+			if !pinned {
+				http.Error(w, "Keypoint not found in your revision of the tour", http.StatusNotFound)
+				return
+			}
+		}
+	}
+
+	if media == nil {
+		media = []models.KeypointMedia{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keypointId": keypoint.ID,
+		"media":      media,
+	})
+}
+
+// DeleteKeypointMedia removes one item from a keypoint's gallery. The file itself stays
+// with image-service, earlier revisions may still point to it.
+func (h *KeypointHandler) DeleteKeypointMedia(w http.ResponseWriter, r *http.Request) {
+	mediaID, err := strconv.Atoi(mux.Vars(r)["mediaId"])
+	if err != nil {
+		http.Error(w, "Invalid media ID", http.StatusBadRequest)
+		return
+	}
+
+	keypoint, userID, ok := h.authorKeypoint(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.keypointService.DeleteMedia(keypoint, mediaID, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Media not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete media", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReorderKeypointMedia takes the full ordered list of media IDs of a keypoint's gallery.
+func (h *KeypointHandler) ReorderKeypointMedia(w http.ResponseWriter, r *http.Request) {
+	var req models.ReorderMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	keypoint, userID, ok := h.authorKeypoint(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.keypointService.ReorderMedia(keypoint, req.MediaIDs, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMedia) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.keypointService.GetKeypointByID(keypoint.ID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve keypoint", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Keypoint was modified by another request", current, current.Version)
+		} else {
+			http.Error(w, "Failed to reorder media", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, updated.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated.Media)
+}
+
+func writeMediaError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidMedia) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "not found") {
+		http.Error(w, "Keypoint not found", http.StatusNotFound)
+	} else {
+		http.Error(w, "Failed to save media", http.StatusInternalServerError)
+	}
+}
+
+func (h *KeypointHandler) uploadKeypointMediaToService(file io.Reader, filename string, mediaType models.MediaType, keypointId int, tourId int) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	if _, err = io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	writer.WriteField("type", string(mediaType))
+	writer.WriteField("keypointId", strconv.Itoa(keypointId))
+	writer.WriteField("tourId", strconv.Itoa(tourId))
+	writer.Close()
+
+	req, err := http.NewRequest(
+		"POST",
+		os.Getenv("IMAGE_SERVICE_URL")+"/api/saveKeypointMedia",
+		&body,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to image service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("image service responded with error: %s", string(respBody))
+	}
+
+	var result struct {
+		MediaURL string `json:"mediaURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response from image service: %w", err)
+	}
+
+	return result.MediaURL, nil
+}
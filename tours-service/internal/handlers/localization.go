@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+)
+
+// preferredLanguages reads the languages the caller accepts, most preferred first, and
+// marks the response as varying with them.
+func preferredLanguages(w http.ResponseWriter, r *http.Request) []string {
+	w.Header().Add("Vary", "Accept-Language")
+	return services.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// localizeTour serves a single tour and its keypoints in the negotiated language and
+// announces it in Content-Language.
+func localizeTour(w http.ResponseWriter, r *http.Request, tour *models.Tour, keypoints []models.Keypoint) {
+	language := services.LocalizeTour(tour, keypoints, preferredLanguages(w, r))
+	w.Header().Set("Content-Language", language)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// SubmitChallengeAnswer answers the challenge of a keypoint the tourist reached during
+// their execution of the tour. Quizzes and codes take a JSON body with the answer, photo
+// challenges a multipart form with the photo. The attempt is recorded on the execution
+// and the keypoint is completed once the challenge is solved.
+func (h *TourExecutionHandler) SubmitChallengeAnswer(w http.ResponseWriter, r *http.Request) {
+	userId, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourId, err := strconv.Atoi(vars["tour_id"])
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	keypointId, err := strconv.Atoi(vars["keypoint_id"])
+	if err != nil {
+		http.Error(w, "Invalid or missing keypoint_id", http.StatusBadRequest)
+		return
+	}
+
+	var answer, photoURL string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		photoURL, err = h.uploadChallengePhoto(w, r, tourId, userId, keypointId)
+		if err != nil {
+			return
+		}
+	} else {
+		var req models.ChallengeAnswerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		answer = req.Answer
+	}
+
+	result, httpStatus, err := h.tourExecutionService.SubmitChallengeAnswer(tourId, userId, keypointId, answer, photoURL)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// uploadChallengePhoto checks the photo of a multipart challenge answer and stores it with
+// image-service. It writes the error response itself and returns a non-nil error then.
+func (h *TourExecutionHandler) uploadChallengePhoto(w http.ResponseWriter, r *http.Request, tourId, userId, keypointId int) (string, error) {
+	limit, _ := services.MediaLimit(models.MediaImage)
+	r.Body = http.MaxBytesReader(w, r.Body, limit.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(limit.MaxSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Photo is too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Error parsing form data: "+err.Error(), http.StatusBadRequest)
+		}
+		return "", err
+	}
+
+	file, header, err := r.FormFile("photo")
+	if err != nil {
+		http.Error(w, "No photo uploaded", http.StatusBadRequest)
+		return "", err
+	}
+	defer file.Close()
+
+	if err := services.ValidateMedia(models.MediaImage, header.Size, 0); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := services.DetectMimeType(models.MediaImage, head[:n]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read photo", http.StatusInternalServerError)
+		return "", err
+	}
+
+	// Refuse early so photos of rejected attempts are not stored, the submission checks again
+	if _, _, httpStatus, err := h.tourExecutionService.PendingChallenge(tourId, userId, keypointId); err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return "", err
+	}
+
+	photoURL, err := uploadChallengePhotoToService(file, header.Filename, keypointId, userId)
+	if err != nil {
+		http.Error(w, "Failed to upload photo: "+err.Error(), http.StatusBadGateway)
+		return "", err
+	}
+	return photoURL, nil
+}
+
+func uploadChallengePhotoToService(file io.Reader, filename string, keypointId int, userId int) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("image", filepath.Base(filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	if _, err = io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	writer.WriteField("keypointId", strconv.Itoa(keypointId))
+	writer.WriteField("userId", strconv.Itoa(userId))
+	writer.Close()
+
+	req, err := http.NewRequest(
+		"POST",
+		os.Getenv("IMAGE_SERVICE_URL")+"/api/saveChallengePhoto",
+		&body,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to image service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("image service responded with error: %s", string(respBody))
+	}
+
+	var result struct {
+		PhotoURL string `json:"photoURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response from image service: %w", err)
+	}
+
+	return result.PhotoURL, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"tours-service/internal/models"
 	"tours-service/internal/services"
 
@@ -61,6 +63,33 @@ func (h *TourExecutionHandler) StartTourExecution(w http.ResponseWriter, r *http
 	}
 }
 
+// ResumeExecution continues an execution that was abandoned for inactivity.
+func (h *TourExecutionHandler) ResumeExecution(w http.ResponseWriter, r *http.Request) {
+	userId, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tour_id"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	execution, httpStatus, err := h.tourExecutionService.ResumeExecution(tourId, userId)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(execution); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *TourExecutionHandler) GetExecutionsByUser(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
 	if err != nil {
@@ -106,6 +135,33 @@ func (h *TourExecutionHandler) GetMyExecutionByTourID(w http.ResponseWriter, r *
 	}
 }
 
+// GetMyExecutionHistoryByTourID lists every execution of the tour by the tourist, newest first.
+func (h *TourExecutionHandler) GetMyExecutionHistoryByTourID(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tour_id"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	executions, httpStatus, err := h.tourExecutionService.GetMyExecutionHistoryByTourID(userID, tourId)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(executions); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *TourExecutionHandler) AbortExecution(w http.ResponseWriter, r *http.Request) {
 	userId, err := h.authService.ValidateAndGetUserID(r, "Tourist")
 	if err != nil {
@@ -139,7 +195,7 @@ func (h *TourExecutionHandler) CheckIsKeyPointReached(w http.ResponseWriter, r *
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	long, lat, err := h.authService.GetMyPosition(r)
+	fix, err := h.authService.GetMyPositionFix(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -151,26 +207,246 @@ func (h *TourExecutionHandler) CheckIsKeyPointReached(w http.ResponseWriter, r *
 		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
 		return
 	}
-	var keyPoint *models.Keypoint
-	httpStatus, keyPoint, finished, err := h.tourExecutionService.CheckIsKeyPointReached(tourId, userId, long, lat)
+	httpStatus, reached, finished, err := h.tourExecutionService.CheckIsKeyPointReached(tourId, userId, fix)
 	if err != nil {
 		http.Error(w, err.Error(), httpStatus)
 		return
 	}
-	if keyPoint == nil {
+	if len(reached) == 0 {
 		http.Error(w, "key point not found", http.StatusInternalServerError)
 		return
 	}
 
+	names := make([]string, 0, len(reached))
+	reachedKeyPoints := make([]map[string]interface{}, 0, len(reached))
+	for _, keyPoint := range reached {
+		names = append(names, keyPoint.Name)
+		// Reaching a keypoint unlocks its media, so the audio guide can start right away
+		reachedKeyPoints = append(reachedKeyPoints, map[string]interface{}{
+			"id":    keyPoint.ID,
+			"name":  keyPoint.Name,
+			"media": keyPoint.Media,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	resp := map[string]interface{}{
+		"message":          "Key point with name: " + strings.Join(names, ", ") + " reached successfully",
+		"keyPointId":       strconv.Itoa(reached[0].ID),
+		"tourFinished":     strconv.FormatBool(finished),
+		"reachedKeyPoints": reachedKeyPoints,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *TourExecutionHandler) CheckIsOnRoute(w http.ResponseWriter, r *http.Request) {
+	userId, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	fix, err := h.authService.GetMyPositionFix(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tour_id"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	httpStatus, onRoute, distance, err := h.tourExecutionService.CheckOnRoute(tourId, userId, fix)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(httpStatus)
-	resp := map[string]string{
-		"message":      "Key point with name: " + keyPoint.Name + " reached successfully",
-		"keyPointId":   strconv.Itoa(keyPoint.ID),
-		"tourFinished": strconv.FormatBool(finished),
+	resp := map[string]interface{}{
+		"onRoute":           onRoute,
+		"distanceFromRoute": distance,
 	}
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "failed to write response", http.StatusInternalServerError)
 		return
 	}
 }
+
+// GetExecutionsByTour lists every execution of one of the guide's tours.
+func (h *TourExecutionHandler) GetExecutionsByTour(w http.ResponseWriter, r *http.Request) {
+	guideID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tour_id"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	executions, httpStatus, err := h.tourExecutionService.GetExecutionsByTour(tourId, guideID)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(executions); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetExecutionTrail serves the recorded position trail of an execution for replay, to
+// the tourist who walked it or to the author of the tour.
+func (h *TourExecutionHandler) GetExecutionTrail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	executionIdStr := vars["execution_id"]
+	executionId, err := strconv.Atoi(executionIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing execution_id", http.StatusBadRequest)
+		return
+	}
+
+	asGuide := false
+	userID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		userID, err = h.authService.ValidateAndGetUserID(r, "Guide")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		asGuide = true
+	}
+
+	trail, httpStatus, err := h.tourExecutionService.GetExecutionTrail(executionId, userID, asGuide)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(trail); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ReportPosition is the request/response counterpart of the execution stream for
+// clients that follow events over SSE: it takes a position fix, completes keypoints in
+// range and answers with the distance to the next keypoint.
+func (h *TourExecutionHandler) ReportPosition(w http.ResponseWriter, r *http.Request) {
+	userId, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tour_id"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid or missing tour_id", http.StatusBadRequest)
+		return
+	}
+	var fix models.PositionFix
+	if err := json.NewDecoder(r.Body).Decode(&fix); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if fix.Latitude < -90 || fix.Latitude > 90 || fix.Longitude < -180 || fix.Longitude > 180 {
+		http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+		return
+	}
+	if fix.Accuracy != nil && *fix.Accuracy < 0 {
+		http.Error(w, "Accuracy must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	next, httpStatus, err := h.tourExecutionService.ProcessPosition(tourId, userId, &fix)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	resp := map[string]interface{}{
+		"tourFinished": next == nil,
+		"next":         next,
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetTourAnalytics serves execution analytics of a tour to its author. ?from= and ?to=
+// limit them to executions started in that range, as dates (to is inclusive) or RFC 3339
+// timestamps.
+func (h *TourExecutionHandler) GetTourAnalytics(w http.ResponseWriter, r *http.Request) {
+	guideID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	tourIdStr := vars["tourId"]
+	tourId, err := strconv.Atoi(tourIdStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+	from, err := parseAnalyticsBound(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, "Invalid from, use YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	to, err := parseAnalyticsBound(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, "Invalid to, use YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	analytics, httpStatus, err := h.tourExecutionService.GetTourAnalytics(tourId, guideID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if err := json.NewEncoder(w).Encode(analytics); err != nil {
+		http.Error(w, "failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseAnalyticsBound parses a date range bound. A plain date as upper bound covers the
+// whole day, so the returned time is the start of the next one.
+func parseAnalyticsBound(value string, upper bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TourFileHandler struct {
+	fileService     *services.TourFileService
+	tourService     *services.TourService
+	keypointService *services.KeypointService
+	revisionService *services.TourRevisionService
+	authService     *services.AuthService
+	purchaseService *services.PurchaseService
+}
+
+func NewTourFileHandler(fileService *services.TourFileService, tourService *services.TourService, keypointService *services.KeypointService, revisionService *services.TourRevisionService, authService *services.AuthService, purchaseService *services.PurchaseService) *TourFileHandler {
+	return &TourFileHandler{
+		fileService:     fileService,
+		tourService:     tourService,
+		keypointService: keypointService,
+		revisionService: revisionService,
+		authService:     authService,
+		purchaseService: purchaseService,
+	}
+}
+
+// ImportTour creates a draft tour from an uploaded GPX or KML file ("file" form field).
+func (h *TourFileHandler) ImportTour(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxImportSize+1<<20)
+	if err := r.ParseMultipartForm(services.MaxImportSize); err != nil {
+		http.Error(w, "Error parsing form data: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file uploaded", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > services.MaxImportSize {
+		http.Error(w, fmt.Sprintf("File is larger than %d bytes", services.MaxImportSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxImportSize+1))
+	if err != nil {
+		http.Error(w, "Error reading uploaded file", http.StatusBadRequest)
+		return
+	}
+
+	tour, err := h.fileService.ImportTour(userID, format, data, r.FormValue("name"), models.TourDifficulty(r.FormValue("difficulty")))
+	if err != nil {
+		if strings.Contains(err.Error(), "larger than") {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		} else if strings.Contains(err.Error(), "invalid file") || strings.Contains(err.Error(), "at least two keypoints") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to import tour: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// ExportTour serves a tour as ?format=gpx|kml|geojson, with the track following
+// ?profile= (foot-walking by default). Guides can export their own tours, tourists the
+// revision of a tour they bought.
+func (h *TourFileHandler) ExportTour(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = services.FormatGPX
+	}
+	if format != services.FormatGPX && format != services.FormatKML && format != services.FormatGeoJSON {
+		http.Error(w, "Unsupported format, use gpx, kml or geojson", http.StatusBadRequest)
+		return
+	}
+
+	profile, ok := routeProfile(w, r)
+	if !ok {
+		return
+	}
+
+	tour, keypoints, ok := h.loadTourForCaller(w, r)
+	if !ok {
+		return
+	}
+	localizeTour(w, r, tour, keypoints)
+
+	data, contentType, err := h.fileService.ExportTour(tour, keypoints, format, profile)
+	if err != nil {
+		http.Error(w, "Failed to export tour: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"tour-%d.%s\"", tour.ID, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetRoute serves the route of a tour for ?profile= as an encoded polyline, or as a
+// GeoJSON LineString with ?format=geojson. Access rules are the same as for export.
+func (h *TourFileHandler) GetRoute(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format != "" && format != "polyline" && format != services.FormatGeoJSON {
+		http.Error(w, "Unsupported format, use polyline or geojson", http.StatusBadRequest)
+		return
+	}
+
+	profile, ok := routeProfile(w, r)
+	if !ok {
+		return
+	}
+
+	tour, keypoints, ok := h.loadTourForCaller(w, r)
+	if !ok {
+		return
+	}
+	localizeTour(w, r, tour, keypoints)
+
+	if format == services.FormatGeoJSON {
+		data, err := services.RouteGeoJSON(tour, profile, h.tourService.RouteLine(tour, keypoints, profile))
+		if err != nil {
+			http.Error(w, "Failed to encode route", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.tourService.RouteForProfile(tour, keypoints, profile))
+}
+
+func routeProfile(w http.ResponseWriter, r *http.Request) (string, bool) {
+	profile := r.URL.Query().Get("profile")
+	if profile == "" {
+		return models.ProfileWalking, true
+	}
+	if !services.IsRouteProfile(profile) {
+		http.Error(w, "Unsupported profile, use foot-walking, cycling-regular or driving-car", http.StatusBadRequest)
+		return "", false
+	}
+	return profile, true
+}
+
+// loadTourForCaller returns the tour from the URL with its keypoints in route order.
+// Guides get their own tours, tourists the revision of a tour they bought.
+func (h *TourFileHandler) loadTourForCaller(w http.ResponseWriter, r *http.Request) (*models.Tour, []models.Keypoint, bool) {
+	tourID, err := strconv.Atoi(mux.Vars(r)["tourId"])
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	tour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return nil, nil, false
+	}
+
+	if userID, err := h.authService.ValidateAndGetUserID(r, "Guide"); err == nil {
+		if tour.AuthorID != userID {
+			http.Error(w, "Only the tour author can access this tour", http.StatusForbidden)
+			return nil, nil, false
+		}
+		keypoints, err := h.keypointService.GetKeypointsByTourID(tourID)
+		if err != nil {
+			http.Error(w, "Failed to retrieve keypoints", http.StatusInternalServerError)
+			return nil, nil, false
+		}
+		return tour, keypoints, true
+	}
+
+	touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	purchasedAt, err := h.purchaseService.GetPurchaseTime(r, tourID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if purchasedAt == nil {
+		http.Error(w, "You must purchase the tour first", http.StatusForbidden)
+		return nil, nil, false
+	}
+	revision, err := h.revisionService.GetRevisionForTourist(tourID, touristID, *purchasedAt)
+	if err != nil {
+		http.Error(w, "Failed to retrieve purchased revision", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if revision != nil && revision.Snapshot != nil {
+		return revision.Snapshot, revision.Snapshot.Keypoints, true
+	}
+	keypoints, err := h.keypointService.GetKeypointsByTourID(tourID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve keypoints", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return tour, keypoints, true
+}
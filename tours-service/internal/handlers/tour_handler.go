@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,19 +12,28 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// tourDetailReviews is how many reviews tour details embed
+const tourDetailReviews = 3
+
 type TourHandler struct {
 	tourService      *services.TourService
 	keypointService  *services.KeypointService
 	reviewService    *services.TourReviewService
+	revisionService  *services.TourRevisionService
+	mediaService     *services.KeypointMediaService
 	authService      *services.AuthService
+	purchaseService  *services.PurchaseService
 }
 
-func NewTourHandler(tourService *services.TourService, keypointService *services.KeypointService, reviewService *services.TourReviewService, authService *services.AuthService) *TourHandler {
+func NewTourHandler(tourService *services.TourService, keypointService *services.KeypointService, reviewService *services.TourReviewService, revisionService *services.TourRevisionService, mediaService *services.KeypointMediaService, authService *services.AuthService, purchaseService *services.PurchaseService) *TourHandler {
 	return &TourHandler{
 		tourService:      tourService,
 		keypointService:  keypointService,
 		reviewService:    reviewService,
+		revisionService:  revisionService,
+		mediaService:     mediaService,
 		authService:      authService,
+		purchaseService:  purchaseService,
 	}
 }
 
@@ -45,6 +56,32 @@ func (h *TourHandler) CreateTour(w http.ResponseWriter, r *http.Request) {
 	tour.AuthorID = userID
 	tour.Status = models.StatusDraft
 	tour.Price = 0.0
+	if tour.CompletionMode == "" {
+		tour.CompletionMode = models.CompletionOrdered
+	} else if !validCompletionMode(tour.CompletionMode) {
+		http.Error(w, "Invalid completion mode, use ordered or free_roam", http.StatusBadRequest)
+		return
+	}
+	if tour.DefaultRadius != 0 && !validRadius(tour.DefaultRadius) {
+		http.Error(w, radiusRangeMessage, http.StatusBadRequest)
+		return
+	}
+	for _, keypoint := range keypoints {
+		if keypoint.Radius != 0 && !validRadius(keypoint.Radius) {
+			http.Error(w, radiusRangeMessage, http.StatusBadRequest)
+			return
+		}
+		if keypoint.Challenge != nil {
+			if err := services.ValidateChallenge(keypoint.Challenge); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if err := normalizeTourLanguages(tour, keypoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	err = h.tourService.CreateTour(tour, keypoints)
 	if err != nil {
@@ -64,17 +101,56 @@ func (h *TourHandler) CreateTour(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *TourHandler) GetPublishedToursWithFirstKeypoint(w http.ResponseWriter, r *http.Request) {
-	toursWithKeypoints, err := h.tourService.GetPublishedToursWithFirstKeypoint()
+	query, err := parseTourListQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	toursWithKeypoints, err := h.tourService.GetPublishedToursWithFirstKeypoint(query)
 	if err != nil {
 		http.Error(w, "Failed to retrieve published tours", http.StatusInternalServerError)
 		return
 	}
 
+	preferred := preferredLanguages(w, r)
+	for i := range toursWithKeypoints {
+		language := services.LocalizeTour(&toursWithKeypoints[i].Tour, nil, preferred)
+		services.LocalizeKeypoint(&toursWithKeypoints[i].FirstKeypoint, language)
+		toursWithKeypoints[i].FirstKeypoint.Media = nil
+		toursWithKeypoints[i].FirstKeypoint.Challenge = nil
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(toursWithKeypoints)
 }
 
+// parseTourListQuery reads the sort, minRating and minReviews query parameters.
+func parseTourListQuery(r *http.Request) (models.TourListQuery, error) {
+	values := r.URL.Query()
+	query := models.TourListQuery{Sort: values.Get("sort")}
+
+	if query.Sort != "" && query.Sort != models.TourSortRating && query.Sort != models.TourSortReviews {
+		return query, fmt.Errorf("sort must be %q or %q", models.TourSortRating, models.TourSortReviews)
+	}
+	if raw := values.Get("minRating"); raw != "" {
+		minRating, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minRating < 0 || minRating > 5 {
+			return query, errors.New("minRating must be a number between 0 and 5")
+		}
+		query.MinRating = minRating
+	}
+	if raw := values.Get("minReviews"); raw != "" {
+		minReviews, err := strconv.Atoi(raw)
+		if err != nil || minReviews < 0 {
+			return query, errors.New("minReviews must be a non-negative integer")
+		}
+		query.MinReviews = minReviews
+	}
+	return query, nil
+}
+
 func (h *TourHandler) SetTourPrice(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourIDStr := vars["tourId"]
@@ -92,7 +168,8 @@ func (h *TourHandler) SetTourPrice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var priceRequest struct {
-		Price float64 `json:"price"`
+		Price   float64 `json:"price"`
+		Version *int    `json:"version,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&priceRequest); err != nil {
@@ -100,9 +177,27 @@ func (h *TourHandler) SetTourPrice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tourService.SetTourPrice(tourID, priceRequest.Price, userID)
+	// The version check is optional here, a price change never clobbers other fields
+	version, ok, err := expectedVersion(r, priceRequest.Version)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found or you are not the author") {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var versionPtr *int
+	if ok {
+		versionPtr = &version
+	}
+
+	err = h.tourService.SetTourPrice(tourID, priceRequest.Price, userID, versionPtr)
+	if err != nil {
+		if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.tourService.GetTourByID(tourID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Tour was modified by another request", current, current.Version)
+		} else if strings.Contains(err.Error(), "not found or you are not the author") {
 			http.Error(w, "Tour not found or you are not the author", http.StatusForbidden)
 		} else {
 			http.Error(w, "Failed to update tour price: " + err.Error(), http.StatusInternalServerError)
@@ -117,6 +212,8 @@ func (h *TourHandler) SetTourPrice(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetToursByAuthor lists the calling guide's own tours. Guides edit their tours, so
+// these stay in the language they were written in.
 func (h *TourHandler) GetToursByAuthor(w http.ResponseWriter, r *http.Request) {
 	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
 	if err != nil {
@@ -135,6 +232,16 @@ func (h *TourHandler) GetToursByAuthor(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tours)
 }
 
+// isTourAuthor reports whether the caller is the guide who wrote the tour. Anonymous
+// callers are answered without asking auth-service.
+func isTourAuthor(authService *services.AuthService, r *http.Request, tour *models.Tour) bool {
+	if r.Header.Get("Authorization") == "" {
+		return false
+	}
+	userID, err := authService.ValidateAndGetUserID(r, "Guide")
+	return err == nil && userID == tour.AuthorID
+}
+
 func (h *TourHandler) GetTourByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourIDStr := vars["tourId"]
@@ -154,12 +261,19 @@ func (h *TourHandler) GetTourByID(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	// The author's editing view stays in the tour's own language
+	if !isTourAuthor(h.authService, r, tour) {
+		localizeTour(w, r, tour, nil)
+	}
 
+	setETag(w, tour.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(tour)
 }
 
+// UpdateTour partially updates a tour. The client must send the version it edited,
+// either as If-Match or in the body, and gets 409 with the current tour if it is stale.
 func (h *TourHandler) UpdateTour(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourIDStr := vars["tourId"]
@@ -170,12 +284,29 @@ func (h *TourHandler) UpdateTour(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var tour models.Tour
-	if err := json.NewDecoder(r.Body).Decode(&tour); err != nil {
+	var update models.TourUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
+	if update.Difficulty != nil {
+		switch *update.Difficulty {
+		case models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard:
+		default:
+			http.Error(w, "Invalid difficulty", http.StatusBadRequest)
+			return
+		}
+	}
+	if update.CompletionMode != nil && !validCompletionMode(*update.CompletionMode) {
+		http.Error(w, "Invalid completion mode, use ordered or free_roam", http.StatusBadRequest)
+		return
+	}
+	if update.DefaultRadius != nil && *update.DefaultRadius != 0 && !validRadius(*update.DefaultRadius) {
+		http.Error(w, radiusRangeMessage, http.StatusBadRequest)
+		return
+	}
+
 	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
@@ -193,17 +324,49 @@ func (h *TourHandler) UpdateTour(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tour.ID = tourID
-	tour.AuthorID = existingTour.AuthorID
-	tour.Status = existingTour.Status
-	tour.Price = existingTour.Price
+	if update.Language != nil {
+		language, ok := services.NormalizeLanguage(*update.Language)
+		if !ok {
+			http.Error(w, "Invalid language tag", http.StatusBadRequest)
+			return
+		}
+		if _, exists := existingTour.Translations[language]; exists {
+			http.Error(w, "The tour has a translation in this language, delete it before making it the default", http.StatusBadRequest)
+			return
+		}
+		update.Language = &language
+	}
 
-	err = h.tourService.UpdateTour(&tour)
+	version, ok, err := expectedVersion(r, update.Version)
 	if err != nil {
-		http.Error(w, "Failed to update tour", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "If-Match header or version is required", http.StatusPreconditionRequired)
 		return
 	}
 
+	tour, err := h.tourService.UpdateTour(tourID, version, &update)
+	if err != nil {
+		if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.tourService.GetTourByID(tourID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Tour was modified by another request", current, current.Version)
+		} else if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to update tour", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	setETag(w, tour.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(tour)
@@ -236,6 +399,17 @@ func (h *TourHandler) DeleteTour(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Tourists who paid keep the tour, it can only be archived
+	purchases, err := h.purchaseService.CountTourPurchases(r, tourID)
+	if err != nil {
+		http.Error(w, "Failed to check purchases of the tour: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if purchases > 0 {
+		http.Error(w, "Tour has been purchased and can only be archived", http.StatusConflict)
+		return
+	}
+
 	err = h.tourService.DeleteTour(tourID)
 	if err != nil {
 		http.Error(w, "Failed to delete tour", http.StatusInternalServerError)
@@ -245,10 +419,184 @@ func (h *TourHandler) DeleteTour(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Tour and its keypoints deleted successfully",
+		"message": "Tour deleted, it can be restored until it is purged",
 	})
 }
 
+// ScheduleTour sets when the tour is published and archived automatically, e.g.
+// {"publishAt": "2025-05-01T08:00:00Z", "archiveAt": "2025-09-30T20:00:00Z"}. A missing
+// or null time clears that schedule.
+func (h *TourHandler) ScheduleTour(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourIDStr := vars["tourId"]
+
+	tourID, err := strconv.Atoi(tourIDStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var scheduleRequest models.TourScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&scheduleRequest); err != nil {
+		http.Error(w, "Invalid request body, times use RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	existingTour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return
+	}
+	if existingTour.AuthorID != userID {
+		http.Error(w, "Only the tour author can schedule a tour", http.StatusForbidden)
+		return
+	}
+
+	tour, err := h.tourService.ScheduleTour(existingTour, scheduleRequest.PublishAt, scheduleRequest.ArchiveAt)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to schedule tour", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// CloneTour duplicates one of the guide's tours into a new draft, optionally named by
+// {"name": "..."} in the body.
+func (h *TourHandler) CloneTour(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourIDStr := vars["tourId"]
+
+	tourID, err := strconv.Atoi(tourIDStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var cloneRequest struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&cloneRequest); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	existingTour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		http.Error(w, "Tour not found", http.StatusNotFound)
+		return
+	}
+	if existingTour.AuthorID != userID {
+		http.Error(w, "Only tour author can clone a tour", http.StatusForbidden)
+		return
+	}
+
+	tour, err := h.tourService.CloneTour(tourID, strings.TrimSpace(cloneRequest.Name))
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "at least two keypoints") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to clone tour: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// GetDeletedTours lists the guide's soft deleted tours that can still be restored.
+func (h *TourHandler) GetDeletedTours(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tours, err := h.tourService.GetDeletedToursByAuthorID(userID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve deleted tours", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tours)
+}
+
+// RestoreDeletedTour brings a soft deleted tour back as a draft.
+func (h *TourHandler) RestoreDeletedTour(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourIDStr := vars["tourId"]
+
+	tourID, err := strconv.Atoi(tourIDStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	deletedTour, err := h.tourService.GetDeletedTourByID(tourID)
+	if err != nil {
+		http.Error(w, "Deleted tour not found", http.StatusNotFound)
+		return
+	}
+	if deletedTour.AuthorID != userID {
+		http.Error(w, "Only tour author can restore a tour", http.StatusForbidden)
+		return
+	}
+
+	tour, err := h.tourService.RestoreDeletedTour(tourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Deleted tour not found", http.StatusNotFound)
+		} else if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, "A tour with this name already exists, rename it before restoring this one", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to restore tour", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tour)
+}
+
 func (h *TourHandler) PublishTour(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourIDStr := vars["tourId"]
@@ -280,7 +628,7 @@ func (h *TourHandler) PublishTour(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tourService.PublishTour(tourID)
+	err = h.tourService.PublishTour(tourID, userID)
 	if err != nil {
 		http.Error(w, "Failed to publish tour: " + err.Error(), http.StatusInternalServerError)
 		return
@@ -324,7 +672,7 @@ func (h *TourHandler) ArchiveTour(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tourService.ArchiveTour(tourID)
+	err = h.tourService.ArchiveTour(tourID, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "only be archived if") {
 			http.Error(w, err.Error(), http.StatusConflict)
@@ -375,14 +723,17 @@ func (h *TourHandler) GetTourForTourist(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to retrieve keypoints", http.StatusInternalServerError)
 		return
 	}
+	localizeTour(w, r, tour, keypoints)
+	services.GateMedia(keypoints, nil)
+	services.HideChallengeAnswers(keypoints)
 
 	var firstKeypoint *models.Keypoint
 	if len(keypoints) > 0 {
 		firstKeypoint = &keypoints[0]
 	}
 
-	// Get reviews for the tour
-	reviews, err := h.reviewService.GetReviewsByTourID(tourID)
+	// Only the best few reviews, the rest are paged through the reviews endpoint
+	reviews, err := h.reviewService.GetTopReviews(tourID, tourDetailReviews)
 	if err != nil {
 		http.Error(w, "Failed to retrieve reviews", http.StatusInternalServerError)
 		return
@@ -394,6 +745,7 @@ func (h *TourHandler) GetTourForTourist(w http.ResponseWriter, r *http.Request)
 			"id":          tour.ID,
 			"name":        tour.Name,
 			"description": tour.Description,
+			"language":    tour.Language,
 			"difficulty":  tour.Difficulty,
 			"tags":        tour.Tags,
 			"price":       tour.Price,
@@ -403,7 +755,8 @@ func (h *TourHandler) GetTourForTourist(w http.ResponseWriter, r *http.Request)
 			"cyclingStats": tour.CyclingStats,
 		},
 		"firstKeypoint": firstKeypoint,
-		"reviews":       reviews,
+		"rating":        tour.Rating,
+		"topReviews":    reviews,
 		"message":       "Tour information for tourists (first keypoint only)",
 	}
 
@@ -412,7 +765,8 @@ func (h *TourHandler) GetTourForTourist(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
-// GetPurchasedKeypoints returns all keypoints for a tour that has been purchased
+// GetPurchasedKeypoints returns all keypoints for a tour that has been purchased.
+// The tourist sees the revision they bought until they upgrade to the latest one.
 func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	tourIDStr := vars["tourId"]
@@ -423,6 +777,22 @@ func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	purchasedAt, err := h.purchaseService.GetPurchaseTime(r, tourID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if purchasedAt == nil {
+		http.Error(w, "You must purchase the tour to see all keypoints", http.StatusForbidden)
+		return
+	}
+
 	// Get tour details
 	tour, err := h.tourService.GetTourByID(tourID)
 	if err != nil {
@@ -434,12 +804,6 @@ func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Check if tour is published
-	if tour.Status != "Published" {
-		http.Error(w, "Tour is not published", http.StatusNotFound)
-		return
-	}
-
 	// Get all keypoints for the tour
 	keypoints, err := h.keypointService.GetKeypointsByTourID(tourID)
 	if err != nil {
@@ -447,8 +811,40 @@ func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Get reviews for the tour
-	reviews, err := h.reviewService.GetReviewsByTourID(tourID)
+	// Swap in the purchased revision, tours without history are served live. Ratings
+	// always come from the live tour.
+	rating := tour.Rating
+	revisionNumber, latestNumber := 0, 0
+	revision, err := h.revisionService.GetRevisionForTourist(tourID, touristID, *purchasedAt)
+	if err != nil {
+		http.Error(w, "Failed to retrieve purchased revision", http.StatusInternalServerError)
+		return
+	}
+	if revision != nil && revision.Snapshot != nil {
+		tour = revision.Snapshot
+		keypoints = revision.Snapshot.Keypoints
+		revisionNumber = revision.Number
+
+		latest, err := h.revisionService.GetLatestRevision(tourID)
+		if err != nil {
+			http.Error(w, "Failed to retrieve latest revision", http.StatusInternalServerError)
+			return
+		}
+		latestNumber = latest.Number
+	}
+	localizeTour(w, r, tour, keypoints)
+
+	// Media only for the keypoints the tourist reached so far
+	unlocked, err := h.mediaService.UnlockedKeypoints(tourID, touristID)
+	if err != nil {
+		http.Error(w, "Failed to check unlocked keypoints", http.StatusInternalServerError)
+		return
+	}
+	services.GateMedia(keypoints, unlocked)
+	services.HideChallengeAnswers(keypoints)
+
+	// Only the best few reviews, the rest are paged through the reviews endpoint
+	reviews, err := h.reviewService.GetTopReviews(tourID, tourDetailReviews)
 	if err != nil {
 		http.Error(w, "Failed to retrieve reviews", http.StatusInternalServerError)
 		return
@@ -460,6 +856,7 @@ func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Reque
 			"id":          tour.ID,
 			"name":        tour.Name,
 			"description": tour.Description,
+			"language":    tour.Language,
 			"difficulty":  tour.Difficulty,
 			"tags":        tour.Tags,
 			"price":       tour.Price,
@@ -468,12 +865,99 @@ func (h *TourHandler) GetPurchasedKeypoints(w http.ResponseWriter, r *http.Reque
 			"walkingStats": tour.WalkingStats,
 			"cyclingStats": tour.CyclingStats,
 		},
-		"keypoints": keypoints,
-		"reviews":   reviews,
-		"message":   "All keypoints for purchased tour",
+		"keypoints":        keypoints,
+		"rating":           rating,
+		"topReviews":       reviews,
+		"revision":         revisionNumber,
+		"latestRevision":   latestNumber,
+		"upgradeAvailable": latestNumber > revisionNumber,
+		"message":          "All keypoints for purchased tour",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+// UpgradePurchasedRevision moves the tourist from their purchased revision to the latest one.
+func (h *TourHandler) UpgradePurchasedRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tourIDStr := vars["tourId"]
+
+	tourID, err := strconv.Atoi(tourIDStr)
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return
+	}
+
+	touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	isPurchased, err := h.purchaseService.IsTourPurchasedByMe(r, tourID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !isPurchased {
+		http.Error(w, "You must purchase the tour before upgrading it", http.StatusForbidden)
+		return
+	}
+
+	revision, err := h.revisionService.UpgradeTourist(tourID, touristID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour has no revisions", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to upgrade tour revision", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Tour upgraded to the latest revision",
+		"revision": revision.Number,
+	})
+}
+
+// normalizeTourLanguages checks the default language and the translations of a new tour
+// and its keypoints, lowercasing every language tag.
+func normalizeTourLanguages(tour *models.Tour, keypoints []*models.Keypoint) error {
+	if tour.Language == "" {
+		tour.Language = models.DefaultLanguage
+	}
+	language, ok := services.NormalizeLanguage(tour.Language)
+	if !ok {
+		return errors.New("invalid language tag")
+	}
+	tour.Language = language
+
+	translations, err := services.NormalizeTranslations(tour.Translations, language)
+	if err != nil {
+		return err
+	}
+	tour.Translations = translations
+	for _, keypoint := range keypoints {
+		translations, err := services.NormalizeTranslations(keypoint.Translations, language)
+		if err != nil {
+			return err
+		}
+		keypoint.Translations = translations
+	}
+	return nil
+}
+
+func validCompletionMode(mode models.CompletionMode) bool {
+	return mode == models.CompletionOrdered || mode == models.CompletionFreeRoam
+}
+
+const radiusRangeMessage = "Radius must be between 5 and 1000 meters, or 0 for the default"
+
+// validRadius checks a keypoint proximity radius; callers treat 0 as "use the default".
+func validRadius(radius float64) bool {
+	return radius >= models.MinKeypointRadius && radius <= models.MaxKeypointRadius
+}
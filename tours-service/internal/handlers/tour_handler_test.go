@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestValidRadius(t *testing.T) {
+	tests := []struct {
+		radius float64
+		want   bool
+	}{
+		{radius: 4.9, want: false},
+		{radius: 5, want: true},
+		{radius: 50, want: true},
+		{radius: 1000, want: true},
+		{radius: 1000.1, want: false},
+		{radius: -10, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validRadius(tt.radius); got != tt.want {
+			t.Errorf("validRadius(%v) = %v, want %v", tt.radius, got, tt.want)
+		}
+	}
+}
+
+func TestParseTourListQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    models.TourListQuery
+		wantErr bool
+	}{
+		{name: "no parameters", query: "", want: models.TourListQuery{}},
+		{name: "sort by rating", query: "sort=rating", want: models.TourListQuery{Sort: models.TourSortRating}},
+		{name: "sort by reviews", query: "sort=reviews", want: models.TourListQuery{Sort: models.TourSortReviews}},
+		{name: "unknown sort", query: "sort=price", wantErr: true},
+		{name: "all filters", query: "sort=rating&minRating=4.5&minReviews=10", want: models.TourListQuery{Sort: models.TourSortRating, MinRating: 4.5, MinReviews: 10}},
+		{name: "minRating bounds", query: "minRating=5", want: models.TourListQuery{MinRating: 5}},
+		{name: "minRating above 5", query: "minRating=5.1", wantErr: true},
+		{name: "negative minRating", query: "minRating=-1", wantErr: true},
+		{name: "minRating not a number", query: "minRating=good", wantErr: true},
+		{name: "negative minReviews", query: "minReviews=-3", wantErr: true},
+		{name: "fractional minReviews", query: "minReviews=2.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/get-published?"+tt.query, nil)
+			got, err := parseTourListQuery(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTourListQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseTourListQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"tours-service/internal/models"
@@ -19,16 +21,18 @@ import (
 )
 
 type TourReviewHandler struct {
-	reviewService *services.TourReviewService
-	tourService   *services.TourService
-	authService   *services.AuthService
+	reviewService   *services.TourReviewService
+	tourService     *services.TourService
+	authService     *services.AuthService
+	purchaseService *services.PurchaseService
 }
 
-func NewTourReviewHandler(reviewService *services.TourReviewService, tourService *services.TourService, authService *services.AuthService) *TourReviewHandler {
+func NewTourReviewHandler(reviewService *services.TourReviewService, tourService *services.TourService, authService *services.AuthService, purchaseService *services.PurchaseService) *TourReviewHandler {
 	return &TourReviewHandler{
-		reviewService: reviewService,
-		tourService:   tourService,
-		authService:   authService,
+		reviewService:   reviewService,
+		tourService:     tourService,
+		authService:     authService,
+		purchaseService: purchaseService,
 	}
 }
 
@@ -84,7 +88,23 @@ func (h *TourReviewHandler) CreateTourReview(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// TODO: Da li je turista bio na turi
+	purchasedAt, err := h.purchaseService.GetPurchaseTime(r, tourID)
+	if err != nil {
+		http.Error(w, "Failed to check purchase of the tour: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := h.reviewService.CheckReviewEligibility(tourID, touristID, purchasedAt, visitDate); err != nil {
+		switch {
+		case errors.Is(err, services.ErrReviewNotAllowed):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case strings.Contains(err.Error(), "visit date"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, "Failed to check review eligibility", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	var imageURLs []string
 	files := r.MultipartForm.File["images"]
@@ -115,14 +135,22 @@ func (h *TourReviewHandler) CreateTourReview(w http.ResponseWriter, r *http.Requ
 		ImageURLs: imageURLs,
 	}
 
-	err = h.reviewService.CreateTourReview(review)
+	created, err := h.reviewService.SubmitTourReview(review)
 	if err != nil {
+		if strings.Contains(err.Error(), "already reviewed") {
+			http.Error(w, "You already reviewed this tour, submit again to edit your review", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Failed to create tour review in database", http.StatusInternalServerError)
 		return
 	}
 
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(review)
 }
 
@@ -185,13 +213,349 @@ func (h *TourReviewHandler) GetReviewsByTourID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	reviews, err := h.reviewService.GetReviewsByTourID(tourID)
+	query, err := parseReviewQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.reviewService.GetReviewsPage(tourID, query)
 	if err != nil {
 		http.Error(w, "Failed to retrieve reviews", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseReviewQuery reads ?sort=newest|highest|lowest, ?media=true, ?page= and ?pageSize=.
+func parseReviewQuery(r *http.Request) (models.ReviewQuery, error) {
+	values := r.URL.Query()
+	query := models.ReviewQuery{Sort: values.Get("sort"), Page: 1, PageSize: defaultReviewPageSize}
+
+	switch query.Sort {
+	case "":
+		query.Sort = models.ReviewSortNewest
+	case models.ReviewSortNewest, models.ReviewSortHighest, models.ReviewSortLowest:
+	default:
+		return query, fmt.Errorf("sort must be %q, %q or %q", models.ReviewSortNewest, models.ReviewSortHighest, models.ReviewSortLowest)
+	}
+	if raw := values.Get("media"); raw != "" {
+		mediaOnly, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, errors.New("media must be true or false")
+		}
+		query.MediaOnly = mediaOnly
+	}
+	if raw := values.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return query, errors.New("page must be a positive integer")
+		}
+		query.Page = page
+	}
+	if raw := values.Get("pageSize"); raw != "" {
+		pageSize, err := strconv.Atoi(raw)
+		if err != nil || pageSize < 1 || pageSize > maxReviewPageSize {
+			return query, fmt.Errorf("pageSize must be between 1 and %d", maxReviewPageSize)
+		}
+		query.PageSize = pageSize
+	}
+	return query, nil
+}
+
+// Length limits for free text around reviews
+const (
+	maxReplyLength         = 2000
+	maxReportReasonLength  = 500
+	defaultModerationLimit = 50
+)
+
+const (
+	defaultReviewPageSize = 10
+	maxReviewPageSize     = 50
+)
+
+func reviewIDFromPath(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["reviewId"])
+}
+
+// writeReviewError maps review service errors onto HTTP statuses.
+func writeReviewError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound), strings.Contains(err.Error(), "has no reply"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrReviewForbidden), errors.Is(err, services.ErrReviewNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "visit date"), strings.Contains(err.Error(), "own review"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case strings.Contains(err.Error(), "already"), strings.Contains(err.Error(), "not hidden"), strings.Contains(err.Error(), "not reported"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+func writeReview(w http.ResponseWriter, review *models.TourReview) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// UpdateTourReview lets the author change the rating, comment and visit date of their review.
+func (h *TourReviewHandler) UpdateTourReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateTourReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Comment) == "" {
+		http.Error(w, "Comment is required", http.StatusBadRequest)
+		return
+	}
+	if req.VisitDate.IsZero() {
+		http.Error(w, "visitDate is required", http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.GetOwnReview(reviewID, touristID)
+	if err != nil {
+		writeReviewError(w, err, "Failed to retrieve review")
+		return
+	}
+
+	purchasedAt, err := h.purchaseService.GetPurchaseTime(r, review.TourID)
+	if err != nil {
+		http.Error(w, "Failed to check purchase of the tour: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := h.reviewService.CheckReviewEligibility(review.TourID, touristID, purchasedAt, req.VisitDate); err != nil {
+		writeReviewError(w, err, "Failed to check review eligibility")
+		return
+	}
+
+	review.Rating = req.Rating
+	review.Comment = req.Comment
+	review.VisitDate = req.VisitDate
+	if err := h.reviewService.UpdateTourReview(review); err != nil {
+		writeReviewError(w, err, "Failed to update review")
+		return
+	}
+	writeReview(w, review)
+}
+
+func (h *TourReviewHandler) DeleteTourReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	touristID, err := h.authService.ValidateAndGetUserID(r, "Tourist")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.reviewService.DeleteTourReview(reviewID, touristID); err != nil {
+		writeReviewError(w, err, "Failed to delete review")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReplyToReview sets the public reply of the tour's guide, a second call edits it.
+func (h *TourReviewHandler) ReplyToReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	guideID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" || len([]rune(req.Text)) > maxReplyLength {
+		http.Error(w, fmt.Sprintf("Reply text is required and may be at most %d characters", maxReplyLength), http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviewService.ReplyToReview(reviewID, guideID, req.Text)
+	if err != nil {
+		writeReviewError(w, err, "Failed to save reply")
+		return
+	}
+	writeReview(w, review)
+}
+
+func (h *TourReviewHandler) DeleteReply(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	guideID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.reviewService.DeleteReply(reviewID, guideID); err != nil {
+		writeReviewError(w, err, "Failed to delete reply")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReportReview lets tourists and guides flag an abusive review for the admins.
+func (h *TourReviewHandler) ReportReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authService.ValidateAnyRole(r, "Tourist", "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len([]rune(req.Reason)) > maxReportReasonLength {
+		http.Error(w, fmt.Sprintf("Reason is required and may be at most %d characters", maxReportReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.reviewService.ReportReview(reviewID, user.UserID, req.Reason); err != nil {
+		writeReviewError(w, err, "Failed to report review")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GetModerationQueue lists reported reviews for admins, ?status=hidden lists hidden ones.
+func (h *TourReviewHandler) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.authService.ValidateAndGetUserID(r, "Admin"); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	status := models.ReviewStatus(r.URL.Query().Get("status"))
+	if status == "" {
+		status = models.ReviewReported
+	}
+	if status != models.ReviewReported && status != models.ReviewHidden {
+		http.Error(w, "status must be reported or hidden", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultModerationLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			http.Error(w, "Invalid limit, must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	reviews, err := h.reviewService.GetModerationQueue(status, limit)
+	if err != nil {
+		http.Error(w, "Failed to retrieve moderation queue", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(reviews)
-}
\ No newline at end of file
+}
+
+func (h *TourReviewHandler) HideReview(w http.ResponseWriter, r *http.Request) {
+	h.moderateReview(w, r, true)
+}
+
+func (h *TourReviewHandler) RestoreReview(w http.ResponseWriter, r *http.Request) {
+	h.moderateReview(w, r, false)
+}
+
+func (h *TourReviewHandler) moderateReview(w http.ResponseWriter, r *http.Request, hide bool) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	adminID, err := h.authService.ValidateAndGetUserID(r, "Admin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	review, err := h.reviewService.ModerateReview(reviewID, adminID, hide)
+	if err != nil {
+		writeReviewError(w, err, "Failed to moderate review")
+		return
+	}
+	writeReview(w, review)
+}
+
+// DismissReviewReports clears the reports on a review an admin found fine and takes it
+// out of the moderation queue.
+func (h *TourReviewHandler) DismissReviewReports(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := reviewIDFromPath(r)
+	if err != nil {
+		http.Error(w, "Invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	adminID, err := h.authService.ValidateAndGetUserID(r, "Admin")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	review, err := h.reviewService.DismissReports(reviewID, adminID)
+	if err != nil {
+		writeReviewError(w, err, "Failed to dismiss review reports")
+		return
+	}
+	writeReview(w, review)
+}
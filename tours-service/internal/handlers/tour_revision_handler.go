@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TourRevisionHandler struct {
+	revisionService *services.TourRevisionService
+	tourService     *services.TourService
+	authService     *services.AuthService
+}
+
+func NewTourRevisionHandler(revisionService *services.TourRevisionService, tourService *services.TourService, authService *services.AuthService) *TourRevisionHandler {
+	return &TourRevisionHandler{
+		revisionService: revisionService,
+		tourService:     tourService,
+		authService:     authService,
+	}
+}
+
+// authorizeAuthor resolves the tour from the URL and checks the caller is its author.
+func (h *TourRevisionHandler) authorizeAuthor(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	tourID, err := strconv.Atoi(mux.Vars(r)["tourId"])
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	tour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		http.Error(w, "Tour not found", http.StatusNotFound)
+		return 0, 0, false
+	}
+
+	if tour.AuthorID != userID {
+		http.Error(w, "Only the tour author can access its revisions", http.StatusForbidden)
+		return 0, 0, false
+	}
+
+	return tourID, userID, true
+}
+
+func (h *TourRevisionHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	tourID, _, ok := h.authorizeAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.revisionService.GetRevisions(tourID)
+	if err != nil {
+		http.Error(w, "Failed to retrieve revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func (h *TourRevisionHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	tourID, _, ok := h.authorizeAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision number", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.revisionService.GetRevision(tourID, number)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve revision", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revision)
+}
+
+// GetRevisionDiff compares two revisions given as ?from=&to= query parameters.
+func (h *TourRevisionHandler) GetRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	tourID, _, ok := h.authorizeAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid or missing from", http.StatusBadRequest)
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid or missing to", http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.revisionService.Diff(tourID, from, to)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to compare revisions", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+// RestoreRevision brings back the content of an old revision. The current version of the
+// tour must be sent as If-Match, a stale one gets 409 with the current tour.
+func (h *TourRevisionHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	tourID, userID, ok := h.authorizeAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision number", http.StatusBadRequest)
+		return
+	}
+
+	// The restore replaces all content, so it must be based on the tour the guide saw
+	version, ok, err := expectedVersion(r, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return
+	}
+
+	revision, err := h.revisionService.Restore(tourID, number, userID, version)
+	if err != nil {
+		if strings.Contains(err.Error(), "revision not found") {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+		} else if strings.Contains(err.Error(), "version conflict") {
+			current, getErr := h.tourService.GetTourByID(tourID)
+			if getErr != nil {
+				http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+				return
+			}
+			writeConflict(w, "Tour was modified by another request", current, current.Version)
+		} else if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to restore revision: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revision)
+}
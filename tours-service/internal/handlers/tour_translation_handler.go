@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+	"tours-service/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// authorTour loads the tour of the request path and checks the caller is its guide.
+func (h *TourHandler) authorTour(w http.ResponseWriter, r *http.Request) (*models.Tour, bool) {
+	tourID, err := strconv.Atoi(mux.Vars(r)["tourId"])
+	if err != nil {
+		http.Error(w, "Invalid tour ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, err := h.authService.ValidateAndGetUserID(r, "Guide")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	tour, err := h.tourService.GetTourByID(tourID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to retrieve tour", http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if tour.AuthorID != userID {
+		http.Error(w, "Only the tour author can manage translations", http.StatusForbidden)
+		return nil, false
+	}
+	return tour, true
+}
+
+// GetTranslations lists every language of a tour and its keypoints for the tour's guide.
+func (h *TourHandler) GetTranslations(w http.ResponseWriter, r *http.Request) {
+	tour, ok := h.authorTour(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := h.tourService.GetTranslations(tour)
+	if err != nil {
+		http.Error(w, "Failed to retrieve translations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(translations)
+}
+
+// SetTranslation adds or replaces the language in the path. The body holds the tour's
+// name and description and, optionally, keypoint translations keyed by keypoint ID.
+func (h *TourHandler) SetTranslation(w http.ResponseWriter, r *http.Request) {
+	var request models.TourTranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tour, ok := h.authorTour(w, r)
+	if !ok {
+		return
+	}
+
+	translations, err := h.tourService.SetTranslation(tour, mux.Vars(r)["lang"], &request)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTranslation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Tour not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to save translation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(translations)
+}
+
+// DeleteTranslation removes the language in the path from the tour and its keypoints.
+func (h *TourHandler) DeleteTranslation(w http.ResponseWriter, r *http.Request) {
+	tour, ok := h.authorTour(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.tourService.DeleteTranslation(tour, mux.Vars(r)["lang"])
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTranslation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Translation not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to delete translation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// TourAnalytics summarizes the executions of a tour started within a date range.
+// Durations are in seconds, rates are fractions of Starts.
+type TourAnalytics struct {
+	TourID         int                `json:"tourId"`
+	From           *time.Time         `json:"from,omitempty"`
+	To             *time.Time         `json:"to,omitempty"`
+	Starts         int                `json:"starts"`
+	Completed      int                `json:"completed"`
+	Aborted        int                `json:"aborted"`
+	Abandoned      int                `json:"abandoned"`
+	InProgress     int                `json:"inProgress"`
+	CompletionRate float64            `json:"completionRate"`
+	AbortRate      float64            `json:"abortRate"`
+	AbandonRate    float64            `json:"abandonRate"`
+	MedianDuration float64            `json:"medianDuration"` // of completed executions
+	Segments       []SegmentAnalytics `json:"segments"`
+	DropOffs       []DropOffAnalytics `json:"dropOffs"`
+	TopDropOff     *DropOffAnalytics  `json:"topDropOff,omitempty"` // the keypoint tourists most often give up at
+}
+
+// SegmentAnalytics is the time tourists take from one keypoint to the next one they
+// complete. FromKeypointID 0 is the start of the execution.
+type SegmentAnalytics struct {
+	FromKeypointID   int     `json:"fromKeypointId"`
+	FromKeypointName string  `json:"fromKeypointName,omitempty"`
+	ToKeypointID     int     `json:"toKeypointId"`
+	ToKeypointName   string  `json:"toKeypointName,omitempty"`
+	Count            int     `json:"count"`
+	AverageDuration  float64 `json:"averageDuration"`
+	MedianDuration   float64 `json:"medianDuration"`
+}
+
+// DropOffAnalytics counts aborted and abandoned executions by the keypoint they stopped
+// at: the keypoint tourists were heading to in ordered tours, the last one they reached
+// in free-roam tours. KeypointID 0 in a free-roam tour means before reaching any.
+type DropOffAnalytics struct {
+	KeypointID   int    `json:"keypointId"`
+	KeypointName string `json:"keypointName,omitempty"`
+	Count        int    `json:"count"`
+}
+
+// ExecutionSummary is the raw result of the analytics aggregation, before it is mapped
+// onto the keypoints of the tour.
+type ExecutionSummary struct {
+	Statuses []StatusCount      `bson:"statuses"`
+	Duration []DurationMedian   `bson:"duration"`
+	DropOffs []DropOffCount     `bson:"dropOffs"`
+	Segments []SegmentDurations `bson:"segments"`
+}
+
+// StatusCount counts the executions that ended with a status and reason.
+type StatusCount struct {
+	Status    ExecutionStatus `bson:"status"`
+	EndReason string          `bson:"endReason"`
+	Count     int             `bson:"count"`
+}
+
+type DurationMedian struct {
+	Median float64 `bson:"median"`
+}
+
+// DropOffCount counts the executions that stopped after completing CompletedCount
+// keypoints, the last of them LastKeypointID.
+type DropOffCount struct {
+	CompletedCount int `bson:"completedCount"`
+	LastKeypointID int `bson:"lastKeypointId"`
+	Count          int `bson:"count"`
+}
+
+type SegmentDurations struct {
+	FromKeypointID  int     `bson:"from"`
+	ToKeypointID    int     `bson:"to"`
+	Count           int     `bson:"count"`
+	AverageDuration float64 `bson:"average"`
+	MedianDuration  float64 `bson:"median"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type ValidationResponse struct {
 	UserID   int    `json:"userId"`
 	Username string `json:"username"`
@@ -12,6 +14,8 @@ type TourWithKeypointsRequest struct {
 	Keypoints []*Keypoint `json:"keypoints"`
 }
 
+// CreateKeypointRequest creates a keypoint. Ordinal is the 1-based position to
+// insert it at; 0 or anything past the end appends it.
 type CreateKeypointRequest struct {
 	TourID      int     `json:"tourId"`
 	Name        string  `json:"name"`
@@ -20,9 +24,77 @@ type CreateKeypointRequest struct {
 	Latitude    float64 `json:"latitude"`
 	Longitude   float64 `json:"longitude"`
 	Ordinal     int     `json:"ordinal"`
+	Radius      float64 `json:"radius"`
+
+	Challenge *KeypointChallenge `json:"challenge,omitempty"`
+}
+
+// TourScheduleRequest replaces the automatic publish and archive times of a tour, a nil
+// time clears that schedule.
+type TourScheduleRequest struct {
+	PublishAt *time.Time `json:"publishAt"`
+	ArchiveAt *time.Time `json:"archiveAt"`
+}
+
+// Orders of the published tour listing, highest first
+const (
+	TourSortRating  = "rating"
+	TourSortReviews = "reviews"
+)
+
+// TourListQuery filters and orders the published tour listing. Zero values leave the
+// listing unfiltered and in its default order.
+type TourListQuery struct {
+	Sort       string
+	MinRating  float64
+	MinReviews int
 }
 
 type TourWithFirstKeypoint struct {
 	Tour
 	FirstKeypoint Keypoint `json:"firstKeypoint"`
-}
\ No newline at end of file
+}
+
+// TourUpdateRequest is a partial update of a tour. Nil fields are left untouched,
+// server-computed fields (status, price, stats, timestamps) can't be set through it.
+type TourUpdateRequest struct {
+	Name           *string         `json:"name,omitempty"`
+	Description    *string         `json:"description,omitempty"`
+	Difficulty     *TourDifficulty `json:"difficulty,omitempty"`
+	Tags           *[]string       `json:"tags,omitempty"`
+	CompletionMode *CompletionMode `json:"completionMode,omitempty"`
+	DefaultRadius  *float64        `json:"defaultRadius,omitempty"`
+	Language       *string         `json:"language,omitempty"`
+	Version        *int            `json:"version,omitempty"`
+}
+
+// KeypointUpdateRequest is a partial update of a keypoint. Nil fields are left untouched.
+type KeypointUpdateRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	ImageURL    *string  `json:"imageUrl,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+	Ordinal     *int     `json:"ordinal,omitempty"`
+	Radius      *float64 `json:"radius,omitempty"`
+	Version     *int     `json:"version,omitempty"`
+}
+
+// ReorderKeypointsRequest lists every keypoint ID of a tour in its new order.
+type ReorderKeypointsRequest struct {
+	KeypointIDs []int `json:"keypointIds"`
+}
+
+// RouteResponse is the route of a tour for one transport profile as encoded polylines.
+type RouteResponse struct {
+	TourID   int                    `json:"tourId"`
+	Profile  string                 `json:"profile"`
+	Polyline string                 `json:"polyline"`
+	Segments []RouteSegmentPolyline `json:"segments"`
+}
+
+type RouteSegmentPolyline struct {
+	FromKeypointID int    `json:"fromKeypointId"`
+	ToKeypointID   int    `json:"toKeypointId"`
+	Polyline       string `json:"polyline"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DistanceCacheEntry is a cached map-service result for one profile between two points.
+// Key is built from the profile and the coordinates rounded to 5 decimals (about 1 m).
+type DistanceCacheEntry struct {
+	Key       string    `bson:"_id" json:"key"`
+	Profile   string    `bson:"profile" json:"profile"`
+	Distance  float64   `bson:"distance" json:"distance"`
+	Duration  float64   `bson:"duration" json:"duration"`
+	Geometry  string    `bson:"geometry" json:"geometry"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
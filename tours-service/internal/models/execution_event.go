@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Execution event types pushed to live execution streams
+const (
+	EventDistance           = "distance" // live only, never stored
+	EventKeypointReached    = "keypoint_reached"
+	EventTourCompleted      = "tour_completed"
+	EventExecutionAbandoned = "execution_abandoned"
+	EventError              = "error" // live only, never stored
+)
+
+// ExecutionEvent is something that happened to a tour execution. Stored events are
+// numbered per execution by Seq so a reconnecting client can resume after the last one
+// it saw; live-only events have Seq 0.
+type ExecutionEvent struct {
+	Seq          int64     `bson:"seq" json:"id"`
+	ExecutionID  int       `bson:"executionId" json:"executionId"`
+	Type         string    `bson:"type" json:"type"`
+	KeypointID   int       `bson:"keypointId,omitempty" json:"keypointId,omitempty"`
+	KeypointName string    `bson:"keypointName,omitempty" json:"keypointName,omitempty"`
+	Distance     float64   `bson:"distance,omitempty" json:"distance,omitempty"`
+	Message      string    `bson:"message,omitempty" json:"message,omitempty"`
+	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
+}
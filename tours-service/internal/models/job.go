@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusDead      JobStatus = "dead" // gave up after MaxAttempts, kept for inspection
+)
+
+// Job is a unit of background work stored in the jobs collection. Workers of any
+// tours-service replica claim pending jobs by leasing them until LockedUntil.
+type Job struct {
+	ID          int                    `bson:"_id,omitempty" json:"id"`
+	Type        string                 `bson:"type" json:"type"`
+	Payload     map[string]interface{} `bson:"payload,omitempty" json:"payload,omitempty"`
+	UniqueKey   string                 `bson:"uniqueKey,omitempty" json:"uniqueKey,omitempty"` // at most one pending job per key
+	Status      JobStatus              `bson:"status" json:"status"`
+	Attempts    int                    `bson:"attempts" json:"attempts"`
+	MaxAttempts int                    `bson:"maxAttempts" json:"maxAttempts"`
+	RunAt       time.Time              `bson:"runAt" json:"runAt"`
+	LockedBy    string                 `bson:"lockedBy,omitempty" json:"lockedBy,omitempty"`
+	LockedUntil *time.Time             `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	LastError   string                 `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	CreatedAt   time.Time              `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time              `bson:"updatedAt" json:"updatedAt"`
+	FinishedAt  *time.Time             `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"` // set on success only
+}
+
+type JobListResponse struct {
+	Jobs   []Job             `json:"jobs"`
+	Counts map[JobStatus]int `json:"counts"`
+}
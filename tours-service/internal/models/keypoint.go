@@ -1,15 +1,17 @@
 package models
 
 type Keypoint struct {
-	ID          int       `bson:"_id,omitempty" json:"id"`
-	TourID      int       `bson:"tourId" json:"tourId"`
-	Name        string    `bson:"name" json:"name"`
-	Description string    `bson:"description" json:"description"`
-	ImageURL    string    `bson:"imageUrl" json:"imageUrl"`
-	Latitude    float64   `bson:"latitude" json:"latitude"`
-	Longitude   float64   `bson:"longitude" json:"longitude"`
-	Ordinal     int       `bson:"ordinal" json:"ordinal"`
+	ID           int                    `bson:"_id,omitempty" json:"id"`
+	TourID       int                    `bson:"tourId" json:"tourId"`
+	Name         string                 `bson:"name" json:"name"`
+	Description  string                 `bson:"description" json:"description"`
+	ImageURL     string                 `bson:"imageUrl" json:"imageUrl"`
+	Latitude     float64                `bson:"latitude" json:"latitude"`
+	Longitude    float64                `bson:"longitude" json:"longitude"`
+	Ordinal      int                    `bson:"ordinal" json:"ordinal"`
+	Radius       float64                `bson:"radius,omitempty" json:"radius,omitempty"`             // proximity radius in meters, 0 means the tour default
+	Translations map[string]Translation `bson:"translations,omitempty" json:"translations,omitempty"` // same languages as the tour's translations
+	Media        []KeypointMedia        `bson:"media,omitempty" json:"media,omitempty"`               // gallery, gated by purchase and unlocking
+	Challenge    *KeypointChallenge     `bson:"challenge,omitempty" json:"challenge,omitempty"`
+	Version      int                    `bson:"version" json:"version"`
 }
-
-
-
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type ChallengeType string
+
+const (
+	ChallengeQuiz  ChallengeType = "quiz"  // answer a question, free text or one of the options
+	ChallengeCode  ChallengeType = "code"  // enter a secret code found on site
+	ChallengePhoto ChallengeType = "photo" // upload a photo taken at the keypoint
+)
+
+// KeypointChallenge must be solved, on top of being in range, to complete a keypoint.
+type KeypointChallenge struct {
+	Type        ChallengeType `bson:"type" json:"type"`
+	Question    string        `bson:"question,omitempty" json:"question,omitempty"`       // the quiz question, or a hint for codes and photos
+	Options     []string      `bson:"options,omitempty" json:"options,omitempty"`         // quiz choices, empty for a free text answer
+	Answers     []string      `bson:"answers,omitempty" json:"answers,omitempty"`         // accepted answers, never served to tourists
+	MaxAttempts int           `bson:"maxAttempts,omitempty" json:"maxAttempts,omitempty"` // per execution, 0 means unlimited
+}
+
+// ChallengeAttempt is one try at a keypoint challenge during a tour execution.
+type ChallengeAttempt struct {
+	KeypointID  int       `json:"keypoint_id" bson:"keypoint_id"`
+	Answer      string    `json:"answer,omitempty" bson:"answer,omitempty"`
+	PhotoURL    string    `json:"photo_url,omitempty" bson:"photo_url,omitempty"`
+	Correct     bool      `json:"correct" bson:"correct"`
+	AttemptedAt time.Time `json:"attempted_at" bson:"attempted_at"`
+}
+
+type ChallengeAnswerRequest struct {
+	Answer string `json:"answer"`
+}
+
+// ChallengeResult is the outcome of one challenge attempt.
+type ChallengeResult struct {
+	KeypointID        int  `json:"keypoint_id"`
+	Correct           bool `json:"correct"`
+	AttemptsLeft      *int `json:"attempts_left,omitempty"` // nil when attempts are unlimited
+	KeypointCompleted bool `json:"keypoint_completed"`
+	ChallengeFailed   bool `json:"challenge_failed"` // out of attempts, the keypoint was skipped unsolved
+	TourFinished      bool `json:"tour_finished"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type MediaType string
+
+const (
+	MediaImage MediaType = "image"
+	MediaAudio MediaType = "audio" // narration
+	MediaVideo MediaType = "video" // short clip
+)
+
+// MediaLimit bounds the uploads of one media type on a keypoint.
+type MediaLimit struct {
+	MaxCount    int      `json:"maxCount"`    // items of the type per keypoint
+	MaxSize     int64    `json:"maxSize"`     // bytes per item
+	MaxDuration float64  `json:"maxDuration"` // seconds per item, 0 for images
+	MimeTypes   []string `json:"mimeTypes"`
+}
+
+var MediaLimits = map[MediaType]MediaLimit{
+	MediaImage: {
+		MaxCount:  20,
+		MaxSize:   10 << 20,
+		MimeTypes: []string{"image/jpeg", "image/png", "image/webp", "image/gif"},
+	},
+	MediaAudio: {
+		MaxCount:    3,
+		MaxSize:     50 << 20,
+		MaxDuration: 30 * 60,
+		MimeTypes:   []string{"audio/mpeg", "audio/mp4", "audio/aac", "audio/ogg", "audio/wave"},
+	},
+	MediaVideo: {
+		MaxCount:    5,
+		MaxSize:     100 << 20,
+		MaxDuration: 120,
+		MimeTypes:   []string{"video/mp4", "video/webm"},
+	},
+}
+
+// KeypointMedia is one item of a keypoint's gallery. The gallery is kept in display order.
+type KeypointMedia struct {
+	ID         int       `bson:"id" json:"id"`
+	Type       MediaType `bson:"type" json:"type"`
+	URL        string    `bson:"url" json:"url"`
+	MimeType   string    `bson:"mimeType" json:"mimeType"`
+	Size       int64     `bson:"size" json:"size"`                             // bytes
+	Duration   float64   `bson:"duration,omitempty" json:"duration,omitempty"` // seconds, audio and video only
+	Caption    string    `bson:"caption,omitempty" json:"caption,omitempty"`
+	UploadedAt time.Time `bson:"uploadedAt" json:"uploadedAt"`
+}
+
+type ReorderMediaRequest struct {
+	MediaIDs []int `json:"mediaIds"`
+}
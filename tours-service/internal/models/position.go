@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PositionFix is the tourist's last reported position, as stored by stakeholders-service.
+type PositionFix struct {
+	Longitude  float64   `json:"longitude"`
+	Latitude   float64   `json:"latitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"` // horizontal accuracy in meters, nil when unknown
+	RecordedAt time.Time `json:"recorded_at"`
+}
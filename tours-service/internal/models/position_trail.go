@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// TrailPoint is one position fix of a tourist during a tour execution, stored in the
+// position_trails time-series collection.
+type TrailPoint struct {
+	RecordedAt time.Time `bson:"recordedAt" json:"recordedAt"`
+	Meta       TrailMeta `bson:"meta" json:"-"`
+	Longitude  float64   `bson:"longitude" json:"longitude"`
+	Latitude   float64   `bson:"latitude" json:"latitude"`
+	Accuracy   *float64  `bson:"accuracy,omitempty" json:"accuracy,omitempty"`
+}
+
+// TrailMeta identifies the execution a trail point belongs to. It is the metaField of
+// the time-series collection, so points of one execution are bucketed together.
+type TrailMeta struct {
+	ExecutionID int `bson:"executionId"`
+	TourID      int `bson:"tourId"`
+	UserID      int `bson:"userId"`
+}
+
+// PositionTrail is the trail of an execution for replay. The points are polyline encoded,
+// Timestamps holds the unix time of each of them in the same order.
+type PositionTrail struct {
+	ExecutionID int            `json:"executionId"`
+	TourID      int            `json:"tourId"`
+	UserID      int            `json:"userId"`
+	Polyline    string         `json:"polyline"`
+	Timestamps  []int64        `json:"timestamps"`
+	Distance    float64        `json:"distance"` // in meters
+	Duration    float64        `json:"duration"` // in seconds
+	Segments    []TrailSegment `json:"segments"`
+}
+
+// TrailSegment is the part of a trail walked towards one keypoint: from the start of the
+// execution or the previous completed keypoint up to the completion of the next one.
+type TrailSegment struct {
+	FromKeypointID int       `json:"fromKeypointId,omitempty"` // 0 is the start of the execution
+	ToKeypointID   int       `json:"toKeypointId,omitempty"`   // 0 while no further keypoint is completed
+	StartedAt      time.Time `json:"startedAt"`
+	EndedAt        time.Time `json:"endedAt"`
+	Distance       float64   `json:"distance"` // in meters
+	Duration       float64   `json:"duration"` // in seconds
+	Polyline       string    `json:"polyline"`
+}
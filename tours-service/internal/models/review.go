@@ -2,15 +2,45 @@ package models
 
 import "time"
 
+type ReviewStatus string
+
+const (
+	ReviewVisible  ReviewStatus = "visible"
+	ReviewReported ReviewStatus = "reported" // still visible, waiting in the moderation queue
+	ReviewHidden   ReviewStatus = "hidden"   // hidden by an admin, left out of rating summaries
+)
+
 type TourReview struct {
-	ID          int       `bson:"_id,omitempty" json:"id"`
-	TourID      int       `bson:"tourId" json:"tourId"`
-	TouristID   int       `bson:"touristId" json:"touristId"`
-	Rating      int       `bson:"rating" json:"rating"`
-	Comment     string    `bson:"comment" json:"comment"`
-	VisitDate   time.Time `bson:"visitDate" json:"visitDate"`
-	CommentDate time.Time `bson:"commentDate" json:"commentDate"`
-	ImageURLs   []string  `bson:"imageUrls" json:"imageUrls"`
+	ID          int          `bson:"_id,omitempty" json:"id"`
+	TourID      int          `bson:"tourId" json:"tourId"`
+	TouristID   int          `bson:"touristId" json:"touristId"`
+	Rating      int          `bson:"rating" json:"rating"`
+	Comment     string       `bson:"comment" json:"comment"`
+	VisitDate   time.Time    `bson:"visitDate" json:"visitDate"`
+	CommentDate time.Time    `bson:"commentDate" json:"commentDate"`
+	UpdatedAt   *time.Time   `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+	ImageURLs   []string     `bson:"imageUrls" json:"imageUrls"`
+	Reply       *ReviewReply `bson:"reply,omitempty" json:"reply,omitempty"`
+
+	// Moderation, an empty status means visible
+	Status      ReviewStatus   `bson:"status,omitempty" json:"status,omitempty"`
+	Reports     []ReviewReport `bson:"reports,omitempty" json:"reports,omitempty"` // only served to admins
+	ModeratedBy int            `bson:"moderatedBy,omitempty" json:"moderatedBy,omitempty"`
+	ModeratedAt *time.Time     `bson:"moderatedAt,omitempty" json:"moderatedAt,omitempty"`
+}
+
+// ReviewReply is the public answer of the tour's guide, one per review.
+type ReviewReply struct {
+	GuideID   int        `bson:"guideId" json:"guideId"`
+	Text      string     `bson:"text" json:"text"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt *time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+type ReviewReport struct {
+	ReporterID int       `bson:"reporterId" json:"reporterId"`
+	Reason     string    `bson:"reason" json:"reason"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
 }
 
 type CreateTourReviewRequest struct {
@@ -19,4 +49,34 @@ type CreateTourReviewRequest struct {
 	Comment   string    `json:"comment" validate:"required"`
 	VisitDate time.Time `json:"visitDate" validate:"required"`
 	ImageURLs []string  `json:"imageUrls"`
-}
\ No newline at end of file
+}
+
+// Orders of a tour's review list
+const (
+	ReviewSortNewest  = "newest"
+	ReviewSortHighest = "highest" // highest rating first, newest first among equal ratings
+	ReviewSortLowest  = "lowest"
+)
+
+// ReviewQuery selects one page of a tour's visible reviews. Page is 1-based.
+type ReviewQuery struct {
+	Sort      string
+	MediaOnly bool // only reviews with at least one image
+	Page      int
+	PageSize  int
+}
+
+type ReviewPage struct {
+	Reviews  []TourReview `json:"reviews"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"pageSize"`
+	Total    int64        `json:"total"`
+	HasMore  bool         `json:"hasMore"`
+}
+
+// UpdateTourReviewRequest edits the text part of a review, images are kept.
+type UpdateTourReviewRequest struct {
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	VisitDate time.Time `json:"visitDate"`
+}
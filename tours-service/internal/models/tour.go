@@ -17,9 +17,64 @@ const (
     DifficultyHard   TourDifficulty = "Hard"
 )
 
+// CompletionMode decides which keypoints a tour execution can complete
+type CompletionMode string
+
+const (
+    CompletionOrdered  CompletionMode = "ordered"   // keypoints one by one, by ordinal
+    CompletionFreeRoam CompletionMode = "free_roam" // any uncompleted keypoint, in any order
+)
+
+// Keypoint proximity radius bounds in meters. Keypoints without a radius use the tour
+// default, tours without a default use DefaultKeypointRadius.
+const (
+    DefaultKeypointRadius = 50.0
+    MinKeypointRadius     = 5.0
+    MaxKeypointRadius     = 1000.0
+)
+
+// Transport profiles as named by map-service
+const (
+    ProfileDriving = "driving-car"
+    ProfileWalking = "foot-walking"
+    ProfileCycling = "cycling-regular"
+)
+
+// Where tour stats come from: map-service routing or the straight-line fallback estimate
+const (
+    StatsRouted    = "routed"
+    StatsEstimated = "estimated"
+)
+
 type DistanceAndDuration struct {
     Distance float64 `bson:"distance" json:"distance"` // in meters
     Duration float64 `bson:"duration" json:"duration"` // in seconds
+    Source   string  `bson:"source,omitempty" json:"source,omitempty"` // StatsRouted or StatsEstimated
+}
+
+// RouteLeg is what map-service returns for one profile between two keypoints.
+type RouteLeg struct {
+    Distance  float64 `json:"distance"`
+    Duration  float64 `json:"duration"`
+    Geometry  string  `json:"geometry"` // encoded polyline, precision 5
+    Estimated bool    `json:"-"`
+}
+
+// RouteSegment is the path between two consecutive keypoints, one encoded polyline per profile.
+type RouteSegment struct {
+    FromKeypointID int               `bson:"fromKeypointId" json:"fromKeypointId"`
+    ToKeypointID   int               `bson:"toKeypointId" json:"toKeypointId"`
+    Polylines      map[string]string `bson:"polylines" json:"polylines"`
+    Estimated      bool              `bson:"estimated,omitempty" json:"estimated,omitempty"`
+}
+
+// RatingSummary is kept up to date on the tour as reviews are written, so listings can
+// show and sort by rating without loading the reviews.
+type RatingSummary struct {
+    Average   float64 `bson:"average" json:"average"` // rounded to two decimals, 0 without reviews
+    Count     int     `bson:"count" json:"count"`
+    Sum       int     `bson:"sum" json:"-"`
+    Histogram []int   `bson:"histogram" json:"histogram"` // review count per rating, index 0 holds 1-star reviews
 }
 
 type Tour struct {
@@ -27,20 +82,38 @@ type Tour struct {
 	AuthorID int `bson:"authorId" json:"authorId"`
 	Name string `bson:"name" json:"name"`
 	Description string `bson:"description" json:"description"`
+	Language string `bson:"language,omitempty" json:"language,omitempty"` // language of name and description, empty means DefaultLanguage
+	Translations map[string]Translation `bson:"translations,omitempty" json:"translations,omitempty"` // other languages, keyed by language tag
 	Difficulty TourDifficulty `bson:"difficulty" json:"difficulty"` // Easy, Medium, Hard
 	Tags []string `bson:"tags" json:"tags"`
 	Status TourStatus `bson:"status" json:"status"` // Draft, Published, Archived
 	Price float64 `bson:"price" json:"price"`
+	CompletionMode CompletionMode `bson:"completionMode,omitempty" json:"completionMode,omitempty"` // empty means ordered
+	DefaultRadius float64 `bson:"defaultRadius,omitempty" json:"defaultRadius,omitempty"` // keypoint proximity radius in meters, 0 means DefaultKeypointRadius
 	Keypoints []Keypoint `bson:"keypoints,omitempty" json:"keypoints,omitempty"` // Lista keypoint-a
 	Reviews []TourReview `bson:"reviews,omitempty" json:"reviews,omitempty"`
+	Rating RatingSummary `bson:"rating" json:"rating"` // maintained by the review repository, never set through updates
 
 	// Distance and duration statistics
 	DrivingStats DistanceAndDuration `bson:"drivingStats,omitempty" json:"drivingStats,omitempty"`
 	WalkingStats DistanceAndDuration `bson:"walkingStats,omitempty" json:"walkingStats,omitempty"`
 	CyclingStats DistanceAndDuration `bson:"cyclingStats,omitempty" json:"cyclingStats,omitempty"`
 
+	// Route geometry between keypoints, served separately through the route endpoint
+	RouteSegments []RouteSegment `bson:"routeSegments,omitempty" json:"-"`
+
 	// Timestamps
 	TimePublished *time.Time `bson:"timePublished,omitempty" json:"timePublished,omitempty"`
 	TimeArchived *time.Time `bson:"timeArchived,omitempty" json:"timeArchived,omitempty"`
 	TimeDrafted *time.Time `bson:"timeDrafted,omitempty" json:"timeDrafted,omitempty"`
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"` // soft deleted, purged once the retention period passes
+
+	// Pending automatic transitions, cleared once carried out or done by hand
+	ScheduledPublishAt *time.Time `bson:"scheduledPublishAt,omitempty" json:"scheduledPublishAt,omitempty"`
+	ScheduledArchiveAt *time.Time `bson:"scheduledArchiveAt,omitempty" json:"scheduledArchiveAt,omitempty"`
+
+	ClonedFrom int `bson:"clonedFrom,omitempty" json:"clonedFrom,omitempty"` // tour this draft was duplicated from
+
+	// Incremented on every write, used for optimistic concurrency (ETag / If-Match)
+	Version int `bson:"version" json:"version"`
 }
\ No newline at end of file
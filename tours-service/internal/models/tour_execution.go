@@ -12,6 +12,9 @@ const (
 	ExecutionStatusAborted    ExecutionStatus = "aborted"
 )
 
+// ExecutionEndReasonAbandoned marks a failed execution the sweeper ended for inactivity.
+const ExecutionEndReasonAbandoned = "abandoned"
+
 type TourExecution struct {
 	ID                int                `json:"id" bson:"_id"`
 	TourID            int                `json:"tour_id" bson:"tour_id"`
@@ -20,10 +23,25 @@ type TourExecution struct {
 	EndedAt           *time.Time         `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
 	LastActivity      *time.Time         `json:"last_activity,omitempty" bson:"last_activity,omitempty"`
 	Status            ExecutionStatus    `json:"status" bson:"status"`
+	EndReason         string             `json:"end_reason,omitempty" bson:"end_reason,omitempty"`
+	ResumableUntil    *time.Time         `json:"resumable_until,omitempty" bson:"resumable_until,omitempty"`
 	FinishedKeypoints []FinishedKeyPoint `json:"finished_keypoints,omitempty" bson:"finished_keypoints,omitempty"`
+	Dwell             []DwellCounter     `json:"dwell,omitempty" bson:"dwell,omitempty"`
+	ChallengeAttempts []ChallengeAttempt `json:"challenge_attempts,omitempty" bson:"challenge_attempts,omitempty"`
+	LastFixAt         *time.Time         `json:"last_fix_at,omitempty" bson:"last_fix_at,omitempty"`
+	LastTrailAt       *time.Time         `json:"-" bson:"last_trail_at,omitempty"`
+}
+
+// DwellCounter counts consecutive position fixes within range of a keypoint that is
+// not completed yet.
+type DwellCounter struct {
+	KeypointID int `json:"keypoint_id" bson:"keypoint_id"`
+	Count      int `json:"count" bson:"count"`
 }
 
 type FinishedKeyPoint struct {
 	KeypointID  int        `json:"keypoint_id" bson:"keypoint_id"`
 	CompletedAt *time.Time `json:"completed_at" bson:"completed_at"`
+	// Skipped after running out of attempts at its challenge, the keypoint was not solved
+	ChallengeFailed bool `json:"challenge_failed,omitempty" bson:"challenge_failed,omitempty"`
 }
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+type RevisionReason string
+
+const (
+	RevisionTourCreated     RevisionReason = "tour_created"
+	RevisionTourUpdated     RevisionReason = "tour_updated"
+	RevisionPriceChanged    RevisionReason = "price_changed"
+	RevisionStatusChanged   RevisionReason = "status_changed"
+	RevisionKeypointCreated RevisionReason = "keypoint_created"
+	RevisionKeypointUpdated RevisionReason = "keypoint_updated"
+	RevisionKeypointDeleted RevisionReason = "keypoint_deleted"
+	RevisionRestored        RevisionReason = "restored"
+	RevisionTranslated      RevisionReason = "translations_changed"
+)
+
+// TourRevision is an immutable snapshot of a tour and its keypoints taken after a change.
+type TourRevision struct {
+	ID           int              `bson:"_id,omitempty" json:"id"`
+	TourID       int              `bson:"tourId" json:"tourId"`
+	Number       int              `bson:"number" json:"number"` // 1, 2, 3... per tour
+	AuthorID     int              `bson:"authorId" json:"authorId"`
+	CreatedAt    time.Time        `bson:"createdAt" json:"createdAt"`
+	Reason       RevisionReason   `bson:"reason" json:"reason"`
+	RestoredFrom int              `bson:"restoredFrom,omitempty" json:"restoredFrom,omitempty"`
+	Changes      []RevisionChange `bson:"changes" json:"changes"` // diff against the previous revision
+	Snapshot     *Tour            `bson:"snapshot,omitempty" json:"snapshot,omitempty"` // tour with Keypoints filled in
+}
+
+// RevisionChange is one entry of a structured diff. Keypoint fields are addressed
+// as "keypoints[<id>].<field>", a whole added or removed keypoint as "keypoints[<id>]".
+type RevisionChange struct {
+	Field string      `bson:"field" json:"field"`
+	Old   interface{} `bson:"old,omitempty" json:"old,omitempty"`
+	New   interface{} `bson:"new,omitempty" json:"new,omitempty"`
+}
+
+type RevisionDiff struct {
+	TourID  int              `json:"tourId"`
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Changes []RevisionChange `json:"changes"`
+}
+
+// RevisionPin remembers which revision a tourist sees for a tour they bought.
+type RevisionPin struct {
+	TourID    int       `bson:"tourId" json:"tourId"`
+	TouristID int       `bson:"touristId" json:"touristId"`
+	Revision  int       `bson:"revision" json:"revision"`
+	PinnedAt  time.Time `bson:"pinnedAt" json:"pinnedAt"`
+}
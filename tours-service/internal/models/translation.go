@@ -0,0 +1,32 @@
+package models
+
+// DefaultLanguage is the language of tours created without one.
+const DefaultLanguage = "en"
+
+// Translation holds the name and description of a tour or keypoint in one language.
+type Translation struct {
+	Name        string `bson:"name" json:"name"`
+	Description string `bson:"description" json:"description"`
+}
+
+// TourTranslationRequest sets one language of a tour and, optionally, of its keypoints
+// (keyed by keypoint ID).
+type TourTranslationRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Keypoints   map[int]Translation `json:"keypoints,omitempty"`
+}
+
+// KeypointTranslations lists the translations of one keypoint.
+type KeypointTranslations struct {
+	KeypointID   int                    `json:"keypointId"`
+	Translations map[string]Translation `json:"translations"`
+}
+
+// TourTranslations is every language of a tour and its keypoints, as managed by its guide.
+type TourTranslations struct {
+	TourID       int                    `json:"tourId"`
+	Language     string                 `json:"language"` // default language, the tour's own name and description
+	Translations map[string]Translation `json:"translations"`
+	Keypoints    []KeypointTranslations `json:"keypoints"`
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tours-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DistanceCacheRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewDistanceCacheRepository(db *mongo.Database) *DistanceCacheRepository {
+	return &DistanceCacheRepository{
+		Collection: db.Collection("distance_cache"),
+	}
+}
+
+// GetEntries returns the cached entries for the given keys, indexed by key. Missing keys
+// are simply absent from the result.
+func (r *DistanceCacheRepository) GetEntries(keys []string) (map[string]models.DistanceCacheEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entries := make(map[string]models.DistanceCacheEntry, len(keys))
+	if len(keys) == 0 {
+		return entries, nil
+	}
+
+	cursor, err := r.Collection.Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read distance cache: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.DistanceCacheEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to decode distance cache entry: %w", err)
+		}
+		entries[entry.Key] = entry
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read distance cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// SaveEntries upserts the entries in one bulk write.
+func (r *DistanceCacheRepository) SaveEntries(entries []models.DistanceCacheEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(entries))
+	for _, entry := range entries {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": entry.Key}).
+			SetReplacement(entry).
+			SetUpsert(true))
+	}
+
+	_, err := r.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("failed to write distance cache: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tours-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ExecutionEventRepository struct {
+	Collection       *mongo.Collection
+	TourExCollection *mongo.Collection
+}
+
+func NewExecutionEventRepository(db *mongo.Database) *ExecutionEventRepository {
+	return &ExecutionEventRepository{
+		Collection:       db.Collection("execution_events"),
+		TourExCollection: db.Collection("tourExecution"),
+	}
+}
+
+// AppendEvent numbers the event with the next sequence value of its execution and
+// stores it, both in one transaction. Appends to the same execution conflict on the
+// counter, so an event is visible before any event with a higher sequence number and
+// readers never skip one that is still being stored.
+func (r *ExecutionEventRepository) AppendEvent(event *models.ExecutionEvent) error {
+	err := withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		var execution struct {
+			EventSeq int64 `bson:"event_seq"`
+		}
+		opts := options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"event_seq": 1})
+		err := r.TourExCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": event.ExecutionID},
+			bson.M{"$inc": bson.M{"event_seq": 1}},
+			opts).Decode(&execution)
+		if err != nil {
+			return fmt.Errorf("failed to number execution event: %w", err)
+		}
+
+		event.Seq = execution.EventSeq
+		if _, err := r.Collection.InsertOne(ctx, event); err != nil {
+			return fmt.Errorf("failed to store execution event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		event.Seq = 0
+		return err
+	}
+	return nil
+}
+
+// GetEventsAfter returns the stored events of an execution with a sequence number
+// greater than afterSeq, oldest first.
+func (r *ExecutionEventRepository) GetEventsAfter(executionID int, afterSeq int64) ([]models.ExecutionEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"executionId": executionID, "seq": bson.M{"$gt": afterSeq}}
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.ExecutionEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode execution events: %w", err)
+	}
+	return events, nil
+}
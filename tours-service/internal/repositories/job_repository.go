@@ -0,0 +1,303 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tours-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobRepository struct {
+	Collection         *mongo.Collection
+	CountersCollection *mongo.Collection
+}
+
+func NewJobRepository(db *mongo.Database) *JobRepository {
+	return &JobRepository{
+		Collection:         db.Collection("jobs"),
+		CountersCollection: db.Collection("counters"),
+	}
+}
+
+func (r *JobRepository) getNextSequenceValue(sequenceName string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var counter Counter
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	filter := bson.M{"_id": sequenceName}
+	update := bson.M{"$inc": bson.M{"value": 1}}
+
+	err := r.CountersCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next sequence value: %w", err)
+	}
+
+	return counter.Value, nil
+}
+
+// EnqueueJob stores a pending job. A job with a UniqueKey is merged into the pending job
+// with the same key if there is one, keeping the earlier RunAt.
+func (r *JobRepository) EnqueueJob(job *models.Job) error {
+	nextID, err := r.getNextSequenceValue("job_id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	job.ID = nextID
+	job.Status = models.JobStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if job.UniqueKey == "" {
+		if _, err := r.Collection.InsertOne(ctx, job); err != nil {
+			return fmt.Errorf("failed to enqueue job: %w", err)
+		}
+		return nil
+	}
+
+	filter := bson.M{"uniqueKey": job.UniqueKey, "status": models.JobStatusPending}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":         job.ID,
+			"type":        job.Type,
+			"payload":     job.Payload,
+			"attempts":    0,
+			"maxAttempts": job.MaxAttempts,
+			"createdAt":   now,
+		},
+		"$set": bson.M{"updatedAt": now},
+		"$min": bson.M{"runAt": job.RunAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	err = r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(job)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another replica inserted the same pending job in the meantime
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return nil
+}
+
+// ClaimJob leases the next due job to the worker. Running jobs whose lease expired,
+// because their worker died, are claimed again. It returns nil, nil when nothing is due.
+func (r *JobRepository) ClaimJob(workerID string, lease time.Duration) (*models.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+	filter := bson.M{"$or": []bson.M{
+		{"status": models.JobStatusPending, "runAt": bson.M{"$lte": now}},
+		{"status": models.JobStatusRunning, "lockedUntil": bson.M{"$lt": now}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.JobStatusRunning,
+			"lockedBy":    workerID,
+			"lockedUntil": lockedUntil,
+			"updatedAt":   now,
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "runAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return &job, nil
+}
+
+// CompleteJob marks a job the worker still holds as succeeded.
+func (r *JobRepository) CompleteJob(jobID int, workerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": jobID, "lockedBy": workerID, "status": models.JobStatusRunning}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.JobStatusSucceeded,
+			"updatedAt":  now,
+			"finishedAt": now,
+		},
+		"$unset": bson.M{"lockedBy": "", "lockedUntil": "", "lastError": ""},
+	}
+
+	if _, err := r.Collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to complete job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// FailJob records a failed attempt. With retryAt the job goes back to pending for
+// another attempt, without it the job is dead-lettered.
+func (r *JobRepository) FailJob(jobID int, workerID, message string, retryAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"lastError": message,
+		"updatedAt": time.Now(),
+		"status":    models.JobStatusDead,
+	}
+	if retryAt != nil {
+		set["status"] = models.JobStatusPending
+		set["runAt"] = *retryAt
+	}
+
+	filter := bson.M{"_id": jobID, "lockedBy": workerID, "status": models.JobStatusRunning}
+	update := bson.M{
+		"$set":   set,
+		"$unset": bson.M{"lockedBy": "", "lockedUntil": ""},
+	}
+
+	_, err := r.Collection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		// A newer pending job with the same key exists, it supersedes this retry
+		return r.deadLetter(jobID, workerID, message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record failure of job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+func (r *JobRepository) deadLetter(jobID int, workerID, message string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": jobID, "lockedBy": workerID}
+	update := bson.M{
+		"$set":   bson.M{"status": models.JobStatusDead, "lastError": message, "updatedAt": time.Now()},
+		"$unset": bson.M{"lockedBy": "", "lockedUntil": ""},
+	}
+	if _, err := r.Collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to dead-letter job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// GetJobs lists jobs, newest first, optionally filtered by status and type.
+func (r *JobRepository) GetJobs(status models.JobStatus, jobType string, limit int) ([]models.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if jobType != "" {
+		filter["type"] = jobType
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to decode jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// CountJobsByStatus returns how many jobs there are in every status.
+func (r *JobRepository) CountJobsByStatus() (map[models.JobStatus]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[models.JobStatus]int{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Status models.JobStatus `bson:"_id"`
+			Count  int              `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode job count: %w", err)
+		}
+		counts[row.Status] = row.Count
+	}
+	return counts, cursor.Err()
+}
+
+func (r *JobRepository) GetJobByID(jobID int) (*models.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.Job
+	err := r.Collection.FindOne(ctx, bson.M{"_id": jobID}).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("job with ID %d not found", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job: %w", err)
+	}
+	return &job, nil
+}
+
+// RetryJob puts a dead job back in the queue with a fresh set of attempts.
+func (r *JobRepository) RetryJob(jobID int) (*models.Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": jobID, "status": models.JobStatusDead}
+	update := bson.M{
+		"$set": bson.M{
+			"status":    models.JobStatusPending,
+			"attempts":  0,
+			"runAt":     now,
+			"updatedAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var job models.Job
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, getErr := r.GetJobByID(jobID); getErr != nil {
+			return nil, getErr
+		}
+		return nil, fmt.Errorf("job %d is not dead, only dead jobs can be retried", jobID)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("a pending job with the same key already exists")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	return &job, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"tours-service/internal/models"
@@ -16,12 +17,14 @@ import (
 type KeypointRepository struct {
 	Collection         *mongo.Collection
 	CountersCollection *mongo.Collection
+	ToursCollection    *mongo.Collection
 }
 
 func NewKeypointRepository(db *mongo.Database) *KeypointRepository {
 	return &KeypointRepository{
 		Collection:         db.Collection("keypoints"),
 		CountersCollection: db.Collection("counters"),
+		ToursCollection:    db.Collection("tours"),
 	}
 }
 
@@ -42,12 +45,15 @@ func (r *KeypointRepository) getNextSequenceValue(sequenceName string) (int, err
 	return counter.Value, nil
 }
 
+// CreateKeypoint stores a keypoint at the ordinal it carries. It is meant for filling a
+// new tour, InsertKeypoint adds one to an existing tour.
 func (r *KeypointRepository) CreateKeypoint(keypoint *models.Keypoint) error {
 	nextID, err := r.getNextSequenceValue("keypoint_id")
 	if err != nil {
 		return err
 	}
 	keypoint.ID = nextID
+	keypoint.Version = 1
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -63,16 +69,63 @@ func (r *KeypointRepository) CreateKeypoint(keypoint *models.Keypoint) error {
 	return nil
 }
 
+// InsertKeypoint adds a keypoint to its tour at its Ordinal (1-based) and shifts the rest,
+// in one transaction. An ordinal outside 1..n+1 appends the keypoint at the end of the tour.
+func (r *KeypointRepository) InsertKeypoint(keypoint *models.Keypoint) error {
+	nextID, err := r.getNextSequenceValue("keypoint_id")
+	if err != nil {
+		return err
+	}
+	keypoint.ID = nextID
+	keypoint.Version = 1
+	position := keypoint.Ordinal
+
+	err = withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		keypoints, err := r.lockOrder(ctx, keypoint.TourID)
+		if err != nil {
+			return err
+		}
+
+		ids := keypointIDs(keypoints)
+		keypoint.Ordinal = position
+		if keypoint.Ordinal < 1 || keypoint.Ordinal > len(ids)+1 {
+			keypoint.Ordinal = len(ids) + 1
+		}
+		// Make room first, the new keypoint takes the freed ordinal
+		if err := r.writeOrder(ctx, keypoint.TourID, keypoints, insertAt(ids, keypoint.Ordinal-1, keypoint.ID)); err != nil {
+			return err
+		}
+		_, err = r.Collection.InsertOne(ctx, keypoint)
+		return err
+	})
+	if err != nil {
+		if err.Error() == "tour not found" {
+			return err
+		}
+		return fmt.Errorf("failed to create keypoint: %w", err)
+	}
+
+	return nil
+}
+
 func (r *KeypointRepository) GetKeypointsByTourID(tourID int) ([]models.Keypoint, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	keypoints, err := r.findByTour(ctx, tourID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find keypoints by tour ID: %w", err)
+	}
+	return keypoints, nil
+}
+
+func (r *KeypointRepository) findByTour(ctx context.Context, tourID int) ([]models.Keypoint, error) {
 	filter := bson.M{"tourId": tourID}
-	opts := options.Find().SetSort(bson.M{"ordinal": 1})
+	opts := options.Find().SetSort(bson.D{{Key: "ordinal", Value: 1}, {Key: "_id", Value: 1}})
 
 	cursor, err := r.Collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find keypoints by tour ID: %w", err)
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
@@ -102,52 +155,153 @@ func (r *KeypointRepository) GetKeypointByID(keypointID int) (*models.Keypoint,
 	return &keypoint, nil
 }
 
-func (r *KeypointRepository) UpdateKeypoint(keypoint *models.Keypoint) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// UpdateKeypoint applies a partial update, but only if the keypoint is still at expectedVersion.
+// A new ordinal moves the keypoint to that position instead of being written as is, in
+// the same transaction as the update, so ordinals never collide or leave gaps.
+func (r *KeypointRepository) UpdateKeypoint(keypointID, expectedVersion int, update *models.KeypointUpdateRequest) (*models.Keypoint, error) {
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.ImageURL != nil {
+		set["imageUrl"] = *update.ImageURL
+	}
+	if update.Latitude != nil {
+		set["latitude"] = *update.Latitude
+	}
+	if update.Longitude != nil {
+		set["longitude"] = *update.Longitude
+	}
+	if update.Radius != nil {
+		set["radius"] = *update.Radius
+	}
 
-	filter := bson.M{"_id": keypoint.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"name":        keypoint.Name,
-			"description": keypoint.Description,
-			"imageUrl":    keypoint.ImageURL,
-			"latitude":    keypoint.Latitude,
-			"longitude":   keypoint.Longitude,
-			"ordinal":     keypoint.Ordinal,
-		},
+	if update.Ordinal == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return r.updateFields(ctx, keypointID, expectedVersion, set)
 	}
 
-	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	current, err := r.GetKeypointByID(keypointID)
 	if err != nil {
-		return fmt.Errorf("failed to update keypoint: %w", err)
+		return nil, err
 	}
+	var keypoint *models.Keypoint
+	err = withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		keypoints, err := r.lockOrder(ctx, current.TourID)
+		if err != nil {
+			return err
+		}
+		if keypoint, err = r.updateFields(ctx, keypointID, expectedVersion, set); err != nil {
+			return err
+		}
 
-	if result.MatchedCount == 0 {
-		return errors.New("keypoint not found")
+		ids := removeID(keypointIDs(keypoints), keypointID)
+		index := *update.Ordinal - 1
+		if index < 0 || index > len(ids) {
+			index = len(ids)
+		}
+		if err := r.writeOrder(ctx, current.TourID, keypoints, insertAt(ids, index, keypointID)); err != nil {
+			return err
+		}
+		return r.Collection.FindOne(ctx, bson.M{"_id": keypointID}).Decode(keypoint)
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "version conflict") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to move keypoint: %w", err)
 	}
 
-	return nil
+	return keypoint, nil
+}
+
+// updateFields is the version-guarded part of UpdateKeypoint.
+func (r *KeypointRepository) updateFields(ctx context.Context, keypointID, expectedVersion int, set bson.M) (*models.Keypoint, error) {
+	filter := bson.M{"_id": keypointID, "version": versionFilter(expectedVersion)}
+	change := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		change["$set"] = set
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var keypoint models.Keypoint
+	err := r.Collection.FindOneAndUpdate(ctx, filter, change, opts).Decode(&keypoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			count, countErr := r.Collection.CountDocuments(ctx, bson.M{"_id": keypointID})
+			if countErr != nil {
+				return nil, fmt.Errorf("failed to check keypoint: %w", countErr)
+			}
+			if count == 0 {
+				return nil, errors.New("keypoint not found")
+			}
+			return nil, errors.New("version conflict: keypoint was modified by another request")
+		}
+		return nil, fmt.Errorf("failed to update keypoint: %w", err)
+	}
+
+	return &keypoint, nil
 }
 
-func (r *KeypointRepository) DeleteKeypoint(keypointID int) error {
+func (r *KeypointRepository) SetKeypointImage(keypointID int, imageURL string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	filter := bson.M{"_id": keypointID}
+	update := bson.M{
+		"$set": bson.M{"imageUrl": imageURL},
+		"$inc": bson.M{"version": 1},
+	}
 
-	result, err := r.Collection.DeleteOne(ctx, filter)
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to delete keypoint: %w", err)
+		return fmt.Errorf("failed to update keypoint image: %w", err)
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return errors.New("keypoint not found")
 	}
 
 	return nil
 }
 
+// DeleteKeypoint removes a keypoint and closes the gap it leaves in the ordinals, in one
+// transaction. It returns the deleted keypoint.
+func (r *KeypointRepository) DeleteKeypoint(keypointID int) (*models.Keypoint, error) {
+	keypoint, err := r.GetKeypointByID(keypointID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		keypoints, err := r.lockOrder(ctx, keypoint.TourID)
+		if err != nil {
+			return err
+		}
+
+		result, err := r.Collection.DeleteOne(ctx, bson.M{"_id": keypointID})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount == 0 {
+			return errors.New("keypoint not found")
+		}
+		return r.writeOrder(ctx, keypoint.TourID, keypoints, removeID(keypointIDs(keypoints), keypointID))
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to delete keypoint: %w", err)
+	}
+
+	return keypoint, nil
+}
+
 func (r *KeypointRepository) DeleteKeypointsByTourID(tourID int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -162,17 +316,17 @@ func (r *KeypointRepository) DeleteKeypointsByTourID(tourID int) error {
 	return nil
 }
 
+// GetFirstKeypointByTourID returns the keypoint with the lowest ordinal, which is
+// ordinal 1 for renumbered tours but doesn't rely on it.
 func (r *KeypointRepository) GetFirstKeypointByTourID(tourID int) (*models.Keypoint, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"tourId":  tourID,
-		"ordinal": 1,
-	}
+	filter := bson.M{"tourId": tourID}
+	opts := options.FindOne().SetSort(bson.D{{Key: "ordinal", Value: 1}, {Key: "_id", Value: 1}})
 
 	var keypoint models.Keypoint
-	err := r.Collection.FindOne(ctx, filter).Decode(&keypoint)
+	err := r.Collection.FindOne(ctx, filter, opts).Decode(&keypoint)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.New("first keypoint not found for this tour")
@@ -203,3 +357,277 @@ func (r *KeypointRepository) GetUncompletedKeyPointsByTourId(tourId int, complet
 	}
 	return &keypoint, nil
 }
+
+// ReorderKeypoints sets the order of a tour's keypoints in one transaction. orderedIDs must
+// list every keypoint of the tour exactly once, which also catches concurrent inserts and deletes.
+func (r *KeypointRepository) ReorderKeypoints(tourID int, orderedIDs []int) error {
+	err := withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		keypoints, err := r.lockOrder(ctx, tourID)
+		if err != nil {
+			return err
+		}
+
+		if len(orderedIDs) != len(keypoints) {
+			return errors.New("keypoint list does not match the tour's keypoints")
+		}
+		existing := make(map[int]bool, len(keypoints))
+		for _, keypoint := range keypoints {
+			existing[keypoint.ID] = true
+		}
+		seen := make(map[int]bool, len(orderedIDs))
+		for _, id := range orderedIDs {
+			if !existing[id] || seen[id] {
+				return errors.New("keypoint list does not match the tour's keypoints")
+			}
+			seen[id] = true
+		}
+
+		return r.writeOrder(ctx, tourID, keypoints, orderedIDs)
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "does not match") {
+			return err
+		}
+		return fmt.Errorf("failed to reorder keypoints: %w", err)
+	}
+
+	return nil
+}
+
+// lockOrder starts a change of the keypoint order of a tour inside a transaction and
+// returns the tour's keypoints in their current order. Bumping keypointOrderVersion on
+// the tour makes concurrent changes to the same tour conflict, so one is retried on top
+// of the other even when they touch different keypoints, like a delete and an append.
+func (r *KeypointRepository) lockOrder(ctx context.Context, tourID int) ([]models.Keypoint, error) {
+	result, err := r.ToursCollection.UpdateOne(ctx, bson.M{"_id": tourID}, bson.M{"$inc": bson.M{"keypointOrderVersion": 1}})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("tour not found")
+	}
+	return r.findByTour(ctx, tourID)
+}
+
+// writeOrder renumbers keypoints 1..n following orderedIDs, touching only the ones that
+// move. IDs missing from current are left to the caller. Moving keypoints are parked on
+// negative ordinals first, so the unique {tourId, ordinal} index never sees two keypoints
+// on one ordinal halfway through.
+func (r *KeypointRepository) writeOrder(ctx context.Context, tourID int, current []models.Keypoint, orderedIDs []int) error {
+	currentOrdinals := make(map[int]int, len(current))
+	for _, keypoint := range current {
+		currentOrdinals[keypoint.ID] = keypoint.Ordinal
+	}
+
+	var park, place []mongo.WriteModel
+	for i, id := range orderedIDs {
+		ordinal, ok := currentOrdinals[id]
+		if !ok || ordinal == i+1 {
+			continue
+		}
+		filter := bson.M{"_id": id, "tourId": tourID}
+		park = append(park, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{"$set": bson.M{"ordinal": -(i + 1)}}))
+		place = append(place, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{
+				"$set": bson.M{"ordinal": i + 1},
+				"$inc": bson.M{"version": 1},
+			}))
+	}
+	if len(park) == 0 {
+		return nil
+	}
+
+	if _, err := r.Collection.BulkWrite(ctx, park); err != nil {
+		return fmt.Errorf("failed to renumber keypoints: %w", err)
+	}
+	if _, err := r.Collection.BulkWrite(ctx, place); err != nil {
+		return fmt.Errorf("failed to renumber keypoints: %w", err)
+	}
+	return nil
+}
+
+func keypointIDs(keypoints []models.Keypoint) []int {
+	ids := make([]int, 0, len(keypoints))
+	for _, keypoint := range keypoints {
+		ids = append(ids, keypoint.ID)
+	}
+	return ids
+}
+
+func insertAt(ids []int, index, id int) []int {
+	ids = append(ids, 0)
+	copy(ids[index+1:], ids[index:])
+	ids[index] = id
+	return ids
+}
+
+func removeID(ids []int, id int) []int {
+	result := make([]int, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
+// SetTranslations writes the translations of a tour's keypoints in one language
+// (keypoint ID -> translation) in a single bulk write.
+func (r *KeypointRepository) SetTranslations(tourID int, language string, translations map[int]models.Translation) error {
+	if len(translations) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	writes := make([]mongo.WriteModel, 0, len(translations))
+	for keypointID, translation := range translations {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": keypointID, "tourId": tourID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{"translations." + language: translation},
+				"$inc": bson.M{"version": 1},
+			}))
+	}
+
+	if _, err := r.Collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to set keypoint translations: %w", err)
+	}
+	return nil
+}
+
+// DeleteTranslations removes one language from all keypoints of a tour.
+func (r *KeypointRepository) DeleteTranslations(tourID int, language string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	field := "translations." + language
+	filter := bson.M{"tourId": tourID, field: bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{field: ""},
+		"$inc":   bson.M{"version": 1},
+	}
+
+	if _, err := r.Collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to delete keypoint translations: %w", err)
+	}
+	return nil
+}
+
+// AddMedia appends an item to the gallery of a keypoint, unless the keypoint already
+// holds maxCount items of that type. It reports false when the limit was reached.
+func (r *KeypointRepository) AddMedia(keypointID int, media *models.KeypointMedia, maxCount int) (bool, error) {
+	nextID, err := r.getNextSequenceValue("keypoint_media_id")
+	if err != nil {
+		return false, err
+	}
+	media.ID = nextID
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The count check and the push happen in one update, so parallel uploads can't overshoot
+	sameType := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$media", bson.A{}}},
+		"cond":  bson.M{"$eq": bson.A{"$$this.type", media.Type}},
+	}}
+	filter := bson.M{
+		"_id":   keypointID,
+		"$expr": bson.M{"$lt": bson.A{bson.M{"$size": sameType}, maxCount}},
+	}
+	update := bson.M{
+		"$push": bson.M{"media": media},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to add keypoint media: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		count, err := r.Collection.CountDocuments(ctx, bson.M{"_id": keypointID})
+		if err != nil {
+			return false, fmt.Errorf("failed to check keypoint: %w", err)
+		}
+		if count == 0 {
+			return false, errors.New("keypoint not found")
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// DeleteMedia removes an item from the gallery of a keypoint.
+func (r *KeypointRepository) DeleteMedia(keypointID, mediaID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": keypointID, "media.id": mediaID}
+	update := bson.M{
+		"$pull": bson.M{"media": bson.M{"id": mediaID}},
+		"$inc":  bson.M{"version": 1},
+	}
+
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to delete keypoint media: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("media not found")
+	}
+	return nil
+}
+
+// SetMediaOrder replaces the gallery of a keypoint with a reordered one, but only if the
+// keypoint is still at expectedVersion.
+func (r *KeypointRepository) SetMediaOrder(keypointID, expectedVersion int, media []models.KeypointMedia) (*models.Keypoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": keypointID, "version": versionFilter(expectedVersion)}
+	update := bson.M{
+		"$set": bson.M{"media": media},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var keypoint models.Keypoint
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&keypoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("version conflict: keypoint was modified by another request")
+		}
+		return nil, fmt.Errorf("failed to reorder keypoint media: %w", err)
+	}
+	return &keypoint, nil
+}
+
+// SetChallenge replaces the challenge of a keypoint, a nil challenge removes it. The
+// write only happens if the keypoint is still at expectedVersion.
+func (r *KeypointRepository) SetChallenge(keypointID, expectedVersion int, challenge *models.KeypointChallenge) (*models.Keypoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": keypointID, "version": versionFilter(expectedVersion)}
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if challenge != nil {
+		update["$set"] = bson.M{"challenge": challenge}
+	} else {
+		update["$unset"] = bson.M{"challenge": ""}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var keypoint models.Keypoint
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&keypoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("version conflict: keypoint was modified by another request")
+		}
+		return nil, fmt.Errorf("failed to update keypoint challenge: %w", err)
+	}
+	return &keypoint, nil
+}
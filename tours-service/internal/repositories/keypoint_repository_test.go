@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestKeypointIDs(t *testing.T) {
+	tests := []struct {
+		name      string
+		keypoints []models.Keypoint
+		want      []int
+	}{
+		{name: "no keypoints", want: []int{}},
+		{name: "keeps the order", keypoints: []models.Keypoint{{ID: 7}, {ID: 3}, {ID: 5}}, want: []int{7, 3, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keypointIDs(tt.keypoints); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keypointIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		ids   []int
+		index int
+		id    int
+		want  []int
+	}{
+		{name: "into an empty tour", ids: []int{}, index: 0, id: 9, want: []int{9}},
+		{name: "at the start", ids: []int{1, 2, 3}, index: 0, id: 9, want: []int{9, 1, 2, 3}},
+		{name: "in the middle", ids: []int{1, 2, 3}, index: 1, id: 9, want: []int{1, 9, 2, 3}},
+		{name: "at the end", ids: []int{1, 2, 3}, index: 3, id: 9, want: []int{1, 2, 3, 9}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := append([]int{}, tt.ids...)
+			if got := insertAt(ids, tt.index, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("insertAt(%v, %d, %d) = %v, want %v", tt.ids, tt.index, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveID(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		id   int
+		want []int
+	}{
+		{name: "first", ids: []int{1, 2, 3}, id: 1, want: []int{2, 3}},
+		{name: "middle", ids: []int{1, 2, 3}, id: 2, want: []int{1, 3}},
+		{name: "last", ids: []int{1, 2, 3}, id: 3, want: []int{1, 2}},
+		{name: "missing", ids: []int{1, 2, 3}, id: 4, want: []int{1, 2, 3}},
+		{name: "only keypoint", ids: []int{1}, id: 1, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeID(tt.ids, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("removeID(%v, %d) = %v, want %v", tt.ids, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveKeypoint(t *testing.T) {
+	// UpdateKeypoint moves a keypoint by removing it and inserting it at its new index
+	tests := []struct {
+		name  string
+		ids   []int
+		id    int
+		index int
+		want  []int
+	}{
+		{name: "forward", ids: []int{1, 2, 3, 4}, id: 1, index: 2, want: []int{2, 3, 1, 4}},
+		{name: "backward", ids: []int{1, 2, 3, 4}, id: 4, index: 0, want: []int{4, 1, 2, 3}},
+		{name: "to the end", ids: []int{1, 2, 3, 4}, id: 2, index: 3, want: []int{1, 3, 4, 2}},
+		{name: "in place", ids: []int{1, 2, 3}, id: 2, index: 1, want: []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insertAt(removeID(tt.ids, tt.id), tt.index, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("moving %d to %d in %v = %v, want %v", tt.id, tt.index, tt.ids, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tours-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PositionTrailRepository struct {
+	Collection *mongo.Collection
+}
+
+func NewPositionTrailRepository(db *mongo.Database) *PositionTrailRepository {
+	return &PositionTrailRepository{
+		Collection: db.Collection("position_trails"),
+	}
+}
+
+func (r *PositionTrailRepository) AppendPoint(point *models.TrailPoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := r.Collection.InsertOne(ctx, point); err != nil {
+		return fmt.Errorf("failed to append trail point: %w", err)
+	}
+	return nil
+}
+
+// GetTrail returns the points of an execution in the order they were recorded.
+func (r *PositionTrailRepository) GetTrail(executionID int) ([]models.TrailPoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "recordedAt", Value: 1}})
+	cursor, err := r.Collection.Find(ctx, bson.M{"meta.executionId": executionID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trail: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	points := []models.TrailPoint{}
+	if err := cursor.All(ctx, &points); err != nil {
+		return nil, fmt.Errorf("failed to decode trail: %w", err)
+	}
+	return points, nil
+}
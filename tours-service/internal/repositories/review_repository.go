@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,15 +16,64 @@ import (
 type TourReviewRepository struct {
 	Collection         *mongo.Collection
 	CountersCollection *mongo.Collection
+	ToursCollection    *mongo.Collection
 }
 
 func NewTourReviewRepository(db *mongo.Database) *TourReviewRepository {
 	return &TourReviewRepository{
 		Collection:         db.Collection("tour_reviews"),
 		CountersCollection: db.Collection("counters"),
+		ToursCollection:    db.Collection("tours"),
 	}
 }
 
+// inTransaction runs fn in a transaction, so a review and the rating summary of its tour
+// always change together.
+func (r *TourReviewRepository) inTransaction(fn func(ctx context.Context) error) error {
+	return withTransaction(r.Collection.Database().Client(), fn)
+}
+
+// applyRatingChange moves the rating summary of a tour by one review. removed is the
+// rating the review had before and added the one it has now, 0 stands for none.
+func (r *TourReviewRepository) applyRatingChange(ctx context.Context, tourID, removed, added int) error {
+	countDelta := 0
+	if added > 0 {
+		countDelta++
+	}
+	if removed > 0 {
+		countDelta--
+	}
+
+	histogram := bson.M{"$map": bson.M{
+		"input": bson.A{0, 1, 2, 3, 4},
+		"as":    "i",
+		"in": bson.M{"$add": bson.A{
+			bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$rating.histogram", "$$i"}}, 0}},
+			bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$i", added - 1}}, 1, 0}},
+			bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$i", removed - 1}}, -1, 0}},
+		}},
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating.count":     bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.count", 0}}, countDelta}},
+			"rating.sum":       bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating.sum", 0}}, added - removed}},
+			"rating.histogram": histogram,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"rating.average": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$rating.count", 0}},
+				bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$rating.sum", "$rating.count"}}, 2}},
+				0,
+			}},
+		}}},
+	}
+
+	if _, err := r.ToursCollection.UpdateOne(ctx, bson.M{"_id": tourID}, pipeline); err != nil {
+		return fmt.Errorf("failed to update rating of tour %d: %w", tourID, err)
+	}
+	return nil
+}
+
 func (r *TourReviewRepository) getNextSequenceValue(sequenceName string) (int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -48,35 +99,345 @@ func (r *TourReviewRepository) CreateTourReview(review *models.TourReview) error
 	review.ID = nextID
 	review.CommentDate = time.Now()
 
+	err = r.inTransaction(func(ctx context.Context) error {
+		if _, err := r.Collection.InsertOne(ctx, review); err != nil {
+			return err
+		}
+		return r.applyRatingChange(ctx, review.TourID, 0, review.Rating)
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("tourist %d already reviewed tour %d", review.TouristID, review.TourID)
+		}
+		return fmt.Errorf("failed to create tour review: %w", err)
+	}
+
+	return nil
+}
+
+// publicReviewProjection leaves the moderation details out of reviews shown to everyone.
+var publicReviewProjection = bson.M{"status": 0, "reports": 0, "moderatedBy": 0, "moderatedAt": 0}
+
+// GetReviewsPage returns one page of the visible reviews of a tour and how many match in total.
+func (r *TourReviewRepository) GetReviewsPage(tourID int, query models.ReviewQuery) ([]models.TourReview, int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = r.Collection.InsertOne(ctx, review)
+	filter := bson.M{"tourId": tourID, "status": bson.M{"$ne": models.ReviewHidden}}
+	if query.MediaOnly {
+		filter["imageUrls.0"] = bson.M{"$exists": true}
+	}
+
+	sort := bson.D{{Key: "commentDate", Value: -1}, {Key: "_id", Value: -1}}
+	switch query.Sort {
+	case models.ReviewSortHighest:
+		sort = append(bson.D{{Key: "rating", Value: -1}}, sort...)
+	case models.ReviewSortLowest:
+		sort = append(bson.D{{Key: "rating", Value: 1}}, sort...)
+	}
+
+	total, err := r.Collection.CountDocuments(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to create tour review: %w", err)
+		return nil, 0, fmt.Errorf("failed to count reviews by tour ID: %w", err)
 	}
 
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64((query.Page - 1) * query.PageSize)).
+		SetLimit(int64(query.PageSize)).
+		SetProjection(publicReviewProjection)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find reviews by tour ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reviews := []models.TourReview{}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode tour reviews: %w", err)
+	}
+
+	return reviews, total, nil
+}
+
+// GetReviewByID returns the review with all moderation details, or nil.
+func (r *TourReviewRepository) GetReviewByID(reviewID int) (*models.TourReview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var review models.TourReview
+	err := r.Collection.FindOne(ctx, bson.M{"_id": reviewID}).Decode(&review)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find tour review: %w", err)
+	}
+	return &review, nil
+}
+
+// GetReviewByTourAndTourist returns the review a tourist wrote for a tour, or nil.
+func (r *TourReviewRepository) GetReviewByTourAndTourist(tourID, touristID int) (*models.TourReview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var review models.TourReview
+	err := r.Collection.FindOne(ctx, bson.M{"tourId": tourID, "touristId": touristID}).Decode(&review)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find tour review: %w", err)
+	}
+	return &review, nil
+}
+
+// UpdateTourReview overwrites the content of an existing review.
+func (r *TourReviewRepository) UpdateTourReview(review *models.TourReview) error {
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"rating":    review.Rating,
+		"comment":   review.Comment,
+		"visitDate": review.VisitDate,
+		"imageUrls": review.ImageURLs,
+		"updatedAt": now,
+	}}
+
+	err := r.inTransaction(func(ctx context.Context) error {
+		var previous models.TourReview
+		err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": review.ID}, update).Decode(&previous)
+		if err != nil {
+			return err
+		}
+		if previous.Rating == review.Rating || previous.Status == models.ReviewHidden {
+			return nil
+		}
+		return r.applyRatingChange(ctx, previous.TourID, previous.Rating, review.Rating)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("tour review %d not found", review.ID)
+		}
+		return fmt.Errorf("failed to update tour review: %w", err)
+	}
+	review.UpdatedAt = &now
+	return nil
+}
+
+// DeleteTourReview removes a review and takes it out of the rating summary of its tour.
+func (r *TourReviewRepository) DeleteTourReview(reviewID int) error {
+	err := r.inTransaction(func(ctx context.Context) error {
+		var deleted models.TourReview
+		if err := r.Collection.FindOneAndDelete(ctx, bson.M{"_id": reviewID}).Decode(&deleted); err != nil {
+			return err
+		}
+		if deleted.Status == models.ReviewHidden {
+			return nil
+		}
+		return r.applyRatingChange(ctx, deleted.TourID, deleted.Rating, 0)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("tour review %d not found", reviewID)
+		}
+		return fmt.Errorf("failed to delete tour review: %w", err)
+	}
 	return nil
 }
 
-func (r *TourReviewRepository) GetReviewsByTourID(tourID int) ([]models.TourReview, error) {
+// SetReply stores the guide's reply to a review, replacing an earlier one.
+func (r *TourReviewRepository) SetReply(reviewID int, reply *models.ReviewReply) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"tourId": tourID}
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": reviewID}, bson.M{"$set": bson.M{"reply": reply}})
+	if err != nil {
+		return fmt.Errorf("failed to save review reply: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("tour review %d not found", reviewID)
+	}
+	return nil
+}
 
-	opts := options.Find().SetSort(bson.M{"commentDate": -1})
+func (r *TourReviewRepository) DeleteReply(reviewID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	cursor, err := r.Collection.Find(ctx, filter, opts)
+	result, err := r.Collection.UpdateOne(ctx, bson.M{"_id": reviewID}, bson.M{"$unset": bson.M{"reply": ""}})
+	if err != nil {
+		return fmt.Errorf("failed to delete review reply: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("tour review %d not found", reviewID)
+	}
+	return nil
+}
+
+// AddReport files a report against a visible review and puts it in the moderation queue.
+// It returns false if the reporter already reported the review or it's hidden.
+func (r *TourReviewRepository) AddReport(reviewID int, report models.ReviewReport) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":                reviewID,
+		"status":             bson.M{"$ne": models.ReviewHidden},
+		"reports.reporterId": bson.M{"$ne": report.ReporterID},
+	}
+	update := bson.M{
+		"$push": bson.M{"reports": report},
+		"$set":  bson.M{"status": models.ReviewReported},
+	}
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to report tour review: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// SetVisibility hides or restores a review and moves its rating in or out of the tour's
+// rating summary. It returns nil if the review doesn't exist or already is in that state.
+func (r *TourReviewRepository) SetVisibility(reviewID int, hidden bool, adminID int) (*models.TourReview, error) {
+	status, previousFilter := models.ReviewVisible, bson.M{"$eq": models.ReviewHidden}
+	if hidden {
+		status, previousFilter = models.ReviewHidden, bson.M{"$ne": models.ReviewHidden}
+	}
+	now := time.Now()
+	update := bson.M{"$set": bson.M{"status": status, "moderatedBy": adminID, "moderatedAt": now}}
+	if !hidden {
+		// Restoring settles the reports, like dismissing them
+		update["$unset"] = bson.M{"reports": ""}
+	}
+
+	var review models.TourReview
+	err := r.inTransaction(func(ctx context.Context) error {
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": reviewID, "status": previousFilter}, update, opts).Decode(&review)
+		if err != nil {
+			return err
+		}
+		if hidden {
+			return r.applyRatingChange(ctx, review.TourID, review.Rating, 0)
+		}
+		return r.applyRatingChange(ctx, review.TourID, 0, review.Rating)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to moderate tour review: %w", err)
+	}
+	return &review, nil
+}
+
+// DismissReports clears the reports of a reported review and makes it plain visible again.
+// Reported reviews still count in the rating summary, so it stays as it is. It returns
+// nil if the review doesn't exist or isn't reported.
+func (r *TourReviewRepository) DismissReports(reviewID int, adminID int) (*models.TourReview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": reviewID, "status": models.ReviewReported}
+	update := bson.M{
+		"$set":   bson.M{"status": models.ReviewVisible, "moderatedBy": adminID, "moderatedAt": time.Now()},
+		"$unset": bson.M{"reports": ""},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var review models.TourReview
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&review)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find reviews by tour ID: %w", err)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to dismiss reports of tour review: %w", err)
+	}
+	return &review, nil
+}
+
+// GetReviewsByStatus lists reviews in a moderation status, the longest waiting first.
+func (r *TourReviewRepository) GetReviewsByStatus(status models.ReviewStatus, limit int) ([]models.TourReview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "reports.createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.Collection.Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find reviews by status: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var reviews []models.TourReview
+	reviews := []models.TourReview{}
 	if err = cursor.All(ctx, &reviews); err != nil {
 		return nil, fmt.Errorf("failed to decode tour reviews: %w", err)
 	}
-
 	return reviews, nil
-}
\ No newline at end of file
+}
+
+// RecalculateRatings rebuilds the rating summary of every tour from its reviews. Review
+// writes racing with the rebuild of the same tour are picked up by the next run.
+func (r *TourReviewRepository) RecalculateRatings(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": bson.M{"$ne": models.ReviewHidden}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"tourId": "$tourId", "rating": "$rating"},
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate review ratings: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		ID struct {
+			TourID int `bson:"tourId"`
+			Rating int `bson:"rating"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return fmt.Errorf("failed to decode review ratings: %w", err)
+	}
+
+	summaries := map[int]*models.RatingSummary{}
+	for _, group := range groups {
+		if group.ID.Rating < 1 || group.ID.Rating > 5 {
+			continue
+		}
+		summary, ok := summaries[group.ID.TourID]
+		if !ok {
+			summary = &models.RatingSummary{Histogram: make([]int, 5)}
+			summaries[group.ID.TourID] = summary
+		}
+		summary.Count += group.Count
+		summary.Sum += group.Count * group.ID.Rating
+		summary.Histogram[group.ID.Rating-1] += group.Count
+	}
+
+	reviewed := make([]int, 0, len(summaries))
+	for tourID, summary := range summaries {
+		summary.Average = math.Round(float64(summary.Sum)/float64(summary.Count)*100) / 100
+		if _, err := r.ToursCollection.UpdateOne(ctx, bson.M{"_id": tourID}, bson.M{"$set": bson.M{"rating": summary}}); err != nil {
+			return fmt.Errorf("failed to update rating of tour %d: %w", tourID, err)
+		}
+		reviewed = append(reviewed, tourID)
+	}
+
+	unreviewed := bson.M{
+		"_id": bson.M{"$nin": reviewed},
+		"$or": bson.A{
+			bson.M{"rating.count": bson.M{"$ne": 0}},
+			bson.M{"rating.histogram": bson.M{"$not": bson.M{"$size": 5}}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"rating": models.RatingSummary{Histogram: make([]int, 5)}}}
+	if _, err := r.ToursCollection.UpdateMany(ctx, unreviewed, update); err != nil {
+		return fmt.Errorf("failed to reset ratings of unreviewed tours: %w", err)
+	}
+	return nil
+}
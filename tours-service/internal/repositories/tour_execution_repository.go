@@ -47,22 +47,79 @@ func (r *TourExecutionRepository) FindByUserId(userId int) ([]*models.TourExecut
 	return executions, nil
 }
 
-func (r *TourExecutionRepository) FindByUserAndTourId(userId, tourId int) (*models.TourExecution, error) {
+// FindActiveByUserAndTourId returns the in-progress execution of a tour, or nil if the
+// user is not walking it right now.
+func (r *TourExecutionRepository) FindActiveByUserAndTourId(userId, tourId int) (*models.TourExecution, error) {
+	return r.findOne(bson.M{"tour_id": tourId, "user_id": userId, "status": models.ExecutionStatusInProgress}, nil)
+}
+
+// FindLatestByUserAndTourId returns the most recently started execution of a tour, or nil.
+func (r *TourExecutionRepository) FindLatestByUserAndTourId(userId, tourId int) (*models.TourExecution, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}})
+	return r.findOne(bson.M{"tour_id": tourId, "user_id": userId}, opts)
+}
+
+func (r *TourExecutionRepository) FindById(executionId int) (*models.TourExecution, error) {
+	return r.findOne(bson.M{"_id": executionId}, nil)
+}
+
+func (r *TourExecutionRepository) findOne(filter bson.M, opts *options.FindOneOptions) (*models.TourExecution, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"tour_id": tourId, "user_id": userId}
+	if opts == nil {
+		opts = options.FindOne()
+	}
 	var tourEx models.TourExecution
-	err := r.TourExCollection.FindOne(ctx, filter).Decode(&tourEx)
+	err := r.TourExCollection.FindOne(ctx, filter, opts).Decode(&tourEx)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("tour execution not found")
+			return nil, nil
 		}
 		return nil, errors.New("failed to find tour execution")
 	}
 	return &tourEx, nil
 }
 
+// FindHistoryByUserAndTourId returns every execution of a tour by the user, newest first.
+func (r *TourExecutionRepository) FindHistoryByUserAndTourId(userId, tourId int) ([]*models.TourExecution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tour_id": tourId, "user_id": userId}
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.TourExCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	executions := []*models.TourExecution{}
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+// FindByTourId returns every execution of a tour, newest first.
+func (r *TourExecutionRepository) FindByTourId(tourId int) ([]*models.TourExecution, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.TourExCollection.Find(ctx, bson.M{"tour_id": tourId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	executions := []*models.TourExecution{}
+	if err := cursor.All(ctx, &executions); err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
 func (r *TourExecutionRepository) CreateExecution(tourExecution *models.TourExecution) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -76,6 +133,10 @@ func (r *TourExecutionRepository) CreateExecution(tourExecution *models.TourExec
 	tourExecution.ID = seq
 
 	_, err = r.TourExCollection.InsertOne(ctx, tourExecution)
+	if mongo.IsDuplicateKeyError(err) {
+		// The partial unique index allows a single in-progress execution per user and tour
+		return errors.New("tour already in progress")
+	}
 	if err != nil {
 		fmt.Printf("Error creating tour execution: %v\n", err)
 		return fmt.Errorf("failed to create tour execution: %w", err)
@@ -125,39 +186,318 @@ func (r *TourExecutionRepository) AbortExecution(tourExecution *models.TourExecu
 	return http.StatusOK, nil
 }
 
-func (r *TourExecutionRepository) CompleateKeyPoint(executionId int, keyPoints []models.FinishedKeyPoint, now *time.Time) (any, error) {
+// CompleateKeyPoint adds a keypoint to the finished keypoints of an in-progress execution
+// and returns the updated execution. It returns nil when the keypoint was already finished
+// or the execution is no longer in progress, so each keypoint is completed once even when
+// several requests reach it at the same time.
+func (r *TourExecutionRepository) CompleateKeyPoint(executionId int, keyPoint models.FinishedKeyPoint) (*models.TourExecution, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"_id": executionId}
+	filter := bson.M{
+		"_id":                            executionId,
+		"status":                         models.ExecutionStatusInProgress,
+		"finished_keypoints.keypoint_id": bson.M{"$ne": keyPoint.KeypointID},
+	}
 	update := bson.M{
-		"finished_keypoints": keyPoints,
-		"last_activity":      now,
+		"$push": bson.M{"finished_keypoints": keyPoint},
+		"$set":  bson.M{"last_activity": keyPoint.CompletedAt},
 	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	_, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": update})
+	var tourExecution models.TourExecution
+	err := r.TourExCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&tourExecution)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
 		fmt.Printf("error adding key point %v\n", err)
-		return http.StatusInternalServerError, err
+		return nil, err
 	}
-	return http.StatusOK, nil
+	return &tourExecution, nil
 }
 
-func (r *TourExecutionRepository) CompleateTour(executionId int, now *time.Time) (any, error) {
+// CompleateTour completes an in-progress execution. It reports false when the execution
+// was no longer in progress, so a tour finished by two requests at once completes once.
+func (r *TourExecutionRepository) CompleateTour(executionId int, now *time.Time) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"_id": executionId}
+	filter := bson.M{"_id": executionId, "status": models.ExecutionStatusInProgress}
 	update := bson.M{
 		"ended_at":      now,
 		"last_activity": now,
 		"status":        models.ExecutionStatusCompleted,
 	}
 
-	_, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": update})
+	result, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": update})
 	if err != nil {
 		fmt.Printf("error completing tour %v\n", err)
-		return http.StatusInternalServerError, err
+		return false, err
 	}
-	return http.StatusOK, nil
+	return result.ModifiedCount > 0, nil
+}
+
+// TouchExecution records activity on an execution that is still in progress.
+func (r *TourExecutionRepository) TouchExecution(executionId int, now *time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": executionId, "status": models.ExecutionStatusInProgress}
+	_, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_activity": now}})
+	return err
+}
+
+// SetDwell stores the dwell counters of an execution together with the time of the
+// position fix they were last updated from.
+func (r *TourExecutionRepository) SetDwell(executionId int, dwell []models.DwellCounter, fixAt *time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": executionId, "status": models.ExecutionStatusInProgress}
+	_, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"dwell": dwell, "last_fix_at": fixAt}})
+	return err
+}
+
+// AddChallengeAttempt appends a keypoint challenge attempt to an execution that is still
+// in progress. The checks are part of the update, so parallel answers can't overshoot
+// maxAttempts (0 means unlimited) or land after the challenge was solved or the keypoint
+// finished. It reports false when any of them fails.
+func (r *TourExecutionRepository) AddChallengeAttempt(executionId int, attempt models.ChallengeAttempt, maxAttempts int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":                            executionId,
+		"status":                         models.ExecutionStatusInProgress,
+		"finished_keypoints.keypoint_id": bson.M{"$ne": attempt.KeypointID},
+		"challenge_attempts": bson.M{"$not": bson.M{"$elemMatch": bson.M{
+			"keypoint_id": attempt.KeypointID,
+			"correct":     true,
+		}}},
+	}
+	if maxAttempts > 0 {
+		keypointAttempts := bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$challenge_attempts", bson.A{}}},
+			"cond":  bson.M{"$eq": bson.A{"$$this.keypoint_id", attempt.KeypointID}},
+		}}
+		filter["$expr"] = bson.M{"$lt": bson.A{bson.M{"$size": keypointAttempts}, maxAttempts}}
+	}
+	update := bson.M{
+		"$push": bson.M{"challenge_attempts": attempt},
+		"$set":  bson.M{"last_activity": attempt.AttemptedAt},
+	}
+
+	result, err := r.TourExCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// AdvanceTrail moves the trail of an in-progress execution forward to a fix recorded at
+// at. It reports false when the trail already holds that fix or a newer one, so each fix
+// is appended once even when several requests see it at the same time.
+func (r *TourExecutionRepository) AdvanceTrail(executionId int, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":    executionId,
+		"status": models.ExecutionStatusInProgress,
+		"$or": bson.A{
+			bson.M{"last_trail_at": bson.M{"$exists": false}},
+			bson.M{"last_trail_at": bson.M{"$lt": at}},
+		},
+	}
+	result, err := r.TourExCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_trail_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// AbandonIdleExecutions fails every in-progress execution without activity since
+// idleSince and returns the IDs of the executions it abandoned. The status check is part
+// of each update, so running it again, or on several replicas at once, never touches an
+// execution twice.
+func (r *TourExecutionRepository) AbandonIdleExecutions(idleSince, now, resumableUntil time.Time) ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"status":        models.ExecutionStatusInProgress,
+		"last_activity": bson.M{"$lt": idleSince},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":          models.ExecutionStatusFailed,
+			"end_reason":      models.ExecutionEndReasonAbandoned,
+			"ended_at":        now,
+			"resumable_until": resumableUntil,
+		},
+	}
+
+	cursor, err := r.TourExCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find idle executions: %w", err)
+	}
+	var idle []struct {
+		ID int `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &idle); err != nil {
+		return nil, fmt.Errorf("failed to decode idle executions: %w", err)
+	}
+
+	abandoned := []int{}
+	for _, execution := range idle {
+		filter["_id"] = execution.ID
+		result, err := r.TourExCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return abandoned, fmt.Errorf("failed to abandon execution %d: %w", execution.ID, err)
+		}
+		if result.ModifiedCount > 0 {
+			abandoned = append(abandoned, execution.ID)
+		}
+	}
+	return abandoned, nil
+}
+
+// ResumeExecution puts an abandoned execution back in progress while it is still
+// resumable. It returns false when the execution can't be resumed (anymore).
+func (r *TourExecutionRepository) ResumeExecution(executionId int, now *time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":             executionId,
+		"status":          models.ExecutionStatusFailed,
+		"end_reason":      models.ExecutionEndReasonAbandoned,
+		"resumable_until": bson.M{"$gte": now},
+	}
+	update := bson.M{
+		"$set":   bson.M{"status": models.ExecutionStatusInProgress, "last_activity": now},
+		"$unset": bson.M{"ended_at": "", "end_reason": "", "resumable_until": ""},
+	}
+
+	result, err := r.TourExCollection.UpdateOne(ctx, filter, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, errors.New("tour already in progress")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to resume execution: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetExecutionSummary aggregates the executions of a tour started within [from, to):
+// counts by status, the median duration of completed executions, where aborted and
+// abandoned executions stopped, and the time between consecutive completed keypoints.
+// Nil bounds are open.
+func (r *TourExecutionRepository) GetExecutionSummary(tourId int, from, to *time.Time) (*models.ExecutionSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	match := bson.M{"tour_id": tourId}
+	if from != nil || to != nil {
+		startedAt := bson.M{}
+		if from != nil {
+			startedAt["$gte"] = *from
+		}
+		if to != nil {
+			startedAt["$lt"] = *to
+		}
+		match["started_at"] = startedAt
+	}
+	durationSeconds := func(end, start interface{}) bson.M {
+		return bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{end, start}}, 1000}}
+	}
+
+	summaryPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"statuses": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"status": "$status", "endReason": "$end_reason"},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$project": bson.M{"_id": 0, "status": "$_id.status", "endReason": "$_id.endReason", "count": 1}},
+			},
+			"duration": bson.A{
+				bson.M{"$match": bson.M{
+					"status":     models.ExecutionStatusCompleted,
+					"started_at": bson.M{"$ne": nil},
+					"ended_at":   bson.M{"$ne": nil},
+				}},
+				bson.M{"$group": bson.M{
+					"_id":    nil,
+					"median": bson.M{"$median": bson.M{"input": durationSeconds("$ended_at", "$started_at"), "method": "approximate"}},
+				}},
+			},
+			"dropOffs": bson.A{
+				bson.M{"$match": bson.M{"$or": bson.A{
+					bson.M{"status": models.ExecutionStatusAborted},
+					bson.M{"status": models.ExecutionStatusFailed},
+				}}},
+				bson.M{"$group": bson.M{
+					"_id": bson.M{
+						"completedCount": bson.M{"$size": bson.M{"$ifNull": bson.A{"$finished_keypoints", bson.A{}}}},
+						"lastKeypointId": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$finished_keypoints.keypoint_id", -1}}, 0}},
+					},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$project": bson.M{"_id": 0, "completedCount": "$_id.completedCount", "lastKeypointId": "$_id.lastKeypointId", "count": 1}},
+			},
+		}}},
+	}
+
+	cursor, err := r.TourExCollection.Aggregate(ctx, summaryPipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate executions: %w", err)
+	}
+	var summaries []models.ExecutionSummary
+	if err := cursor.All(ctx, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode execution summary: %w", err)
+	}
+	summary := &models.ExecutionSummary{}
+	if len(summaries) > 0 {
+		summary = &summaries[0]
+	}
+
+	// Each completion is paired with the previous one of the same execution, the first
+	// one with the start of the execution
+	segmentPipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$finished_keypoints"}},
+		{{Key: "$setWindowFields", Value: bson.M{
+			"partitionBy": "$_id",
+			"sortBy":      bson.M{"finished_keypoints.completed_at": 1},
+			"output": bson.M{
+				"previousKeypoint": bson.M{"$shift": bson.M{"output": "$finished_keypoints.keypoint_id", "by": -1, "default": 0}},
+				"previousAt":       bson.M{"$shift": bson.M{"output": "$finished_keypoints.completed_at", "by": -1, "default": nil}},
+			},
+		}}},
+		{{Key: "$match", Value: bson.M{"finished_keypoints.completed_at": bson.M{"$ne": nil}}}},
+		{{Key: "$project", Value: bson.M{
+			"from":    "$previousKeypoint",
+			"to":      "$finished_keypoints.keypoint_id",
+			"seconds": durationSeconds("$finished_keypoints.completed_at", bson.M{"$ifNull": bson.A{"$previousAt", "$started_at"}}),
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"from": "$from", "to": "$to"},
+			"count":   bson.M{"$sum": 1},
+			"average": bson.M{"$avg": "$seconds"},
+			"median":  bson.M{"$median": bson.M{"input": "$seconds", "method": "approximate"}},
+		}}},
+		{{Key: "$project", Value: bson.M{"_id": 0, "from": "$_id.from", "to": "$_id.to", "count": 1, "average": 1, "median": 1}}},
+	}
+
+	cursor, err = r.TourExCollection.Aggregate(ctx, segmentPipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate keypoint segments: %w", err)
+	}
+	if err := cursor.All(ctx, &summary.Segments); err != nil {
+		return nil, fmt.Errorf("failed to decode keypoint segments: %w", err)
+	}
+	return summary, nil
 }
@@ -4,10 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
 	"tours-service/internal/models"
 )
 
@@ -17,14 +18,16 @@ type Counter struct {
 }
 
 type TourRepository struct {
-	Collection         *mongo.Collection
-	CountersCollection *mongo.Collection
+	Collection          *mongo.Collection
+	CountersCollection  *mongo.Collection
+	KeypointsCollection *mongo.Collection
 }
 
 func NewTourRepository(db *mongo.Database) *TourRepository {
 	return &TourRepository{
-		Collection:         db.Collection("tours"),
-		CountersCollection: db.Collection("counters"),
+		Collection:          db.Collection("tours"),
+		CountersCollection:  db.Collection("counters"),
+		KeypointsCollection: db.Collection("keypoints"),
 	}
 }
 
@@ -53,8 +56,10 @@ func (r *TourRepository) CreateTour(tour *models.Tour) error {
 	tour.ID = nextID
 
 	tour.Status = models.StatusDraft
+	tour.Version = 1
+	tour.Rating = models.RatingSummary{Histogram: make([]int, 5)}
 	now := time.Now()
-  tour.TimeDrafted = &now
+	tour.TimeDrafted = &now
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -70,13 +75,27 @@ func (r *TourRepository) CreateTour(tour *models.Tour) error {
 	return nil
 }
 
-func (r *TourRepository) GetPublishedTours() ([]models.Tour, error) {
+func (r *TourRepository) GetPublishedTours(query models.TourListQuery) ([]models.Tour, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"status": models.StatusPublished}
+	filter := bson.M{"status": models.StatusPublished, "deletedAt": bson.M{"$exists": false}}
+	if query.MinRating > 0 {
+		filter["rating.average"] = bson.M{"$gte": query.MinRating}
+	}
+	if query.MinReviews > 0 {
+		filter["rating.count"] = bson.M{"$gte": query.MinReviews}
+	}
+
+	opts := options.Find()
+	switch query.Sort {
+	case models.TourSortRating:
+		opts.SetSort(bson.D{{Key: "rating.average", Value: -1}, {Key: "rating.count", Value: -1}, {Key: "_id", Value: 1}})
+	case models.TourSortReviews:
+		opts.SetSort(bson.D{{Key: "rating.count", Value: -1}, {Key: "rating.average", Value: -1}, {Key: "_id", Value: 1}})
+	}
 
-	cursor, err := r.Collection.Find(ctx, filter)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find published tours: %w", err)
 	}
@@ -90,11 +109,43 @@ func (r *TourRepository) GetPublishedTours() ([]models.Tour, error) {
 	return tours, nil
 }
 
+// GetTourIDsWithEstimatedStats returns the tours whose stats were estimated without map-service.
+func (r *TourRepository) GetTourIDsWithEstimatedStats() ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{
+		{"drivingStats.source": models.StatsEstimated},
+		{"walkingStats.source": models.StatsEstimated},
+		{"cyclingStats.source": models.StatsEstimated},
+	}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tours with estimated stats: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tourIDs []int
+	for cursor.Next(ctx) {
+		var tour struct {
+			ID int `bson:"_id"`
+		}
+		if err := cursor.Decode(&tour); err != nil {
+			return nil, fmt.Errorf("failed to decode tour: %w", err)
+		}
+		tourIDs = append(tourIDs, tour.ID)
+	}
+
+	return tourIDs, cursor.Err()
+}
+
 func (r *TourRepository) GetToursByAuthorID(authorID int) ([]models.Tour, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"authorId": authorID}
+	filter := bson.M{"authorId": authorID, "deletedAt": bson.M{"$exists": false}}
 
 	cursor, err := r.Collection.Find(ctx, filter)
 	if err != nil {
@@ -111,11 +162,18 @@ func (r *TourRepository) GetToursByAuthorID(authorID int) ([]models.Tour, error)
 }
 
 func (r *TourRepository) GetTourByID(tourID int) (*models.Tour, error) {
+	return r.getTour(bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": false}})
+}
+
+// GetDeletedTourByID returns a soft deleted tour.
+func (r *TourRepository) GetDeletedTourByID(tourID int) (*models.Tour, error) {
+	return r.getTour(bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": true}})
+}
+
+func (r *TourRepository) getTour(filter bson.M) (*models.Tour, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"_id": tourID}
-
 	var tour models.Tour
 	err := r.Collection.FindOne(ctx, filter).Decode(&tour)
 	if err != nil {
@@ -128,20 +186,325 @@ func (r *TourRepository) GetTourByID(tourID int) (*models.Tour, error) {
 	return &tour, nil
 }
 
-func (r *TourRepository) UpdateTour(tour *models.Tour) error {
+// versionFilter matches documents at the given version. Tours written before
+// versioning was introduced have no version field and count as version 0.
+func versionFilter(version int) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// UpdateTour applies a partial update, but only if the tour is still at expectedVersion.
+func (r *TourRepository) UpdateTour(tourID, expectedVersion int, update *models.TourUpdateRequest) (*models.Tour, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"_id": tour.ID}
-	update := bson.M{"$set": tour}
+	set := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Description != nil {
+		set["description"] = *update.Description
+	}
+	if update.Difficulty != nil {
+		set["difficulty"] = *update.Difficulty
+	}
+	if update.Tags != nil {
+		set["tags"] = *update.Tags
+	}
+	if update.CompletionMode != nil {
+		set["completionMode"] = *update.CompletionMode
+	}
+	if update.DefaultRadius != nil {
+		set["defaultRadius"] = *update.DefaultRadius
+	}
+	if update.Language != nil {
+		set["language"] = *update.Language
+	}
 
-	_, err := r.Collection.UpdateOne(ctx, filter, update)
+	filter := bson.M{"_id": tourID, "version": versionFilter(expectedVersion)}
+	change := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		change["$set"] = set
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tour models.Tour
+	err := r.Collection.FindOneAndUpdate(ctx, filter, change, opts).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, r.notFoundOrConflict(ctx, tourID)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("a tour with this name already exists for this author")
+		}
+		return nil, fmt.Errorf("failed to update tour: %w", err)
+	}
+	return &tour, nil
+}
+
+// notFoundOrConflict tells apart a missing tour from one whose version moved on.
+func (r *TourRepository) notFoundOrConflict(ctx context.Context, tourID int) error {
+	count, err := r.Collection.CountDocuments(ctx, bson.M{"_id": tourID})
+	if err != nil {
+		return fmt.Errorf("failed to check tour: %w", err)
+	}
+	if count == 0 {
+		return errors.New("tour not found")
+	}
+	return errors.New("version conflict: tour was modified by another request")
+}
+
+// SetSchedule replaces the automatic publish and archive times of a tour.
+func (r *TourRepository) SetSchedule(tourID int, publishAt, archiveAt *time.Time) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set, unset := bson.M{}, bson.M{}
+	if publishAt != nil {
+		set["scheduledPublishAt"] = publishAt
+	} else {
+		unset["scheduledPublishAt"] = ""
+	}
+	if archiveAt != nil {
+		set["scheduledArchiveAt"] = archiveAt
+	} else {
+		unset["scheduledArchiveAt"] = ""
+	}
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": false}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tour models.Tour
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("tour not found")
+		}
+		return nil, fmt.Errorf("failed to schedule tour: %w", err)
+	}
+	return &tour, nil
+}
+
+// GetToursWithDueSchedule returns ID and author of the tours with a publish or archive
+// time that has passed.
+func (r *TourRepository) GetToursWithDueSchedule(now time.Time) ([]models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"deletedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"scheduledPublishAt": bson.M{"$lte": now}},
+			bson.M{"scheduledArchiveAt": bson.M{"$lte": now}},
+		},
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "authorId": 1})
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find scheduled tours: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tours []models.Tour
+	if err = cursor.All(ctx, &tours); err != nil {
+		return nil, fmt.Errorf("failed to decode tours: %w", err)
+	}
+	return tours, nil
+}
+
+// PublishScheduledTour publishes a tour whose publish time has passed. It returns false
+// if the schedule was changed or carried out in the meantime.
+func (r *TourRepository) PublishScheduledTour(tourID int, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": tourID, "scheduledPublishAt": bson.M{"$lte": now}, "deletedAt": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set":   bson.M{"status": models.StatusPublished, "timePublished": now},
+		"$unset": bson.M{"scheduledPublishAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	res, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to publish scheduled tour: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ArchiveScheduledTour archives a published tour whose archive time has passed. An
+// archive time that passed while the tour wasn't published is dropped. It returns
+// whether the tour was archived.
+func (r *TourRepository) ArchiveScheduledTour(tourID int, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": tourID, "scheduledArchiveAt": bson.M{"$lte": now}, "deletedAt": bson.M{"$exists": false}}
+	published := bson.M{"status": models.StatusPublished}
+	for key, value := range filter {
+		published[key] = value
+	}
+	update := bson.M{
+		"$set":   bson.M{"status": models.StatusArchived, "timeArchived": now},
+		"$unset": bson.M{"scheduledArchiveAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	res, err := r.Collection.UpdateOne(ctx, published, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to archive scheduled tour: %w", err)
+	}
+	if res.ModifiedCount > 0 {
+		return true, nil
+	}
+
+	if _, err := r.Collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"scheduledArchiveAt": ""}}); err != nil {
+		return false, fmt.Errorf("failed to drop archive schedule: %w", err)
+	}
+	return false, nil
+}
+
+// GetDeletedToursByAuthorID lists the author's soft deleted tours, most recently deleted first.
+func (r *TourRepository) GetDeletedToursByAuthorID(authorID int) ([]models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"authorId": authorID, "deletedAt": bson.M{"$exists": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted tours by author ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tours := []models.Tour{}
+	if err = cursor.All(ctx, &tours); err != nil {
+		return nil, fmt.Errorf("failed to decode tours: %w", err)
+	}
+
+	return tours, nil
+}
+
+// SoftDeleteTour marks the tour deleted and turns it into a draft, so it leaves the
+// published listing. Keypoints stay for a restore until the tour is purged.
+func (r *TourRepository) SoftDeleteTour(tourID int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{
+			"deletedAt":   now,
+			"status":      models.StatusDraft,
+			"timeDrafted": now,
+		},
+		"$unset": bson.M{"scheduledPublishAt": "", "scheduledArchiveAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	result, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update tour: %w", err)
+		return fmt.Errorf("failed to delete tour: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("tour not found")
 	}
 	return nil
 }
 
+// RestoreDeletedTour brings a soft deleted tour back as a draft. It fails when the author
+// gave another tour the same name in the meantime.
+func (r *TourRepository) RestoreDeletedTour(tourID int) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tour models.Tour
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("deleted tour not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("a tour with this name already exists for this author")
+		}
+		return nil, fmt.Errorf("failed to restore tour: %w", err)
+	}
+	return &tour, nil
+}
+
+// GetTourIDsDeletedBefore returns the soft deleted tours whose retention period is over.
+func (r *TourRepository) GetTourIDsDeletedBefore(cutoff time.Time) ([]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.Collection.Find(ctx, bson.M{"deletedAt": bson.M{"$lte": cutoff}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted tours: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tourIDs []int
+	for cursor.Next(ctx) {
+		var tour struct {
+			ID int `bson:"_id"`
+		}
+		if err := cursor.Decode(&tour); err != nil {
+			return nil, fmt.Errorf("failed to decode tour: %w", err)
+		}
+		tourIDs = append(tourIDs, tour.ID)
+	}
+
+	return tourIDs, cursor.Err()
+}
+
+// PurgeTour removes a tour that was soft deleted before the cutoff for good, its keypoints
+// first, in one transaction so a failure never leaves keypoints without their tour. It
+// returns false if the tour was restored in the meantime.
+func (r *TourRepository) PurgeTour(tourID int, cutoff time.Time) (bool, error) {
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$lte": cutoff}}
+
+	purged := false
+	err := withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		purged = false
+		count, err := r.Collection.CountDocuments(ctx, filter)
+		if err != nil || count == 0 {
+			return err
+		}
+		if _, err := r.KeypointsCollection.DeleteMany(ctx, bson.M{"tourId": tourID}); err != nil {
+			return err
+		}
+		// Deleting the tour last also makes a concurrent restore conflict with the purge
+		result, err := r.Collection.DeleteOne(ctx, filter)
+		if err != nil {
+			return err
+		}
+		purged = result.DeletedCount > 0
+		if !purged {
+			return errors.New("tour was restored while purging it")
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to purge tour: %w", err)
+	}
+	return purged, nil
+}
+
 func (r *TourRepository) DeleteTour(tourID int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -159,16 +522,19 @@ func (r *TourRepository) DeleteTour(tourID int) error {
 	return nil
 }
 
-func (r *TourRepository) UpdateTourLength(tourID int, driving, walking, cycling models.DistanceAndDuration) error {
+// UpdateTourLength stores route stats computed by the server. They follow from the
+// guide's edits rather than being one, so the version stays and no editor gets a 409.
+func (r *TourRepository) UpdateTourLength(tourID int, driving, walking, cycling models.DistanceAndDuration, segments []models.RouteSegment) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	filter := bson.M{"_id": tourID}
 	update := bson.M{
 		"$set": bson.M{
-			"drivingStats": driving,
-			"walkingStats": walking,
-			"cyclingStats": cycling,
+			"drivingStats":  driving,
+			"walkingStats":  walking,
+			"cyclingStats":  cycling,
+			"routeSegments": segments,
 		},
 	}
 
@@ -187,11 +553,13 @@ func (r *TourRepository) PublishTour(tourID int) error {
 	now := time.Now()
 	filter := bson.M{"_id": tourID}
 	update := bson.M{
-    "$set": bson.M{
-      "status": models.StatusPublished,
-      "timePublished": &now,
-    },
-  }
+		"$set": bson.M{
+			"status":        models.StatusPublished,
+			"timePublished": &now,
+		},
+		"$unset": bson.M{"scheduledPublishAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
 
 	res, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -210,15 +578,17 @@ func (r *TourRepository) ArchiveTour(tourID int) error {
 
 	now := time.Now()
 	filter := bson.M{
-    "_id":    tourID,
-    "status": models.StatusPublished,
-  }
-  update := bson.M{
-    "$set": bson.M{
-      "status": models.StatusArchived,
-      "timeArchived": &now,
-    },
-  }
+		"_id":    tourID,
+		"status": models.StatusPublished,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.StatusArchived,
+			"timeArchived": &now,
+		},
+		"$unset": bson.M{"scheduledArchiveAt": ""},
+		"$inc":   bson.M{"version": 1},
+	}
 
 	res, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -245,7 +615,8 @@ func (r *TourRepository) ArchiveTour(tourID int) error {
 	return nil
 }
 
-func (r *TourRepository) SetTourPrice(tourID int, newPrice float64, authorID int) error {
+// SetTourPrice updates the price. A nil expectedVersion skips the version check.
+func (r *TourRepository) SetTourPrice(tourID int, newPrice float64, authorID int, expectedVersion *int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -253,8 +624,14 @@ func (r *TourRepository) SetTourPrice(tourID int, newPrice float64, authorID int
 		"_id":      tourID,
 		"authorId": authorID,
 	}
+	if expectedVersion != nil {
+		filter["version"] = versionFilter(*expectedVersion)
+	}
 
-	update := bson.M{"$set": bson.M{"price": newPrice}}
+	update := bson.M{
+		"$set": bson.M{"price": newPrice},
+		"$inc": bson.M{"version": 1},
+	}
 
 	res, err := r.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -262,8 +639,142 @@ func (r *TourRepository) SetTourPrice(tourID int, newPrice float64, authorID int
 	}
 
 	if res.MatchedCount == 0 {
+		if expectedVersion != nil {
+			count, err := r.Collection.CountDocuments(ctx, bson.M{"_id": tourID, "authorId": authorID})
+			if err == nil && count > 0 {
+				return errors.New("version conflict: tour was modified by another request")
+			}
+		}
 		return errors.New("tour not found or you are not the author")
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// SetTranslation adds or replaces the name and description of a tour in one language.
+func (r *TourRepository) SetTranslation(tourID int, language string, translation models.Translation) (*models.Tour, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": false}}
+	update := bson.M{
+		"$set": bson.M{"translations." + language: translation},
+		"$inc": bson.M{"version": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tour models.Tour
+	err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&tour)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("tour not found")
+		}
+		return nil, fmt.Errorf("failed to set tour translation: %w", err)
+	}
+	return &tour, nil
+}
+
+// DeleteTranslation removes one language from a tour. It reports false when the tour
+// has no translation in that language.
+func (r *TourRepository) DeleteTranslation(tourID int, language string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	field := "translations." + language
+	filter := bson.M{"_id": tourID, "deletedAt": bson.M{"$exists": false}, field: bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{field: ""},
+		"$inc":   bson.M{"version": 1},
+	}
+
+	res, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete tour translation: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RestoreTourContent copies the content of a snapshot back onto the tour and swaps its
+// keypoints for the snapshot's, in one transaction and only if the tour is still at
+// expectedVersion. The tour becomes a draft with no schedule, so the restored content is
+// only served once the guide publishes it again. Price and author are left as they are.
+// Keypoints get their old IDs back so finished keypoints in executions still match.
+func (r *TourRepository) RestoreTourContent(tourID, expectedVersion int, snapshot *models.Tour) (*models.Tour, error) {
+	var tour models.Tour
+	err := withTransaction(r.Collection.Database().Client(), func(ctx context.Context) error {
+		now := time.Now()
+		filter := bson.M{"_id": tourID, "version": versionFilter(expectedVersion)}
+		update := bson.M{
+			"$set": bson.M{
+				"status":         models.StatusDraft,
+				"timeDrafted":    now,
+				"name":           snapshot.Name,
+				"description":    snapshot.Description,
+				"language":       snapshot.Language,
+				"translations":   snapshot.Translations,
+				"difficulty":     snapshot.Difficulty,
+				"tags":           snapshot.Tags,
+				"completionMode": snapshot.CompletionMode,
+				"defaultRadius":  snapshot.DefaultRadius,
+				"drivingStats":   snapshot.DrivingStats,
+				"walkingStats":   snapshot.WalkingStats,
+				"cyclingStats":   snapshot.CyclingStats,
+				"routeSegments":  snapshot.RouteSegments,
+			},
+			"$unset": bson.M{"scheduledPublishAt": "", "scheduledArchiveAt": ""},
+			"$inc":   bson.M{"version": 1},
+		}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		if err := r.Collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&tour); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return r.notFoundOrConflict(ctx, tourID)
+			}
+			return err
+		}
+
+		cursor, err := r.KeypointsCollection.Find(ctx, bson.M{"tourId": tourID})
+		if err != nil {
+			return err
+		}
+		var current []models.Keypoint
+		if err := cursor.All(ctx, &current); err != nil {
+			return err
+		}
+		currentVersions := make(map[int]int, len(current))
+		for _, keypoint := range current {
+			currentVersions[keypoint.ID] = keypoint.Version
+		}
+
+		if _, err := r.KeypointsCollection.DeleteMany(ctx, bson.M{"tourId": tourID}); err != nil {
+			return err
+		}
+		if len(snapshot.Keypoints) == 0 {
+			return nil
+		}
+		docs := make([]interface{}, 0, len(snapshot.Keypoints))
+		for i, keypoint := range snapshot.Keypoints {
+			// Snapshots list keypoints in order, renumbering drops duplicate ordinals of old ones
+			keypoint.Ordinal = i + 1
+			// Versions only move forward, otherwise an old ETag could match again
+			if version, ok := currentVersions[keypoint.ID]; ok && version > keypoint.Version {
+				keypoint.Version = version
+			}
+			keypoint.Version++
+			keypoint.TourID = tourID
+			docs = append(docs, keypoint)
+		}
+		_, err = r.KeypointsCollection.InsertMany(ctx, docs)
+		return err
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "version conflict") {
+			return nil, err
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("a tour with this name already exists for this author")
+		}
+		return nil, fmt.Errorf("failed to restore tour: %w", err)
+	}
+
+	return &tour, nil
+}
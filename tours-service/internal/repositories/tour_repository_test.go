@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVersionFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		want    interface{}
+	}{
+		{name: "documents written before versioning match version 0", version: 0, want: bson.M{"$in": bson.A{0, nil}}},
+		{name: "first version", version: 1, want: 1},
+		{name: "later version", version: 42, want: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := versionFilter(tt.version); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("versionFilter(%d) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
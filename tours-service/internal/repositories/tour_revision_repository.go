@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tours-service/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TourRevisionRepository struct {
+	Collection         *mongo.Collection
+	PinsCollection     *mongo.Collection
+	CountersCollection *mongo.Collection
+}
+
+func NewTourRevisionRepository(db *mongo.Database) *TourRevisionRepository {
+	return &TourRevisionRepository{
+		Collection:         db.Collection("tour_revisions"),
+		PinsCollection:     db.Collection("tour_revision_pins"),
+		CountersCollection: db.Collection("counters"),
+	}
+}
+
+func (r *TourRevisionRepository) getNextSequenceValue(sequenceName string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var counter Counter
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	filter := bson.M{"_id": sequenceName}
+	update := bson.M{"$inc": bson.M{"value": 1}}
+
+	err := r.CountersCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next sequence value for %s: %w", sequenceName, err)
+	}
+
+	return counter.Value, nil
+}
+
+// CreateRevision assigns both the global ID and the per-tour revision number.
+func (r *TourRevisionRepository) CreateRevision(revision *models.TourRevision) error {
+	nextID, err := r.getNextSequenceValue("tour_revision_id")
+	if err != nil {
+		return err
+	}
+	number, err := r.getNextSequenceValue("tour_revision_" + strconv.Itoa(revision.TourID))
+	if err != nil {
+		return err
+	}
+	revision.ID = nextID
+	revision.Number = number
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = r.Collection.InsertOne(ctx, revision)
+	if err != nil {
+		return fmt.Errorf("failed to create tour revision: %w", err)
+	}
+
+	return nil
+}
+
+// GetRevisionsByTourID lists revisions newest first, without their snapshots.
+func (r *TourRevisionRepository) GetRevisionsByTourID(tourID int) ([]models.TourRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tourId": tourID}
+	opts := options.Find().
+		SetSort(bson.M{"number": -1}).
+		SetProjection(bson.M{"snapshot": 0})
+
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tour revisions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.TourRevision
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to decode tour revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *TourRevisionRepository) GetRevision(tourID, number int) (*models.TourRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tourId": tourID, "number": number}
+
+	var revision models.TourRevision
+	err := r.Collection.FindOne(ctx, filter).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("revision not found")
+		}
+		return nil, fmt.Errorf("failed to find revision: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// GetLatestRevision returns nil, nil if the tour has no history yet.
+func (r *TourRevisionRepository) GetLatestRevision(tourID int) (*models.TourRevision, error) {
+	return r.findOneSorted(bson.M{"tourId": tourID}, -1)
+}
+
+// GetRevisionAt returns the revision that was current at the given time, or the
+// oldest one if the tour has no history reaching that far back.
+func (r *TourRevisionRepository) GetRevisionAt(tourID int, at time.Time) (*models.TourRevision, error) {
+	revision, err := r.findOneSorted(bson.M{"tourId": tourID, "createdAt": bson.M{"$lte": at}}, -1)
+	if err != nil || revision != nil {
+		return revision, err
+	}
+	return r.findOneSorted(bson.M{"tourId": tourID}, 1)
+}
+
+func (r *TourRevisionRepository) findOneSorted(filter bson.M, direction int) (*models.TourRevision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.M{"number": direction})
+
+	var revision models.TourRevision
+	err := r.Collection.FindOne(ctx, filter, opts).Decode(&revision)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find revision: %w", err)
+	}
+
+	return &revision, nil
+}
+
+// GetPin returns nil, nil if the tourist hasn't been pinned to a revision yet.
+func (r *TourRevisionRepository) GetPin(tourID, touristID int) (*models.RevisionPin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tourId": tourID, "touristId": touristID}
+
+	var pin models.RevisionPin
+	err := r.PinsCollection.FindOne(ctx, filter).Decode(&pin)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find revision pin: %w", err)
+	}
+
+	return &pin, nil
+}
+
+func (r *TourRevisionRepository) SetPin(pin *models.RevisionPin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"tourId": pin.TourID, "touristId": pin.TouristID}
+	update := bson.M{"$set": pin}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := r.PinsCollection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save revision pin: %w", err)
+	}
+
+	return nil
+}
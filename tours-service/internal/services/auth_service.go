@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"tours-service/internal/models"
 )
@@ -18,73 +19,90 @@ func NewAuthService() *AuthService {
 }
 
 func (s *AuthService) ValidateAndGetUserID(r *http.Request, role string) (int, error) {
+	validation, err := s.ValidateAnyRole(r, role)
+	if err != nil {
+		return 0, err
+	}
+	return validation.UserID, nil
+}
+
+// ValidateAnyRole accepts a user holding any of the given roles and reports which one.
+func (s *AuthService) ValidateAnyRole(r *http.Request, roles ...string) (*models.ValidationResponse, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return 0, errors.New("authorization header is required")
+		return nil, errors.New("authorization header is required")
 	}
 
-	validationURL := os.Getenv("STAKEHOLDERS_SERVICE_URL") + "/api/validateRole?role=" + role
+	query := url.Values{"role": roles}
+	validationURL := os.Getenv("STAKEHOLDERS_SERVICE_URL") + "/api/validateRole?" + query.Encode()
 	req, err := http.NewRequest("POST", validationURL, nil)
 	if err != nil {
-		return 0, errors.New("failed to create validation request")
+		return nil, errors.New("failed to create validation request")
 	}
 	req.Header.Set("Authorization", authHeader)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, errors.New("failed to contact authentication service")
+		return nil, errors.New("failed to contact authentication service")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		var errorBody bytes.Buffer
 		errorBody.ReadFrom(resp.Body)
-		return 0, fmt.Errorf("unauthorized: %s", errorBody.String())
+		return nil, fmt.Errorf("unauthorized: %s", errorBody.String())
 	}
 
 	var validationResp models.ValidationResponse
 	if err := json.NewDecoder(resp.Body).Decode(&validationResp); err != nil {
-		return 0, errors.New("failed to decode validation response")
+		return nil, errors.New("failed to decode validation response")
 	}
 
-	return validationResp.UserID, nil
+	return &validationResp, nil
 }
 
 func (s *AuthService) GetMyPosition(r *http.Request) (float64, float64, error) {
+	fix, err := s.GetMyPositionFix(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return fix.Longitude, fix.Latitude, nil
+}
+
+// GetMyPositionFix returns the caller's last reported position with its GPS accuracy
+// and the time it was recorded.
+func (s *AuthService) GetMyPositionFix(r *http.Request) (*models.PositionFix, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return 0, 0, errors.New("authorization header is required")
+		return nil, errors.New("authorization header is required")
 	}
 
 	validationURL := os.Getenv("STAKEHOLDERS_SERVICE_URL") + "/api/position"
 	fmt.Println("Validation URL:", validationURL)
 	req, err := http.NewRequest("GET", validationURL, nil)
 	if err != nil {
-		return 0, 0, errors.New("failed to create position request")
+		return nil, errors.New("failed to create position request")
 	}
 	req.Header.Set("Authorization", authHeader)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, 0, errors.New("failed to contact position service")
+		return nil, errors.New("failed to contact position service")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		var errorBody bytes.Buffer
 		errorBody.ReadFrom(resp.Body)
-		return 0, 0, fmt.Errorf("bad request: %s", errorBody.String())
+		return nil, fmt.Errorf("bad request: %s", errorBody.String())
 	}
 
-	var respData = struct {
-		Longitude float64 `json:"longitude"`
-		Latitude  float64 `json:"latitude"`
-	}{}
-	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
-		return 0, 0, errors.New("failed to decode validation response")
+	var fix models.PositionFix
+	if err := json.NewDecoder(resp.Body).Decode(&fix); err != nil {
+		return nil, errors.New("failed to decode validation response")
 	}
 
-	return respData.Longitude, respData.Latitude, nil
+	return &fix, nil
 }
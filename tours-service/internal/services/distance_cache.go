@@ -0,0 +1,108 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+// DistanceCache keeps map-service results in an in-memory LRU backed by a Mongo
+// collection, so lookups survive restarts and are shared between replicas.
+type DistanceCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+	repo     *repositories.DistanceCacheRepository
+}
+
+func NewDistanceCache(capacity int, repo *repositories.DistanceCacheRepository) *DistanceCache {
+	return &DistanceCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		repo:     repo,
+	}
+}
+
+// distanceCacheKey identifies a leg by profile and endpoints rounded to 5 decimals.
+func distanceCacheKey(profile string, origin, dest models.Keypoint) string {
+	return fmt.Sprintf("%s:%.5f,%.5f:%.5f,%.5f", profile, origin.Latitude, origin.Longitude, dest.Latitude, dest.Longitude)
+}
+
+// Get returns the cached entries for the keys, reading the ones missing from memory
+// from Mongo. A Mongo failure is logged and treated as a miss.
+func (c *DistanceCache) Get(keys []string) map[string]models.DistanceCacheEntry {
+	found := make(map[string]models.DistanceCacheEntry, len(keys))
+	var missing []string
+
+	c.mu.Lock()
+	for _, key := range keys {
+		if element, ok := c.items[key]; ok {
+			c.order.MoveToFront(element)
+			found[key] = element.Value.(models.DistanceCacheEntry)
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 || c.repo == nil {
+		return found
+	}
+
+	stored, err := c.repo.GetEntries(missing)
+	if err != nil {
+		fmt.Printf("Warning: Failed to read distance cache: %v\n", err)
+		return found
+	}
+
+	c.mu.Lock()
+	for key, entry := range stored {
+		found[key] = entry
+		c.add(entry)
+	}
+	c.mu.Unlock()
+
+	return found
+}
+
+// Put stores the entries in memory and in Mongo. A Mongo failure is only logged.
+func (c *DistanceCache) Put(entries []models.DistanceCacheEntry) {
+	now := time.Now()
+
+	c.mu.Lock()
+	for i := range entries {
+		entries[i].CreatedAt = now
+		c.add(entries[i])
+	}
+	c.mu.Unlock()
+
+	if c.repo == nil {
+		return
+	}
+	if err := c.repo.SaveEntries(entries); err != nil {
+		fmt.Printf("Warning: Failed to write distance cache: %v\n", err)
+	}
+}
+
+// add inserts or refreshes an entry and evicts the least recently used one when full.
+// The caller must hold c.mu.
+func (c *DistanceCache) add(entry models.DistanceCacheEntry) {
+	if element, ok := c.items[entry.Key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[entry.Key] = c.order.PushFront(entry)
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(models.DistanceCacheEntry).Key)
+	}
+}
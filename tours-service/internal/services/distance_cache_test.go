@@ -0,0 +1,111 @@
+package services
+
+import (
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestDistanceCacheKey(t *testing.T) {
+	origin := models.Keypoint{Latitude: 45.251671, Longitude: 19.836694}
+	dest := models.Keypoint{Latitude: 45.255, Longitude: 19.8452}
+
+	tests := []struct {
+		name    string
+		profile string
+		origin  models.Keypoint
+		dest    models.Keypoint
+		want    string
+	}{
+		{name: "rounds to five decimals", profile: models.ProfileWalking, origin: origin, dest: dest, want: "foot-walking:45.25167,19.83669:45.25500,19.84520"},
+		{name: "profile is part of the key", profile: models.ProfileDriving, origin: origin, dest: dest, want: "driving-car:45.25167,19.83669:45.25500,19.84520"},
+		{name: "direction is part of the key", profile: models.ProfileWalking, origin: dest, dest: origin, want: "foot-walking:45.25500,19.84520:45.25167,19.83669"},
+		{name: "moves below the precision share a key", profile: models.ProfileWalking, origin: models.Keypoint{Latitude: 45.2516712, Longitude: 19.8366939}, dest: dest, want: "foot-walking:45.25167,19.83669:45.25500,19.84520"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := distanceCacheKey(tt.profile, tt.origin, tt.dest); got != tt.want {
+				t.Errorf("distanceCacheKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistanceCacheEviction(t *testing.T) {
+	entry := func(key string) models.DistanceCacheEntry {
+		return models.DistanceCacheEntry{Key: key, Distance: float64(len(key))}
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		puts     [][]string // each Put call
+		touch    []string   // read between the first and the remaining puts
+		wantHit  []string
+		wantMiss []string
+	}{
+		{
+			name:     "within capacity",
+			capacity: 3,
+			puts:     [][]string{{"a", "b", "c"}},
+			wantHit:  []string{"a", "b", "c"},
+		},
+		{
+			name:     "least recently stored is evicted",
+			capacity: 2,
+			puts:     [][]string{{"a", "b"}, {"c"}},
+			wantHit:  []string{"b", "c"},
+			wantMiss: []string{"a"},
+		},
+		{
+			name:     "reading an entry keeps it",
+			capacity: 2,
+			puts:     [][]string{{"a", "b"}, {"c"}},
+			touch:    []string{"a"},
+			wantHit:  []string{"a", "c"},
+			wantMiss: []string{"b"},
+		},
+		{
+			name:     "storing an entry again refreshes it",
+			capacity: 2,
+			puts:     [][]string{{"a", "b"}, {"a", "c"}},
+			wantHit:  []string{"a", "c"},
+			wantMiss: []string{"b"},
+		},
+		{
+			name:     "no capacity means unbounded",
+			capacity: 0,
+			puts:     [][]string{{"a", "b"}, {"c", "d"}},
+			wantHit:  []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewDistanceCache(tt.capacity, nil)
+			for i, keys := range tt.puts {
+				if i == 1 && len(tt.touch) > 0 {
+					cache.Get(tt.touch)
+				}
+				entries := make([]models.DistanceCacheEntry, 0, len(keys))
+				for _, key := range keys {
+					entries = append(entries, entry(key))
+				}
+				cache.Put(entries)
+			}
+
+			found := cache.Get(append(append([]string{}, tt.wantHit...), tt.wantMiss...))
+			for _, key := range tt.wantHit {
+				if _, ok := found[key]; !ok {
+					t.Errorf("%q was evicted, want it cached", key)
+				}
+			}
+			for _, key := range tt.wantMiss {
+				if _, ok := found[key]; ok {
+					t.Errorf("%q is cached, want it evicted", key)
+				}
+			}
+		})
+	}
+}
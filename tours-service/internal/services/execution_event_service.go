@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+// ExecutionEventService stores execution events and fans them out to the live streams
+// of this instance. Streams also poll the store, so events published by another
+// instance, like the sweeper abandoning an execution, still reach them.
+type ExecutionEventService struct {
+	EventRepository *repositories.ExecutionEventRepository
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan models.ExecutionEvent]struct{}
+}
+
+func NewExecutionEventService(eventRepository *repositories.ExecutionEventRepository) *ExecutionEventService {
+	return &ExecutionEventService{
+		EventRepository: eventRepository,
+		subscribers:     make(map[int]map[chan models.ExecutionEvent]struct{}),
+	}
+}
+
+// Publish stores an event and pushes it to the streams of its execution.
+func (s *ExecutionEventService) Publish(event models.ExecutionEvent) error {
+	event.CreatedAt = time.Now()
+	if err := s.EventRepository.AppendEvent(&event); err != nil {
+		return err
+	}
+	s.notify(event)
+	return nil
+}
+
+// PublishOrWarn is Publish for callers that should not fail because of an event.
+func (s *ExecutionEventService) PublishOrWarn(event models.ExecutionEvent) {
+	if err := s.Publish(event); err != nil {
+		fmt.Printf("Warning: Failed to publish %s event of execution %d: %v\n", event.Type, event.ExecutionID, err)
+	}
+}
+
+// PublishLive pushes a live-only event, such as a distance update, without storing it.
+func (s *ExecutionEventService) PublishLive(event models.ExecutionEvent) {
+	event.Seq = 0
+	event.CreatedAt = time.Now()
+	s.notify(event)
+}
+
+// Subscribe returns a channel receiving the events of an execution published on this
+// instance, and a function that ends the subscription.
+func (s *ExecutionEventService) Subscribe(executionID int) (<-chan models.ExecutionEvent, func()) {
+	ch := make(chan models.ExecutionEvent, 32)
+
+	s.mu.Lock()
+	if s.subscribers[executionID] == nil {
+		s.subscribers[executionID] = make(map[chan models.ExecutionEvent]struct{})
+	}
+	s.subscribers[executionID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers[executionID], ch)
+		if len(s.subscribers[executionID]) == 0 {
+			delete(s.subscribers, executionID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// EventsAfter returns the stored events of an execution after seq, for replay.
+func (s *ExecutionEventService) EventsAfter(executionID int, seq int64) ([]models.ExecutionEvent, error) {
+	return s.EventRepository.GetEventsAfter(executionID, seq)
+}
+
+// notify never blocks: a stream too slow to keep up misses live events, and picks up
+// stored ones on its next poll.
+func (s *ExecutionEventService) notify(event models.ExecutionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers[event.ExecutionID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+// Job types run by the background workers
+const (
+	JobRecalculateTourLength = "recalculate-tour-length"
+	JobResolveEstimatedStats = "resolve-estimated-stats"
+	JobEnsureIndexes         = "ensure-indexes"
+	JobSweepIdleExecutions   = "sweep-idle-executions"
+	JobRecalculateRatings    = "recalculate-ratings"
+	JobPurgeDeletedTours     = "purge-deleted-tours"
+	JobApplyTourSchedules    = "apply-tour-schedules"
+)
+
+const (
+	defaultJobMaxAttempts = 5
+	jobLease              = 5 * time.Minute
+	jobPollInterval       = 2 * time.Second
+	jobBaseBackoff        = 10 * time.Second
+	jobMaxBackoff         = time.Hour
+)
+
+// JobHandler runs one job. Returning an error schedules a retry with backoff until the
+// job runs out of attempts and is dead-lettered.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+type jobDefinition struct {
+	handler     JobHandler
+	maxAttempts int
+	interval    time.Duration // periodic jobs re-enqueue themselves after every run
+}
+
+// JobService is a durable job queue on top of the jobs collection. Any number of
+// replicas can run workers, jobs are leased so each one runs on a single worker.
+type JobService struct {
+	JobRepo  *repositories.JobRepository
+	workerID string
+
+	mu          sync.RWMutex
+	definitions map[string]jobDefinition
+}
+
+func NewJobService(jobRepo *repositories.JobRepository) *JobService {
+	hostname, _ := os.Hostname()
+	return &JobService{
+		JobRepo:     jobRepo,
+		workerID:    hostname + "-" + strconv.Itoa(os.Getpid()),
+		definitions: make(map[string]jobDefinition),
+	}
+}
+
+// Register sets the handler of a job type. maxAttempts <= 0 uses the default.
+func (s *JobService) Register(jobType string, maxAttempts int, handler JobHandler) {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.definitions[jobType] = jobDefinition{handler: handler, maxAttempts: maxAttempts}
+}
+
+// RegisterPeriodic registers a job that runs every interval on one replica at a time
+// and enqueues its first run.
+func (s *JobService) RegisterPeriodic(jobType string, interval time.Duration, handler JobHandler) error {
+	s.mu.Lock()
+	s.definitions[jobType] = jobDefinition{handler: handler, maxAttempts: defaultJobMaxAttempts, interval: interval}
+	s.mu.Unlock()
+
+	return s.EnqueueAt(jobType, nil, jobType, time.Now())
+}
+
+// Enqueue schedules a job to run as soon as a worker is free. Jobs with the same
+// uniqueKey that are still pending are merged into one.
+func (s *JobService) Enqueue(jobType string, payload map[string]interface{}, uniqueKey string) error {
+	return s.EnqueueAt(jobType, payload, uniqueKey, time.Now())
+}
+
+func (s *JobService) EnqueueAt(jobType string, payload map[string]interface{}, uniqueKey string, runAt time.Time) error {
+	s.mu.RLock()
+	definition, ok := s.definitions[jobType]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown job type %q", jobType)
+	}
+
+	return s.JobRepo.EnqueueJob(&models.Job{
+		Type:        jobType,
+		Payload:     payload,
+		UniqueKey:   uniqueKey,
+		MaxAttempts: definition.maxAttempts,
+		RunAt:       runAt,
+	})
+}
+
+// EnqueueTourRecalculation schedules a refresh of a tour's stats and route geometry.
+func (s *JobService) EnqueueTourRecalculation(tourID int) error {
+	return s.Enqueue(JobRecalculateTourLength, map[string]interface{}{"tourId": tourID},
+		JobRecalculateTourLength+":"+strconv.Itoa(tourID))
+}
+
+// Start runs the given number of workers until ctx is done.
+func (s *JobService) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go s.work(ctx, fmt.Sprintf("%s-%d", s.workerID, i))
+	}
+}
+
+func (s *JobService) work(ctx context.Context, workerID string) {
+	for {
+		job, err := s.JobRepo.ClaimJob(workerID, jobLease)
+		if err != nil {
+			fmt.Printf("Warning: Failed to claim job: %v\n", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+
+		s.run(ctx, workerID, job)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (s *JobService) run(ctx context.Context, workerID string, job *models.Job) {
+	s.mu.RLock()
+	definition, ok := s.definitions[job.Type]
+	s.mu.RUnlock()
+
+	var err error
+	switch {
+	case !ok:
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	case job.Attempts > job.MaxAttempts:
+		// The lease ran out on the last attempt, the worker most likely crashed on it
+		err = fmt.Errorf("job lease expired on its last attempt")
+	default:
+		jobCtx, cancel := context.WithTimeout(ctx, jobLease)
+		err = s.safeRun(jobCtx, definition.handler, job)
+		cancel()
+	}
+
+	if err == nil {
+		if err := s.JobRepo.CompleteJob(job.ID, workerID); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	} else {
+		retryAt := jobRetryAt(job, ok, time.Now())
+		if retryAt == nil {
+			fmt.Printf("Warning: Job %d (%s) dead-lettered: %v\n", job.ID, job.Type, err)
+		}
+		if err := s.JobRepo.FailJob(job.ID, workerID, err.Error(), retryAt); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	if ok && definition.interval > 0 && (err == nil || job.Attempts >= job.MaxAttempts) {
+		if err := s.EnqueueAt(job.Type, job.Payload, job.UniqueKey, time.Now().Add(definition.interval)); err != nil {
+			fmt.Printf("Warning: Failed to schedule next %s job: %v\n", job.Type, err)
+		}
+	}
+}
+
+// safeRun turns a panicking handler into a failed attempt instead of a dead worker.
+func (s *JobService) safeRun(ctx context.Context, handler JobHandler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// jobRetryAt returns when a failed job runs again, or nil when it is dead-lettered
+// because it has no handler or used up its attempts.
+func jobRetryAt(job *models.Job, registered bool, now time.Time) *time.Time {
+	if !registered || job.Attempts >= job.MaxAttempts {
+		return nil
+	}
+	next := now.Add(jobBackoff(job.Attempts))
+	return &next
+}
+
+// jobBackoff doubles the delay with every attempt: 10s, 20s, 40s... capped at an hour.
+func jobBackoff(attempts int) time.Duration {
+	delay := time.Duration(float64(jobBaseBackoff) * math.Pow(2, float64(attempts-1)))
+	if delay > jobMaxBackoff || delay <= 0 {
+		return jobMaxBackoff
+	}
+	return delay
+}
+
+func (s *JobService) GetJobs(status models.JobStatus, jobType string, limit int) (*models.JobListResponse, error) {
+	jobs, err := s.JobRepo.GetJobs(status, jobType, limit)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.JobRepo.CountJobsByStatus()
+	if err != nil {
+		return nil, err
+	}
+	return &models.JobListResponse{Jobs: jobs, Counts: counts}, nil
+}
+
+func (s *JobService) GetJobByID(jobID int) (*models.Job, error) {
+	return s.JobRepo.GetJobByID(jobID)
+}
+
+func (s *JobService) RetryJob(jobID int) (*models.Job, error) {
+	return s.JobRepo.RetryJob(jobID)
+}
+
+// PayloadInt reads an integer from a job payload. Numbers come back from Mongo as
+// int32, int64 or float64 depending on how they were stored.
+func PayloadInt(job *models.Job, key string) (int, error) {
+	switch value := job.Payload[key].(type) {
+	case int:
+		return value, nil
+	case int32:
+		return int(value), nil
+	case int64:
+		return int(value), nil
+	case float64:
+		return int(value), nil
+	}
+	return 0, fmt.Errorf("job %d has no integer %q in its payload", job.ID, key)
+}
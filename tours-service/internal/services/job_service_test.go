@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"tours-service/internal/models"
+)
+
+func TestJobBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 3, want: 40 * time.Second},
+		{attempts: 9, want: 2560 * time.Second},
+		{attempts: 10, want: time.Hour},
+		{attempts: 100, want: time.Hour},
+		{attempts: 5000, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := jobBackoff(tt.attempts); got != tt.want {
+			t.Errorf("jobBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestJobRetryAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		attempts    int
+		maxAttempts int
+		registered  bool
+		want        time.Duration // delay after now, ignored when dead-lettered
+		deadLetter  bool
+	}{
+		{name: "first failure", attempts: 1, maxAttempts: 5, registered: true, want: 10 * time.Second},
+		{name: "later failure backs off", attempts: 3, maxAttempts: 5, registered: true, want: 40 * time.Second},
+		{name: "last attempt is dead-lettered", attempts: 5, maxAttempts: 5, registered: true, deadLetter: true},
+		{name: "attempts past the limit", attempts: 6, maxAttempts: 5, registered: true, deadLetter: true},
+		{name: "single attempt job", attempts: 1, maxAttempts: 1, registered: true, deadLetter: true},
+		{name: "unknown job type", attempts: 1, maxAttempts: 5, registered: false, deadLetter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &models.Job{Attempts: tt.attempts, MaxAttempts: tt.maxAttempts}
+			got := jobRetryAt(job, tt.registered, now)
+			if tt.deadLetter {
+				if got != nil {
+					t.Fatalf("jobRetryAt() = %v, want dead-lettered", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("jobRetryAt() dead-lettered, want a retry")
+			}
+			if delay := got.Sub(now); delay != tt.want {
+				t.Errorf("retry delay = %v, want %v", delay, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeRun(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		handler JobHandler
+		wantErr bool
+	}{
+		{
+			name:    "success",
+			handler: func(ctx context.Context, job *models.Job) error { return nil },
+		},
+		{
+			name:    "error",
+			handler: func(ctx context.Context, job *models.Job) error { return errFailed },
+			wantErr: true,
+		},
+		{
+			name:    "panic becomes an error",
+			handler: func(ctx context.Context, job *models.Job) error { panic("boom") },
+			wantErr: true,
+		},
+	}
+
+	s := &JobService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.safeRun(context.Background(), tt.handler, &models.Job{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("safeRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
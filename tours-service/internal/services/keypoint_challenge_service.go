@@ -0,0 +1,244 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tours-service/internal/models"
+)
+
+// ErrInvalidChallenge wraps every rejected keypoint challenge.
+var ErrInvalidChallenge = errors.New("invalid challenge")
+
+// ValidateChallenge checks a challenge written by a guide and trims its text in place.
+func ValidateChallenge(challenge *models.KeypointChallenge) error {
+	challenge.Question = strings.TrimSpace(challenge.Question)
+	for i := range challenge.Options {
+		challenge.Options[i] = strings.TrimSpace(challenge.Options[i])
+	}
+	for i := range challenge.Answers {
+		challenge.Answers[i] = strings.TrimSpace(challenge.Answers[i])
+		if challenge.Answers[i] == "" {
+			return fmt.Errorf("%w: answers can't be empty", ErrInvalidChallenge)
+		}
+	}
+	if challenge.MaxAttempts < 0 {
+		return fmt.Errorf("%w: maxAttempts can't be negative", ErrInvalidChallenge)
+	}
+
+	switch challenge.Type {
+	case models.ChallengeQuiz:
+		if challenge.Question == "" {
+			return fmt.Errorf("%w: a quiz needs a question", ErrInvalidChallenge)
+		}
+		if len(challenge.Answers) == 0 {
+			return fmt.Errorf("%w: a quiz needs at least one accepted answer", ErrInvalidChallenge)
+		}
+		if len(challenge.Options) == 0 {
+			return nil
+		}
+		if len(challenge.Options) < 2 {
+			return fmt.Errorf("%w: a multiple choice quiz needs at least two options", ErrInvalidChallenge)
+		}
+		for _, answer := range challenge.Answers {
+			if !containsAnswer(challenge.Options, answer) {
+				return fmt.Errorf("%w: answer %q is not one of the options", ErrInvalidChallenge, answer)
+			}
+		}
+	case models.ChallengeCode:
+		if len(challenge.Answers) == 0 {
+			return fmt.Errorf("%w: a code challenge needs at least one code", ErrInvalidChallenge)
+		}
+		if len(challenge.Options) > 0 {
+			return fmt.Errorf("%w: a code challenge has no options", ErrInvalidChallenge)
+		}
+	case models.ChallengePhoto:
+		if len(challenge.Answers) > 0 || len(challenge.Options) > 0 {
+			return fmt.Errorf("%w: a photo challenge has no answers or options", ErrInvalidChallenge)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q, use quiz, code or photo", ErrInvalidChallenge, challenge.Type)
+	}
+	return nil
+}
+
+// normalizeAnswer makes answers compare regardless of case and spacing.
+func normalizeAnswer(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+func containsAnswer(accepted []string, answer string) bool {
+	answer = normalizeAnswer(answer)
+	for _, candidate := range accepted {
+		if normalizeAnswer(candidate) == answer {
+			return true
+		}
+	}
+	return false
+}
+
+// HideChallengeAnswers drops the accepted answers from keypoints served to tourists.
+func HideChallengeAnswers(keypoints []models.Keypoint) {
+	for i := range keypoints {
+		if keypoints[i].Challenge != nil {
+			challenge := *keypoints[i].Challenge
+			challenge.Answers = nil
+			keypoints[i].Challenge = &challenge
+		}
+	}
+}
+
+// challengeSolved reports whether the keypoint's challenge was answered correctly during
+// the execution, keypoints without a challenge need nothing solved.
+func challengeSolved(tourExecution *models.TourExecution, keypoint models.Keypoint) bool {
+	if keypoint.Challenge == nil {
+		return true
+	}
+	for _, attempt := range tourExecution.ChallengeAttempts {
+		if attempt.KeypointID == keypoint.ID && attempt.Correct {
+			return true
+		}
+	}
+	return false
+}
+
+// challengeFailed reports whether the execution ran out of attempts at the keypoint's
+// challenge without solving it. Such a keypoint is skipped: it is finished with
+// ChallengeFailed set so the tour can go on, but it never counts as solved.
+func challengeFailed(tourExecution *models.TourExecution, keypoint models.Keypoint) bool {
+	if keypoint.Challenge == nil || keypoint.Challenge.MaxAttempts == 0 || challengeSolved(tourExecution, keypoint) {
+		return false
+	}
+	return challengeAttempts(tourExecution, keypoint.ID) >= keypoint.Challenge.MaxAttempts
+}
+
+// challengeAttempts counts the attempts at a keypoint's challenge during the execution.
+func challengeAttempts(tourExecution *models.TourExecution, keypointID int) int {
+	attempts := 0
+	for _, attempt := range tourExecution.ChallengeAttempts {
+		if attempt.KeypointID == keypointID {
+			attempts++
+		}
+	}
+	return attempts
+}
+
+// PendingChallenge returns the execution and the keypoint whose challenge the tourist may
+// answer now: the keypoint must be uncompleted, have a challenge and have been reached.
+func (tes *TourExecutionService) PendingChallenge(tourId, userId, keypointId int) (*models.TourExecution, *models.Keypoint, int, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution == nil {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("no tour execution found")
+	}
+	if tourExecution.Status != models.ExecutionStatusInProgress {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("tour execution not in progress")
+	}
+	if userId != tourExecution.UserID {
+		return nil, nil, http.StatusUnauthorized, fmt.Errorf("user not authorized to change this tour execution")
+	}
+
+	keypoint, err := tes.KeyPointsService.GetKeypointByID(keypointId)
+	if err != nil || keypoint.TourID != tourId {
+		return nil, nil, http.StatusNotFound, fmt.Errorf("key point %d not found in tour %d", keypointId, tourId)
+	}
+	if keypoint.Challenge == nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("key point %s has no challenge", keypoint.Name)
+	}
+	for _, finished := range tourExecution.FinishedKeypoints {
+		if finished.KeypointID == keypoint.ID {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("key point %s is already completed", keypoint.Name)
+		}
+	}
+	if challengeSolved(tourExecution, *keypoint) {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("the challenge of key point %s is already solved", keypoint.Name)
+	}
+	if challengeFailed(tourExecution, *keypoint) {
+		return nil, nil, http.StatusForbidden, fmt.Errorf("no attempts left for the challenge of key point %s", keypoint.Name)
+	}
+
+	// Position checks keep reached keypoints with an open challenge in the dwell counters
+	// for as long as the tourist stays in range
+	reached := false
+	for _, counter := range tourExecution.Dwell {
+		if counter.KeypointID == keypoint.ID && counter.Count >= tes.DwellFixes {
+			reached = true
+		}
+	}
+	if !reached {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("reach key point %s before answering its challenge", keypoint.Name)
+	}
+	return tourExecution, keypoint, http.StatusOK, nil
+}
+
+// SubmitChallengeAnswer records an attempt at the challenge of a reached keypoint and
+// completes the keypoint once it is solved. Quizzes and codes are checked against the
+// accepted answers, photo challenges are solved by any uploaded photo. Running out of
+// attempts skips the keypoint unsolved, so a tour is never stuck on a challenge.
+func (tes *TourExecutionService) SubmitChallengeAnswer(tourId, userId, keypointId int, answer, photoURL string) (*models.ChallengeResult, int, error) {
+	tourExecution, keypoint, httpStatus, err := tes.PendingChallenge(tourId, userId, keypointId)
+	if err != nil {
+		return nil, httpStatus, err
+	}
+	challenge := keypoint.Challenge
+
+	attempt := models.ChallengeAttempt{KeypointID: keypoint.ID, AttemptedAt: time.Now()}
+	switch challenge.Type {
+	case models.ChallengePhoto:
+		if photoURL == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("the challenge of key point %s needs a photo", keypoint.Name)
+		}
+		attempt.PhotoURL = photoURL
+		attempt.Correct = true
+	default:
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("the challenge of key point %s needs an answer", keypoint.Name)
+		}
+		if len(challenge.Options) > 0 && !containsAnswer(challenge.Options, answer) {
+			return nil, http.StatusBadRequest, fmt.Errorf("answer must be one of the options")
+		}
+		attempt.Answer = answer
+		attempt.Correct = containsAnswer(challenge.Answers, answer)
+	}
+
+	recorded, err := tes.TourExecutionRepository.AddChallengeAttempt(tourExecution.ID, attempt, challenge.MaxAttempts)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("unable to record challenge attempt")
+	}
+	if !recorded {
+		// Another request got in first, tell why the challenge no longer takes answers
+		if _, _, httpStatus, err := tes.PendingChallenge(tourId, userId, keypointId); err != nil {
+			return nil, httpStatus, err
+		}
+		return nil, http.StatusConflict, fmt.Errorf("the challenge of key point %s was answered by another request, try again", keypoint.Name)
+	}
+	tourExecution.ChallengeAttempts = append(tourExecution.ChallengeAttempts, attempt)
+
+	result := &models.ChallengeResult{KeypointID: keypoint.ID, Correct: attempt.Correct}
+	if challenge.MaxAttempts > 0 {
+		left := max(challenge.MaxAttempts-challengeAttempts(tourExecution, keypoint.ID), 0)
+		result.AttemptsLeft = &left
+	}
+	result.ChallengeFailed = challengeFailed(tourExecution, *keypoint)
+	if !attempt.Correct && !result.ChallengeFailed {
+		return result, http.StatusOK, nil
+	}
+
+	keypoints, err := tes.KeyPointsService.GetKeypointsByTourID(tourId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	_, finished, err := tes.completeKeypoints(tourExecution, []models.Keypoint{*keypoint}, keypoints)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	result.KeypointCompleted = attempt.Correct
+	result.TourFinished = finished
+	return result, http.StatusOK, nil
+}
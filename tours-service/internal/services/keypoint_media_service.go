@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+// ErrInvalidMedia wraps every rejected media upload.
+var ErrInvalidMedia = errors.New("invalid media")
+
+// sniffedMimeTypes maps what http.DetectContentType reports for audio containers to the
+// audio mime type, since it can't tell an audio-only mp4 or ogg from a video one.
+var sniffedMimeTypes = map[models.MediaType]map[string]string{
+	models.MediaAudio: {
+		"video/mp4":       "audio/mp4",
+		"application/ogg": "audio/ogg",
+	},
+}
+
+type KeypointMediaService struct {
+	KeypointRepo  *repositories.KeypointRepository
+	ExecutionRepo *repositories.TourExecutionRepository
+}
+
+func NewKeypointMediaService(keypointRepo *repositories.KeypointRepository, executionRepo *repositories.TourExecutionRepository) *KeypointMediaService {
+	return &KeypointMediaService{
+		KeypointRepo:  keypointRepo,
+		ExecutionRepo: executionRepo,
+	}
+}
+
+// MediaLimit returns the limits of a media type, or false for an unknown type.
+func MediaLimit(mediaType models.MediaType) (models.MediaLimit, bool) {
+	limit, ok := models.MediaLimits[mediaType]
+	return limit, ok
+}
+
+// DetectMimeType sniffs the mime type of an upload from its first bytes, the type the
+// client declares is not trusted.
+func DetectMimeType(mediaType models.MediaType, head []byte) (string, error) {
+	limit, ok := MediaLimit(mediaType)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown media type %q, use image, audio or video", ErrInvalidMedia, mediaType)
+	}
+
+	detected := http.DetectContentType(head)
+	if mapped, ok := sniffedMimeTypes[mediaType][detected]; ok {
+		detected = mapped
+	}
+	// Raw mp3 and aac streams without an ID3 tag start with an MPEG frame sync, and m4a
+	// files carry their own mp4 brand. http.DetectContentType knows neither.
+	switch {
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		if head[1]&0xF6 == 0xF0 {
+			detected = "audio/aac"
+		} else {
+			detected = "audio/mpeg"
+		}
+	case mediaType == models.MediaAudio && len(head) >= 12 && string(head[4:8]) == "ftyp" && string(head[8:11]) == "M4A":
+		detected = "audio/mp4"
+	}
+	for _, allowed := range limit.MimeTypes {
+		if detected == allowed {
+			return detected, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s files must be one of %v", ErrInvalidMedia, mediaType, limit.MimeTypes)
+}
+
+// ValidateMedia checks the size and duration of an upload against the limits of its type.
+func ValidateMedia(mediaType models.MediaType, size int64, duration float64) error {
+	limit, ok := MediaLimit(mediaType)
+	if !ok {
+		return fmt.Errorf("%w: unknown media type %q, use image, audio or video", ErrInvalidMedia, mediaType)
+	}
+	if size <= 0 {
+		return fmt.Errorf("%w: file is empty", ErrInvalidMedia)
+	}
+	if size > limit.MaxSize {
+		return fmt.Errorf("%w: %s files can be at most %d MB", ErrInvalidMedia, mediaType, limit.MaxSize>>20)
+	}
+	if limit.MaxDuration > 0 {
+		if duration <= 0 {
+			return fmt.Errorf("%w: duration in seconds is required for %s", ErrInvalidMedia, mediaType)
+		}
+		if duration > limit.MaxDuration {
+			return fmt.Errorf("%w: %s can be at most %.0f seconds long", ErrInvalidMedia, mediaType, limit.MaxDuration)
+		}
+	} else if duration != 0 {
+		return fmt.Errorf("%w: %s has no duration", ErrInvalidMedia, mediaType)
+	}
+	return nil
+}
+
+// AddMedia appends an uploaded item to the end of the keypoint's gallery.
+func (s *KeypointService) AddMedia(keypoint *models.Keypoint, media *models.KeypointMedia, authorID int) error {
+	limit, ok := MediaLimit(media.Type)
+	if !ok {
+		return fmt.Errorf("%w: unknown media type %q, use image, audio or video", ErrInvalidMedia, media.Type)
+	}
+	media.UploadedAt = time.Now()
+
+	added, err := s.KeypointRepo.AddMedia(keypoint.ID, media, limit.MaxCount)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return fmt.Errorf("%w: a keypoint can hold at most %d %s items", ErrInvalidMedia, limit.MaxCount, media.Type)
+	}
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+	return nil
+}
+
+func (s *KeypointService) DeleteMedia(keypoint *models.Keypoint, mediaID, authorID int) error {
+	if err := s.KeypointRepo.DeleteMedia(keypoint.ID, mediaID); err != nil {
+		return err
+	}
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+	return nil
+}
+
+// ReorderMedia puts the gallery in the order of mediaIDs, which must list every item once.
+func (s *KeypointService) ReorderMedia(keypoint *models.Keypoint, mediaIDs []int, authorID int) (*models.Keypoint, error) {
+	if len(mediaIDs) != len(keypoint.Media) {
+		return nil, fmt.Errorf("%w: the order must list all %d media items of the keypoint", ErrInvalidMedia, len(keypoint.Media))
+	}
+
+	byID := make(map[int]models.KeypointMedia, len(keypoint.Media))
+	for _, media := range keypoint.Media {
+		byID[media.ID] = media
+	}
+	ordered := make([]models.KeypointMedia, 0, len(mediaIDs))
+	for _, id := range mediaIDs {
+		media, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: media %d is not part of this keypoint or is listed twice", ErrInvalidMedia, id)
+		}
+		delete(byID, id)
+		ordered = append(ordered, media)
+	}
+
+	updated, err := s.KeypointRepo.SetMediaOrder(keypoint.ID, keypoint.Version, ordered)
+	if err != nil {
+		return nil, err
+	}
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+	return updated, nil
+}
+
+// UnlockedKeypoints returns the keypoints of a tour the tourist reached in any of their
+// executions of it. Their media stays unlocked after the execution ends.
+func (s *KeypointMediaService) UnlockedKeypoints(tourID, touristID int) (map[int]bool, error) {
+	executions, err := s.ExecutionRepo.FindHistoryByUserAndTourId(touristID, tourID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tour executions: %w", err)
+	}
+
+	unlocked := map[int]bool{}
+	for _, execution := range executions {
+		for _, finished := range execution.FinishedKeypoints {
+			unlocked[finished.KeypointID] = true
+		}
+	}
+	return unlocked, nil
+}
+
+// GateMedia drops the media of every keypoint that is not unlocked. A nil map drops all.
+func GateMedia(keypoints []models.Keypoint, unlocked map[int]bool) {
+	for i := range keypoints {
+		if !unlocked[keypoints[i].ID] {
+			keypoints[i].Media = nil
+		}
+	}
+}
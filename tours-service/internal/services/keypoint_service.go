@@ -1,20 +1,34 @@
 package services
 
 import (
+	"context"
+	"fmt"
+
 	"tours-service/internal/models"
 	"tours-service/internal/repositories"
 )
 
 type KeypointService struct {
-	KeypointRepo *repositories.KeypointRepository
+	KeypointRepo    *repositories.KeypointRepository
+	TourService     *TourService
+	JobService      *JobService
+	RevisionService *TourRevisionService
 }
 
-func NewKeypointService(keypointRepo *repositories.KeypointRepository) *KeypointService {
-	return &KeypointService{KeypointRepo: keypointRepo}
+func NewKeypointService(keypointRepo *repositories.KeypointRepository, tourService *TourService, jobService *JobService, revisionService *TourRevisionService) *KeypointService {
+	return &KeypointService{KeypointRepo: keypointRepo, TourService: tourService, JobService: jobService, RevisionService: revisionService}
 }
 
-func (s *KeypointService) CreateKeypoint(keypoint *models.Keypoint) error {
-	return s.KeypointRepo.CreateKeypoint(keypoint)
+// CreateKeypoint inserts the keypoint at its Ordinal (1-based) and shifts the rest.
+// An ordinal outside 1..n+1 appends the keypoint at the end of the tour.
+func (s *KeypointService) CreateKeypoint(ctx context.Context, keypoint *models.Keypoint, authorID int) error {
+	if err := s.KeypointRepo.InsertKeypoint(keypoint); err != nil {
+		return err
+	}
+
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointCreated)
+	s.recalculate(ctx, keypoint.TourID)
+	return nil
 }
 
 func (s *KeypointService) GetKeypointsByTourID(tourID int) ([]models.Keypoint, error) {
@@ -25,12 +39,68 @@ func (s *KeypointService) GetKeypointByID(keypointID int) (*models.Keypoint, err
 	return s.KeypointRepo.GetKeypointByID(keypointID)
 }
 
-func (s *KeypointService) UpdateKeypoint(keypoint *models.Keypoint) error {
-	return s.KeypointRepo.UpdateKeypoint(keypoint)
+// UpdateKeypoint applies a partial update. A new ordinal moves the keypoint to that
+// position instead of being written as is, so ordinals never collide or leave gaps.
+func (s *KeypointService) UpdateKeypoint(ctx context.Context, keypointID, expectedVersion, authorID int, update *models.KeypointUpdateRequest) (*models.Keypoint, error) {
+	keypoint, err := s.KeypointRepo.UpdateKeypoint(keypointID, expectedVersion, update)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+
+	if update.Ordinal != nil || update.Latitude != nil || update.Longitude != nil {
+		s.recalculate(ctx, keypoint.TourID)
+	}
+	return keypoint, nil
 }
 
-func (s *KeypointService) DeleteKeypoint(keypointID int) error {
-	return s.KeypointRepo.DeleteKeypoint(keypointID)
+func (s *KeypointService) SetKeypointImage(keypoint *models.Keypoint, imageURL string, authorID int) error {
+	if err := s.KeypointRepo.SetKeypointImage(keypoint.ID, imageURL); err != nil {
+		return err
+	}
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+	return nil
+}
+
+// SetChallenge validates and replaces the challenge of a keypoint, nil removes it.
+func (s *KeypointService) SetChallenge(keypointID, expectedVersion, authorID int, challenge *models.KeypointChallenge) (*models.Keypoint, error) {
+	if challenge != nil {
+		if err := ValidateChallenge(challenge); err != nil {
+			return nil, err
+		}
+	}
+	keypoint, err := s.KeypointRepo.SetChallenge(keypointID, expectedVersion, challenge)
+	if err != nil {
+		return nil, err
+	}
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointUpdated)
+	return keypoint, nil
+}
+
+// DeleteKeypoint removes the keypoint and closes the gap it leaves in the ordinals.
+func (s *KeypointService) DeleteKeypoint(ctx context.Context, keypointID, authorID int) error {
+	keypoint, err := s.KeypointRepo.DeleteKeypoint(keypointID)
+	if err != nil {
+		return err
+	}
+
+	s.RevisionService.RecordOrWarn(keypoint.TourID, authorID, models.RevisionKeypointDeleted)
+	s.recalculate(ctx, keypoint.TourID)
+	return nil
+}
+
+// ReorderKeypoints sets the order of a tour's keypoints. orderedIDs must list every
+// keypoint of the tour exactly once, which also catches concurrent inserts and deletes.
+func (s *KeypointService) ReorderKeypoints(ctx context.Context, tourID, authorID int, orderedIDs []int) ([]models.Keypoint, error) {
+	if err := s.KeypointRepo.ReorderKeypoints(tourID, orderedIDs); err != nil {
+		return nil, err
+	}
+
+	s.RevisionService.RecordOrWarn(tourID, authorID, models.RevisionKeypointUpdated)
+
+	s.recalculate(ctx, tourID)
+	return s.KeypointRepo.GetKeypointsByTourID(tourID)
 }
 
 func (s *KeypointService) DeleteKeypointsByTourID(tourID int) error {
@@ -40,3 +110,17 @@ func (s *KeypointService) DeleteKeypointsByTourID(tourID int) error {
 func (s *KeypointService) GetNextUncompletedKeyPointByTourId(tourID int, completedPoints []int) (*models.Keypoint, error) {
 	return s.KeypointRepo.GetUncompletedKeyPointsByTourId(tourID, completedPoints)
 }
+
+// recalculate refreshes the tour stats in the background. If the job can't be queued
+// the stats are refreshed right away; the keypoint change is already stored either way.
+func (s *KeypointService) recalculate(ctx context.Context, tourID int) {
+	err := s.JobService.EnqueueTourRecalculation(tourID)
+	if err == nil {
+		return
+	}
+	fmt.Printf("Warning: Failed to queue recalculation of tour %d: %v\n", tourID, err)
+
+	if err := s.TourService.RecalculateTourLength(ctx, tourID); err != nil {
+		fmt.Printf("Warning: Failed to recalculate length of tour %d: %v\n", tourID, err)
+	}
+}
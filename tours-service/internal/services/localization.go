@@ -0,0 +1,161 @@
+package services
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+)
+
+// languageTagPattern accepts BCP 47 style tags such as "en", "sr-latn" or "pt-br", already lowercased.
+var languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// NormalizeLanguage lowercases a language tag and reports whether it is well formed.
+// Tags are used as keys in the translations maps, so only letters, digits and dashes pass.
+func NormalizeLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	return tag, languageTagPattern.MatchString(tag)
+}
+
+// TourLanguage is the language of the tour's own name and description.
+func TourLanguage(tour *models.Tour) string {
+	if tour.Language == "" {
+		return models.DefaultLanguage
+	}
+	return tour.Language
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header, most preferred
+// first. Entries with q=0, the "*" wildcard and malformed tags are dropped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var entries []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag, ok := NormalizeLanguage(fields[0])
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			quality = q
+		}
+		if quality == 0 {
+			continue
+		}
+		entries = append(entries, weighted{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].quality > entries[j].quality
+	})
+	languages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		languages = append(languages, entry.tag)
+	}
+	return languages
+}
+
+// primarySubtag is the language part of a tag, "sr" for "sr-latn-rs".
+func primarySubtag(tag string) string {
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// NegotiateTourLanguage picks the language a tour is served in. Each preferred language
+// is tried as an exact match, then by its primary subtag ("de-at" is served "de", "de"
+// is served "de-ch"), before falling back to the tour's default language.
+func NegotiateTourLanguage(tour *models.Tour, preferred []string) string {
+	defaultLanguage := TourLanguage(tour)
+	available := func(tag string) bool {
+		if tag == defaultLanguage {
+			return true
+		}
+		_, ok := tour.Translations[tag]
+		return ok
+	}
+
+	for _, tag := range preferred {
+		if available(tag) {
+			return tag
+		}
+	}
+	for _, tag := range preferred {
+		base := primarySubtag(tag)
+		if available(base) {
+			return base
+		}
+		if primarySubtag(defaultLanguage) == base {
+			return defaultLanguage
+		}
+		for _, candidate := range translationLanguages(tour.Translations) {
+			if primarySubtag(candidate) == base {
+				return candidate
+			}
+		}
+	}
+	return defaultLanguage
+}
+
+// translationLanguages lists the languages of a translations map in a stable order.
+func translationLanguages(translations map[string]models.Translation) []string {
+	languages := make([]string, 0, len(translations))
+	for language := range translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// LocalizeTour serves the tour and its keypoints in the negotiated language and returns
+// it. Name and description are replaced in place, texts missing from a translation keep
+// the default ones, and the translation maps are dropped from the result.
+func LocalizeTour(tour *models.Tour, keypoints []models.Keypoint, preferred []string) string {
+	language := NegotiateTourLanguage(tour, preferred)
+	if translation, ok := tour.Translations[language]; ok && language != TourLanguage(tour) {
+		applyTranslation(&tour.Name, &tour.Description, translation)
+	}
+	tour.Language = language
+	tour.Translations = nil
+
+	for i := range keypoints {
+		LocalizeKeypoint(&keypoints[i], language)
+	}
+	for i := range tour.Keypoints {
+		LocalizeKeypoint(&tour.Keypoints[i], language)
+	}
+	return language
+}
+
+// LocalizeKeypoint serves a keypoint in language, when it has a translation for it.
+func LocalizeKeypoint(keypoint *models.Keypoint, language string) {
+	if translation, ok := keypoint.Translations[language]; ok {
+		applyTranslation(&keypoint.Name, &keypoint.Description, translation)
+	}
+	keypoint.Translations = nil
+}
+
+func applyTranslation(name, description *string, translation models.Translation) {
+	if translation.Name != "" {
+		*name = translation.Name
+	}
+	if translation.Description != "" {
+		*description = translation.Description
+	}
+}
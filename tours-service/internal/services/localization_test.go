@@ -0,0 +1,75 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{name: "empty", header: "", want: []string{}},
+		{name: "single tag", header: "sr", want: []string{"sr"}},
+		{name: "normalizes case and underscores", header: "sr_Latn-RS", want: []string{"sr-latn-rs"}},
+		{name: "sorted by quality", header: "de;q=0.5, en-US, fr;q=0.8", want: []string{"en-us", "fr", "de"}},
+		{name: "equal quality keeps header order", header: "it;q=0.7, es;q=0.7, pt", want: []string{"pt", "it", "es"}},
+		{name: "spaces around parameters", header: " en ; q=0.9 , de ", want: []string{"de", "en"}},
+		{name: "q=0 is dropped", header: "en, de;q=0", want: []string{"en"}},
+		{name: "wildcard is dropped", header: "fr, *;q=0.5", want: []string{"fr"}},
+		{name: "malformed tags are dropped", header: "e, english, 12, x$y, hu", want: []string{"hu"}},
+		{name: "invalid quality is dropped", header: "en;q=abc, de;q=1.5, fr;q=-1, it", want: []string{"it"}},
+		{name: "other parameters are ignored", header: "en;level=1;q=0.4, de", want: []string{"de", "en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateTourLanguage(t *testing.T) {
+	translated := &models.Tour{
+		Language: "sr",
+		Translations: map[string]models.Translation{
+			"en":    {Name: "Fortress walk"},
+			"de-ch": {Name: "Festungsspaziergang"},
+			"de-at": {Name: "Festungsrundgang"},
+			"fr":    {Name: "Promenade"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		tour      *models.Tour
+		preferred []string
+		want      string
+	}{
+		{name: "no preference serves the default", tour: translated, preferred: nil, want: "sr"},
+		{name: "unset language defaults to english", tour: &models.Tour{}, preferred: []string{"fr"}, want: models.DefaultLanguage},
+		{name: "default language", tour: translated, preferred: []string{"sr"}, want: "sr"},
+		{name: "exact translation", tour: translated, preferred: []string{"fr"}, want: "fr"},
+		{name: "first available preference wins", tour: translated, preferred: []string{"ja", "en", "fr"}, want: "en"},
+		{name: "exact match beats an earlier primary subtag match", tour: translated, preferred: []string{"en-gb", "fr"}, want: "fr"},
+		{name: "region falls back to the base language", tour: translated, preferred: []string{"en-gb"}, want: "en"},
+		{name: "region falls back to the default language", tour: translated, preferred: []string{"sr-latn"}, want: "sr"},
+		{name: "base language picks the first regional translation", tour: translated, preferred: []string{"de"}, want: "de-at"},
+		{name: "other region of the same language", tour: translated, preferred: []string{"de-de"}, want: "de-at"},
+		{name: "nothing matches", tour: translated, preferred: []string{"ja", "ko"}, want: "sr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NegotiateTourLanguage(tt.tour, tt.preferred); got != tt.want {
+				t.Errorf("NegotiateTourLanguage(%q) = %q, want %q", tt.preferred, got, tt.want)
+			}
+		})
+	}
+}
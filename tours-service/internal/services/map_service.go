@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,26 +10,136 @@ import (
 	"tours-service/internal/models"
 )
 
+// maxBatchWaypoints is the most coordinates map-service takes in one batch request.
+const maxBatchWaypoints = 50
+
+var routeProfiles = []string{models.ProfileDriving, models.ProfileWalking, models.ProfileCycling}
+
 type MapService struct {
 	Client  *http.Client
 	BaseURL string
+	Cache   *DistanceCache
 }
 
-func NewMapService(baseURL string) *MapService {
+func NewMapService(baseURL string, cache *DistanceCache) *MapService {
 	return &MapService{
 		Client:  &http.Client{Timeout: 10 * time.Second},
 		BaseURL: baseURL,
+		Cache:   cache,
 	}
 }
 
-func (s *MapService) GetDistanceBetweenTwoKeypoints(ctx context.Context, origin, dest models.Keypoint) (map[string]models.DistanceAndDuration, error) {
-	url := fmt.Sprintf("%s/api/getdistances?originLat=%f&originLng=%f&destLat=%f&destLng=%f",
-		s.BaseURL, origin.Latitude, origin.Longitude, dest.Latitude, dest.Longitude)
+// GetRouteLegs returns distance, duration and route geometry per transport profile for
+// every pair of consecutive keypoints. Cached legs are served from the cache, the rest
+// are fetched in as few batch requests as possible: one per run of consecutive misses.
+// When map-service fails the legs resolved so far are returned with the error, the
+// unresolved ones are nil.
+func (s *MapService) GetRouteLegs(ctx context.Context, keypoints []models.Keypoint) ([]map[string]models.RouteLeg, error) {
+	if len(keypoints) < 2 {
+		return nil, nil
+	}
+
+	legs := make([]map[string]models.RouteLeg, len(keypoints)-1)
+
+	keys := make([]string, 0, len(legs)*len(routeProfiles))
+	for i := range legs {
+		for _, profile := range routeProfiles {
+			keys = append(keys, distanceCacheKey(profile, keypoints[i], keypoints[i+1]))
+		}
+	}
+	cached := map[string]models.DistanceCacheEntry{}
+	if s.Cache != nil {
+		cached = s.Cache.Get(keys)
+	}
+
+	var missing []int
+	for i := range legs {
+		leg := make(map[string]models.RouteLeg, len(routeProfiles))
+		for _, profile := range routeProfiles {
+			entry, ok := cached[distanceCacheKey(profile, keypoints[i], keypoints[i+1])]
+			if !ok {
+				leg = nil
+				break
+			}
+			leg[profile] = models.RouteLeg{Distance: entry.Distance, Duration: entry.Duration, Geometry: entry.Geometry}
+		}
+		if leg == nil {
+			missing = append(missing, i)
+		} else {
+			legs[i] = leg
+		}
+	}
+
+	var fresh []models.DistanceCacheEntry
+	for start := 0; start < len(missing); {
+		end := start
+		for end+1 < len(missing) && missing[end+1] == missing[end]+1 && end-start+1 < maxBatchWaypoints-1 {
+			end++
+		}
+		first, last := missing[start], missing[end]
+
+		batch, err := s.fetchBatch(ctx, keypoints[first:last+2])
+		if err != nil {
+			s.cacheLegs(fresh)
+			return legs, err
+		}
+		for i := first; i <= last; i++ {
+			legs[i] = batch[i-first]
+			for profile, leg := range batch[i-first] {
+				fresh = append(fresh, models.DistanceCacheEntry{
+					Key:      distanceCacheKey(profile, keypoints[i], keypoints[i+1]),
+					Profile:  profile,
+					Distance: leg.Distance,
+					Duration: leg.Duration,
+					Geometry: leg.Geometry,
+				})
+			}
+		}
+		start = end + 1
+	}
+
+	s.cacheLegs(fresh)
+	return legs, nil
+}
+
+func (s *MapService) cacheLegs(entries []models.DistanceCacheEntry) {
+	if s.Cache != nil && len(entries) > 0 {
+		s.Cache.Put(entries)
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// Healthy reports whether map-service answers at all.
+func (s *MapService) Healthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/", nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// fetchBatch resolves a path of keypoints with a single map-service request and returns
+// one leg per consecutive pair.
+func (s *MapService) fetchBatch(ctx context.Context, keypoints []models.Keypoint) ([]map[string]models.RouteLeg, error) {
+	coordinates := make([][]float64, 0, len(keypoints))
+	for _, keypoint := range keypoints {
+		coordinates = append(coordinates, []float64{keypoint.Longitude, keypoint.Latitude})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"coordinates": coordinates})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.BaseURL+"/api/getdistances/batch", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := s.Client.Do(req)
 	if err != nil {
@@ -42,10 +153,24 @@ func (s *MapService) GetDistanceBetweenTwoKeypoints(ctx context.Context, origin,
 		return nil, fmt.Errorf("map-service returned non-OK status %d: %s", resp.StatusCode, errResponse["error"])
 	}
 
-	var results map[string]models.DistanceAndDuration
+	var results map[string][]models.RouteLeg
 	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
 		return nil, fmt.Errorf("failed to decode response from map-service: %w", err)
 	}
 
-	return results, nil
-}
\ No newline at end of file
+	legs := make([]map[string]models.RouteLeg, len(keypoints)-1)
+	for i := range legs {
+		legs[i] = make(map[string]models.RouteLeg, len(routeProfiles))
+	}
+	for _, profile := range routeProfiles {
+		profileLegs := results[profile]
+		if len(profileLegs) != len(legs) {
+			return nil, fmt.Errorf("map-service returned %d legs for %s, expected %d", len(profileLegs), profile, len(legs))
+		}
+		for i, leg := range profileLegs {
+			legs[i][profile] = leg
+		}
+	}
+
+	return legs, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+// fakeMapService answers batch requests with one leg per consecutive pair, the distance
+// of a leg being the longitude of its origin. It records how many coordinates every
+// request carried.
+type fakeMapService struct {
+	mu      sync.Mutex
+	batches []int
+	status  int
+}
+
+func (f *fakeMapService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Coordinates [][]float64 `json:"coordinates"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.batches = append(f.batches, len(request.Coordinates))
+	f.mu.Unlock()
+
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		json.NewEncoder(w).Encode(map[string]string{"error": "routing engine unavailable"})
+		return
+	}
+
+	results := make(map[string][]models.RouteLeg, len(routeProfiles))
+	for _, profile := range routeProfiles {
+		for _, coordinate := range request.Coordinates[:len(request.Coordinates)-1] {
+			results[profile] = append(results[profile], models.RouteLeg{Distance: coordinate[0], Duration: 1, Geometry: "??"})
+		}
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+func TestGetRouteLegs(t *testing.T) {
+	keypoints := func(n int) []models.Keypoint {
+		keypoints := make([]models.Keypoint, n)
+		for i := range keypoints {
+			keypoints[i] = models.Keypoint{ID: i + 1, Latitude: 45, Longitude: float64(i)}
+		}
+		return keypoints
+	}
+	leg := func(distance float64) map[string]models.RouteLeg {
+		leg := make(map[string]models.RouteLeg, len(routeProfiles))
+		for _, profile := range routeProfiles {
+			leg[profile] = models.RouteLeg{Distance: distance, Duration: 1, Geometry: "??"}
+		}
+		return leg
+	}
+	legs := func(n int) []map[string]models.RouteLeg {
+		legs := make([]map[string]models.RouteLeg, n)
+		for i := range legs {
+			legs[i] = leg(float64(i))
+		}
+		return legs
+	}
+
+	tests := []struct {
+		name        string
+		keypoints   []models.Keypoint
+		cachedLegs  []int // indexes of legs already in the cache
+		status      int
+		wantBatches []int
+		wantLegs    []map[string]models.RouteLeg
+		wantErr     bool
+	}{
+		{
+			name:      "a single keypoint has no legs",
+			keypoints: keypoints(1),
+		},
+		{
+			name:        "uncached legs in one batch",
+			keypoints:   keypoints(4),
+			wantBatches: []int{4},
+			wantLegs:    legs(3),
+		},
+		{
+			name:       "every leg cached",
+			keypoints:  keypoints(4),
+			cachedLegs: []int{0, 1, 2},
+			wantLegs:   legs(3),
+		},
+		{
+			name:        "a cached leg splits the batch",
+			keypoints:   keypoints(5),
+			cachedLegs:  []int{1},
+			wantBatches: []int{2, 3},
+			wantLegs:    legs(4),
+		},
+		{
+			name:        "long routes are split by the batch limit",
+			keypoints:   keypoints(maxBatchWaypoints + 2),
+			wantBatches: []int{maxBatchWaypoints, 3},
+			wantLegs:    legs(maxBatchWaypoints + 1),
+		},
+		{
+			name:        "map-service failure leaves the legs unresolved",
+			keypoints:   keypoints(4),
+			cachedLegs:  []int{2},
+			status:      http.StatusServiceUnavailable,
+			wantBatches: []int{3},
+			wantLegs:    []map[string]models.RouteLeg{nil, nil, leg(2)},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMapService{status: tt.status}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			cache := NewDistanceCache(100, nil)
+			var entries []models.DistanceCacheEntry
+			for _, i := range tt.cachedLegs {
+				for _, profile := range routeProfiles {
+					entries = append(entries, models.DistanceCacheEntry{
+						Key:      distanceCacheKey(profile, tt.keypoints[i], tt.keypoints[i+1]),
+						Profile:  profile,
+						Distance: float64(i),
+						Duration: 1,
+						Geometry: "??",
+					})
+				}
+			}
+			cache.Put(entries)
+
+			got, err := NewMapService(server.URL, cache).GetRouteLegs(context.Background(), tt.keypoints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRouteLegs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.wantLegs) {
+				t.Errorf("GetRouteLegs() = %v, want %v", got, tt.wantLegs)
+			}
+			if !reflect.DeepEqual(fake.batches, tt.wantBatches) {
+				t.Errorf("batch sizes = %v, want %v", fake.batches, tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestGetRouteLegsCachesFetchedLegs(t *testing.T) {
+	fake := &fakeMapService{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	keypoints := []models.Keypoint{{ID: 1, Latitude: 45, Longitude: 19}, {ID: 2, Latitude: 45.1, Longitude: 19.1}}
+	service := NewMapService(server.URL, NewDistanceCache(100, nil))
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.GetRouteLegs(context.Background(), keypoints); err != nil {
+			t.Fatalf("GetRouteLegs() error = %v", err)
+		}
+	}
+	if len(fake.batches) != 1 {
+		t.Errorf("map-service was called %d times, want 1", len(fake.batches))
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const polylinePrecision = 1e5
+
+// decodePolyline decodes a Google encoded polyline (precision 5) into [lon, lat] pairs,
+// the coordinate order used by GeoJSON.
+func decodePolyline(encoded string) ([][]float64, error) {
+	var coordinates [][]float64
+	lat, lon := 0, 0
+	for i := 0; i < len(encoded); {
+		var deltas [2]int
+		for j := range deltas {
+			result, shift := 0, 0
+			for {
+				if i >= len(encoded) {
+					return nil, errors.New("malformed polyline")
+				}
+				b := int(encoded[i]) - 63
+				i++
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[j] = ^(result >> 1)
+			} else {
+				deltas[j] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lon += deltas[1]
+		coordinates = append(coordinates, []float64{float64(lon) / polylinePrecision, float64(lat) / polylinePrecision})
+	}
+	return coordinates, nil
+}
+
+// encodePolyline is the inverse of decodePolyline.
+func encodePolyline(coordinates [][]float64) string {
+	var sb strings.Builder
+	prevLat, prevLon := 0, 0
+	for _, coordinate := range coordinates {
+		lat := int(math.Round(coordinate[1] * polylinePrecision))
+		lon := int(math.Round(coordinate[0] * polylinePrecision))
+		encodePolylineValue(&sb, lat-prevLat)
+		encodePolylineValue(&sb, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return sb.String()
+}
+
+func encodePolylineValue(sb *strings.Builder, value int) {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		sb.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	sb.WriteByte(byte(v + 63))
+}
+
+// haversineDistance returns the distance in meters between two points.
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const EarthRadius = 6371000
+
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return EarthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// distanceToLine returns the distance in meters from a point to the closest part of a
+// [lon, lat] line. Each segment is projected onto a local flat plane, which is accurate
+// enough at the scale of a walking route.
+func distanceToLine(lat, lon float64, line [][]float64) float64 {
+	if len(line) == 0 {
+		return math.Inf(1)
+	}
+	if len(line) == 1 {
+		return haversineDistance(lat, lon, line[0][1], line[0][0])
+	}
+
+	const metersPerDegree = 111320.0
+	cosLat := math.Cos(lat * math.Pi / 180)
+	toXY := func(c []float64) (float64, float64) {
+		return (c[0] - lon) * metersPerDegree * cosLat, (c[1] - lat) * metersPerDegree
+	}
+
+	best := math.Inf(1)
+	for i := 0; i < len(line)-1; i++ {
+		ax, ay := toXY(line[i])
+		bx, by := toXY(line[i+1])
+		dx, dy := bx-ax, by-ay
+		t := 0.0
+		if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+			t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+		}
+		px, py := ax+t*dx, ay+t*dy
+		if d := math.Hypot(px, py); d < best {
+			best = d
+		}
+	}
+	return best
+}
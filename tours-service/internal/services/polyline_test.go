@@ -0,0 +1,132 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodePolyline(t *testing.T) {
+	tests := []struct {
+		name        string
+		coordinates [][]float64
+		want        string
+	}{
+		{
+			name:        "empty",
+			coordinates: nil,
+			want:        "",
+		},
+		{
+			name:        "single point",
+			coordinates: [][]float64{{-120.2, 38.5}},
+			want:        "_p~iF~ps|U",
+		},
+		{
+			name:        "reference example",
+			coordinates: [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+			want:        "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+		},
+		{
+			name:        "repeated point encodes a zero delta",
+			coordinates: [][]float64{{-120.2, 38.5}, {-120.2, 38.5}},
+			want:        "_p~iF~ps|U??",
+		},
+		{
+			name:        "rounds beyond five decimals",
+			coordinates: [][]float64{{-120.200004, 38.500004}},
+			want:        "_p~iF~ps|U",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodePolyline(tt.coordinates); got != tt.want {
+				t.Errorf("encodePolyline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    [][]float64
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			encoded: "",
+			want:    nil,
+		},
+		{
+			name:    "reference example",
+			encoded: "_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			want:    [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name:    "missing longitude",
+			encoded: "_p~iF",
+			wantErr: true,
+		},
+		{
+			name:    "truncated value",
+			encoded: "_p~iF~ps|",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePolyline(tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodePolyline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			assertCoordinates(t, got, tt.want)
+		})
+	}
+}
+
+func TestPolylineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		coordinates [][]float64
+	}{
+		{
+			name:        "city route",
+			coordinates: [][]float64{{19.84472, 45.25167}, {19.84611, 45.25502}, {19.85021, 45.25733}},
+		},
+		{
+			name:        "crosses the antimeridian and equator",
+			coordinates: [][]float64{{179.99999, 0.00001}, {-179.99999, -0.00001}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodePolyline(encodePolyline(tt.coordinates))
+			if err != nil {
+				t.Fatalf("decodePolyline() error = %v", err)
+			}
+			assertCoordinates(t, got, tt.coordinates)
+		})
+	}
+}
+
+func assertCoordinates(t *testing.T, got, want [][]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d coordinates, want %d", len(got), len(want))
+	}
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(got[i][j]-want[i][j]) > 1/polylinePrecision {
+				t.Errorf("coordinate %d = %v, want %v", i, got[i], want[i])
+				break
+			}
+		}
+	}
+}
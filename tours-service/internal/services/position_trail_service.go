@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+type PositionTrailService struct {
+	TrailRepository         *repositories.PositionTrailRepository
+	TourExecutionRepository *repositories.TourExecutionRepository
+}
+
+func NewPositionTrailService(trailRepository *repositories.PositionTrailRepository, tourExRepository *repositories.TourExecutionRepository) *PositionTrailService {
+	return &PositionTrailService{
+		TrailRepository:         trailRepository,
+		TourExecutionRepository: tourExRepository,
+	}
+}
+
+// RecordFix appends a position fix to the trail of an in-progress execution. Fixes the
+// trail already holds, and fixes too inaccurate to complete a keypoint, are skipped.
+func (s *PositionTrailService) RecordFix(execution *models.TourExecution, fix *models.PositionFix) error {
+	if fix.Accuracy != nil && *fix.Accuracy > maxFixAccuracy {
+		return nil
+	}
+	recordedAt := fix.RecordedAt
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+
+	advanced, err := s.TourExecutionRepository.AdvanceTrail(execution.ID, recordedAt)
+	if err != nil {
+		return fmt.Errorf("failed to advance trail: %w", err)
+	}
+	if !advanced {
+		return nil
+	}
+
+	return s.TrailRepository.AppendPoint(&models.TrailPoint{
+		RecordedAt: recordedAt,
+		Meta:       models.TrailMeta{ExecutionID: execution.ID, TourID: execution.TourID, UserID: execution.UserID},
+		Longitude:  fix.Longitude,
+		Latitude:   fix.Latitude,
+		Accuracy:   fix.Accuracy,
+	})
+}
+
+// GetTrail returns the recorded trail of an execution, split into one segment per
+// completed keypoint.
+func (s *PositionTrailService) GetTrail(execution *models.TourExecution) (*models.PositionTrail, error) {
+	points, err := s.TrailRepository.GetTrail(execution.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTrail(execution, points), nil
+}
+
+func buildTrail(execution *models.TourExecution, points []models.TrailPoint) *models.PositionTrail {
+	trail := &models.PositionTrail{
+		ExecutionID: execution.ID,
+		TourID:      execution.TourID,
+		UserID:      execution.UserID,
+		Timestamps:  make([]int64, 0, len(points)),
+		Segments:    []models.TrailSegment{},
+	}
+
+	line := make([][]float64, 0, len(points))
+	for _, point := range points {
+		line = append(line, []float64{point.Longitude, point.Latitude})
+		trail.Timestamps = append(trail.Timestamps, point.RecordedAt.Unix())
+	}
+	trail.Polyline = encodePolyline(line)
+
+	// Segment boundaries are the execution start and the keypoint completions in time order
+	completions := make([]models.FinishedKeyPoint, 0, len(execution.FinishedKeypoints))
+	for _, finished := range execution.FinishedKeypoints {
+		if finished.CompletedAt != nil {
+			completions = append(completions, finished)
+		}
+	}
+	sort.SliceStable(completions, func(i, j int) bool {
+		return completions[i].CompletedAt.Before(*completions[j].CompletedAt)
+	})
+
+	start := time.Time{}
+	if execution.StartedAt != nil {
+		start = *execution.StartedAt
+	} else if len(points) > 0 {
+		start = points[0].RecordedAt
+	}
+	end := start
+	if execution.EndedAt != nil {
+		end = *execution.EndedAt
+	} else if len(points) > 0 && points[len(points)-1].RecordedAt.After(end) {
+		end = points[len(points)-1].RecordedAt
+	}
+	trail.Duration = end.Sub(start).Seconds()
+
+	next, from, segmentStart := 0, 0, start
+	for _, completion := range completions {
+		last := next
+		for last < len(points) && !points[last].RecordedAt.After(*completion.CompletedAt) {
+			last++
+		}
+		trail.Segments = append(trail.Segments, trailSegment(points, next, last, from, completion.KeypointID, segmentStart, *completion.CompletedAt))
+		next, from, segmentStart = last, completion.KeypointID, *completion.CompletedAt
+	}
+	// What was walked after the last completion, unless the execution ended right there
+	if next < len(points) || execution.EndedAt == nil {
+		trail.Segments = append(trail.Segments, trailSegment(points, next, len(points), from, 0, segmentStart, end))
+	}
+
+	for _, segment := range trail.Segments {
+		trail.Distance += segment.Distance
+	}
+	return trail
+}
+
+// trailSegment builds the segment of points[first:last]. The line starts at the last
+// point of the previous segment so consecutive segments join up and their distances add
+// up to the whole trail.
+func trailSegment(points []models.TrailPoint, first, last, fromKeypointID, toKeypointID int, startedAt, endedAt time.Time) models.TrailSegment {
+	if first > 0 {
+		first--
+	}
+	line := make([][]float64, 0, last-first)
+	distance := 0.0
+	for i := first; i < last; i++ {
+		line = append(line, []float64{points[i].Longitude, points[i].Latitude})
+		if i > first {
+			distance += haversineDistance(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+		}
+	}
+
+	duration := 0.0
+	if endedAt.After(startedAt) {
+		duration = endedAt.Sub(startedAt).Seconds()
+	}
+	return models.TrailSegment{
+		FromKeypointID: fromKeypointID,
+		ToKeypointID:   toKeypointID,
+		StartedAt:      startedAt,
+		EndedAt:        endedAt,
+		Distance:       distance,
+		Duration:       duration,
+		Polyline:       encodePolyline(line),
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 )
 
 type PurchaseService struct {
@@ -53,3 +54,91 @@ func (s *PurchaseService) IsTourPurchasedByMe(r *http.Request, tourId int) (bool
 
 	return respData.Purchased, nil
 }
+
+// GetPurchaseTime returns when the calling tourist bought the tour, or nil if they didn't.
+func (s *PurchaseService) GetPurchaseTime(r *http.Request, tourId int) (*time.Time, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errors.New("authorization header is required")
+	}
+
+	historyURL := os.Getenv("PURCHASE_SERVICE_URL") + "/purchases"
+	req, err := http.NewRequest("GET", historyURL, nil)
+	if err != nil {
+		return nil, errors.New("failed to create purchase history request")
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.New("failed to contact purchase service")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorBody bytes.Buffer
+		errorBody.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("bad request: %s", errorBody.String())
+	}
+
+	var respData struct {
+		Purchases []struct {
+			TourID      int       `json:"tour_id"`
+			PurchasedAt time.Time `json:"purchased_at"`
+		} `json:"purchases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return nil, errors.New("failed to decode purchase history response")
+	}
+
+	var purchasedAt *time.Time
+	for _, purchase := range respData.Purchases {
+		if purchase.TourID != tourId {
+			continue
+		}
+		if purchasedAt == nil || purchase.PurchasedAt.Before(*purchasedAt) {
+			t := purchase.PurchasedAt
+			purchasedAt = &t
+		}
+	}
+
+	return purchasedAt, nil
+}
+
+// CountTourPurchases returns how many times the tour was bought, on behalf of its guide.
+func (s *PurchaseService) CountTourPurchases(r *http.Request, tourId int) (int, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return 0, errors.New("authorization header is required")
+	}
+
+	countURL := os.Getenv("PURCHASE_SERVICE_URL") + "/tours/" + strconv.Itoa(tourId) + "/purchase-count"
+	req, err := http.NewRequest("GET", countURL, nil)
+	if err != nil {
+		return 0, errors.New("failed to create purchase count request")
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, errors.New("failed to contact purchase service")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errorBody bytes.Buffer
+		errorBody.ReadFrom(resp.Body)
+		return 0, fmt.Errorf("bad request: %s", errorBody.String())
+	}
+
+	var respData struct {
+		Purchases int `json:"purchases"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respData); err != nil {
+		return 0, errors.New("failed to decode purchase count response")
+	}
+
+	return respData.Purchases, nil
+}
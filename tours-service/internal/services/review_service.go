@@ -1,22 +1,310 @@
 package services
 
 import(
+	"context"
+	"errors"
+	"math"
+	"time"
+
 	"tours-service/internal/repositories"
 	"tours-service/internal/models"
 )
 
+// minReviewProgress is the share of keypoints a walked, unfinished execution needs
+// before its tourist may review the tour without a purchase.
+const minReviewProgress = 0.5
+
+var (
+	ErrReviewNotAllowed = errors.New("only tourists who purchased or walked the tour can review it")
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewForbidden  = errors.New("you are not allowed to change this review")
+)
+
 type TourReviewService struct{
 	TourReviewRepository *repositories.TourReviewRepository
+	ExecutionRepository  *repositories.TourExecutionRepository
+	KeypointRepository   *repositories.KeypointRepository
+	TourRepository       *repositories.TourRepository
+}
+
+func NewTourReviewService(tourReviewRepository *repositories.TourReviewRepository, executionRepository *repositories.TourExecutionRepository, keypointRepository *repositories.KeypointRepository, tourRepository *repositories.TourRepository) *TourReviewService{
+	return &TourReviewService{
+		TourReviewRepository: tourReviewRepository,
+		ExecutionRepository:  executionRepository,
+		KeypointRepository:   keypointRepository,
+		TourRepository:       tourRepository,
+	}
 }
 
-func NewTourReviewService(tourReviewRepository *repositories.TourReviewRepository) *TourReviewService{
-	return &TourReviewService{TourReviewRepository: tourReviewRepository}
+// CheckReviewEligibility verifies the tourist purchased the tour or walked enough of it,
+// and that visitDate lies between that moment and now. purchasedAt is nil when the
+// tourist has no purchase of the tour.
+func (t *TourReviewService) CheckReviewEligibility(tourId, touristId int, purchasedAt *time.Time, visitDate time.Time) error {
+	var walkedAt *time.Time
+	if purchasedAt == nil {
+		var err error
+		walkedAt, err = t.firstQualifyingExecution(tourId, touristId)
+		if err != nil {
+			return err
+		}
+	}
+	return reviewEligibility(purchasedAt, walkedAt, visitDate, time.Now())
 }
 
-func (t *TourReviewService) CreateTourReview(tourReview *models.TourReview) error{
-	return t.TourReviewRepository.CreateTourReview(tourReview);
+// reviewEligibility decides CheckReviewEligibility once the purchase and the earliest
+// qualifying execution are known. The purchase wins when there are both.
+func reviewEligibility(purchasedAt, walkedAt *time.Time, visitDate, now time.Time) error {
+	eligibleSince := purchasedAt
+	if eligibleSince == nil {
+		eligibleSince = walkedAt
+	}
+	if eligibleSince == nil {
+		return ErrReviewNotAllowed
+	}
+
+	if visitDate.After(now) {
+		return errors.New("visit date can't be in the future")
+	}
+	if visitDate.Before(*eligibleSince) {
+		if purchasedAt != nil {
+			return errors.New("visit date can't be before the tour was purchased")
+		}
+		return errors.New("visit date can't be before the tour was started")
+	}
+	return nil
+}
+
+// firstQualifyingExecution returns the start of the earliest execution that was completed
+// or covered at least minReviewProgress of the keypoints, or nil if there is none.
+func (t *TourReviewService) firstQualifyingExecution(tourId, touristId int) (*time.Time, error) {
+	executions, err := t.ExecutionRepository.FindHistoryByUserAndTourId(touristId, tourId)
+	if err != nil {
+		return nil, err
+	}
+	if len(executions) == 0 {
+		return nil, nil
+	}
+
+	keypoints, err := t.KeypointRepository.GetKeypointsByTourID(tourId)
+	if err != nil {
+		return nil, err
+	}
+	return earliestQualifyingStart(executions, len(keypoints)), nil
 }
 
-func (t *TourReviewService) GetReviewsByTourID(tourReviewId int) ([]models.TourReview, error) {
-	return t.TourReviewRepository.GetReviewsByTourID(tourReviewId);
-}
\ No newline at end of file
+// earliestQualifyingStart returns the start of the earliest execution that was completed
+// or finished at least minReviewProgress of the tour's keypoints.
+func earliestQualifyingStart(executions []*models.TourExecution, keypointCount int) *time.Time {
+	required := int(math.Ceil(float64(keypointCount) * minReviewProgress))
+	if required < 1 {
+		required = 1
+	}
+
+	var earliest *time.Time
+	for _, execution := range executions {
+		if execution.StartedAt == nil {
+			continue
+		}
+		if execution.Status != models.ExecutionStatusCompleted && len(execution.FinishedKeypoints) < required {
+			continue
+		}
+		if earliest == nil || execution.StartedAt.Before(*earliest) {
+			earliest = execution.StartedAt
+		}
+	}
+	return earliest
+}
+
+// SubmitTourReview stores the tourist's review of the tour, replacing the content of an
+// earlier one if they already reviewed it. It reports whether a new review was created.
+func (t *TourReviewService) SubmitTourReview(tourReview *models.TourReview) (bool, error) {
+	existing, err := t.TourReviewRepository.GetReviewByTourAndTourist(tourReview.TourID, tourReview.TouristID)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		return true, t.TourReviewRepository.CreateTourReview(tourReview)
+	}
+
+	tourReview.ID = existing.ID
+	tourReview.CommentDate = existing.CommentDate
+	if len(tourReview.ImageURLs) == 0 {
+		tourReview.ImageURLs = existing.ImageURLs
+	}
+	return false, t.TourReviewRepository.UpdateTourReview(tourReview)
+}
+
+// GetOwnReview returns a review of the tourist, hidden ones included.
+func (t *TourReviewService) GetOwnReview(reviewId, touristId int) (*models.TourReview, error) {
+	review, err := t.TourReviewRepository.GetReviewByID(reviewId)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, ErrReviewNotFound
+	}
+	if review.TouristID != touristId {
+		return nil, ErrReviewForbidden
+	}
+	return review, nil
+}
+
+func (t *TourReviewService) UpdateTourReview(tourReview *models.TourReview) error {
+	return t.TourReviewRepository.UpdateTourReview(tourReview)
+}
+
+func (t *TourReviewService) DeleteTourReview(reviewId, touristId int) error {
+	if _, err := t.GetOwnReview(reviewId, touristId); err != nil {
+		return err
+	}
+	return t.TourReviewRepository.DeleteTourReview(reviewId)
+}
+
+// visibleReviewOfGuide returns a visible review of one of the guide's tours.
+func (t *TourReviewService) visibleReviewOfGuide(reviewId, guideId int) (*models.TourReview, error) {
+	review, err := t.TourReviewRepository.GetReviewByID(reviewId)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil || review.Status == models.ReviewHidden {
+		return nil, ErrReviewNotFound
+	}
+	tour, err := t.TourRepository.GetTourByID(review.TourID)
+	if err != nil {
+		return nil, err
+	}
+	if tour.AuthorID != guideId {
+		return nil, ErrReviewForbidden
+	}
+	return review, nil
+}
+
+// ReplyToReview sets the single public reply of the tour's guide, editing it if it exists.
+func (t *TourReviewService) ReplyToReview(reviewId, guideId int, text string) (*models.TourReview, error) {
+	review, err := t.visibleReviewOfGuide(reviewId, guideId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	reply := &models.ReviewReply{GuideID: guideId, Text: text, CreatedAt: now}
+	if review.Reply != nil {
+		reply.CreatedAt = review.Reply.CreatedAt
+		reply.UpdatedAt = &now
+	}
+	if err := t.TourReviewRepository.SetReply(reviewId, reply); err != nil {
+		return nil, err
+	}
+	review.Reply = reply
+	return review, nil
+}
+
+func (t *TourReviewService) DeleteReply(reviewId, guideId int) error {
+	review, err := t.visibleReviewOfGuide(reviewId, guideId)
+	if err != nil {
+		return err
+	}
+	if review.Reply == nil {
+		return errors.New("review has no reply")
+	}
+	return t.TourReviewRepository.DeleteReply(reviewId)
+}
+
+// ReportReview flags a review as abusive, which puts it in the admin moderation queue.
+func (t *TourReviewService) ReportReview(reviewId, reporterId int, reason string) error {
+	review, err := t.TourReviewRepository.GetReviewByID(reviewId)
+	if err != nil {
+		return err
+	}
+	if review == nil || review.Status == models.ReviewHidden {
+		return ErrReviewNotFound
+	}
+	if review.TouristID == reporterId {
+		return errors.New("you can't report your own review")
+	}
+
+	report := models.ReviewReport{ReporterID: reporterId, Reason: reason, CreatedAt: time.Now()}
+	added, err := t.TourReviewRepository.AddReport(reviewId, report)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.New("you already reported this review")
+	}
+	return nil
+}
+
+// ModerateReview hides a review or restores a hidden one.
+func (t *TourReviewService) ModerateReview(reviewId, adminId int, hide bool) (*models.TourReview, error) {
+	review, err := t.TourReviewRepository.SetVisibility(reviewId, hide, adminId)
+	if err != nil {
+		return nil, err
+	}
+	if review != nil {
+		return review, nil
+	}
+
+	existing, err := t.TourReviewRepository.GetReviewByID(reviewId)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrReviewNotFound
+	}
+	if hide {
+		return nil, errors.New("review is already hidden")
+	}
+	return nil, errors.New("review is not hidden")
+}
+
+// DismissReports closes the reports on a review the admin found fine.
+func (t *TourReviewService) DismissReports(reviewId, adminId int) (*models.TourReview, error) {
+	review, err := t.TourReviewRepository.DismissReports(reviewId, adminId)
+	if err != nil {
+		return nil, err
+	}
+	if review != nil {
+		return review, nil
+	}
+
+	existing, err := t.TourReviewRepository.GetReviewByID(reviewId)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrReviewNotFound
+	}
+	return nil, errors.New("review is not reported")
+}
+
+// GetModerationQueue lists reported reviews waiting for an admin, or hidden ones to restore.
+func (t *TourReviewService) GetModerationQueue(status models.ReviewStatus, limit int) ([]models.TourReview, error) {
+	return t.TourReviewRepository.GetReviewsByStatus(status, limit)
+}
+
+// RecalculateRatings rebuilds the rating summaries of all tours from their reviews.
+func (t *TourReviewService) RecalculateRatings(ctx context.Context) error {
+	return t.TourReviewRepository.RecalculateRatings(ctx)
+}
+
+func (t *TourReviewService) GetReviewsPage(tourId int, query models.ReviewQuery) (*models.ReviewPage, error) {
+	reviews, total, err := t.TourReviewRepository.GetReviewsPage(tourId, query)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReviewPage{
+		Reviews:  reviews,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+		Total:    total,
+		HasMore:  int64(query.Page*query.PageSize) < total,
+	}, nil
+}
+
+// GetTopReviews returns the best rated visible reviews of a tour, newest first among
+// equal ratings, for embedding in tour details.
+func (t *TourReviewService) GetTopReviews(tourId, count int) ([]models.TourReview, error) {
+	query := models.ReviewQuery{Sort: models.ReviewSortHighest, Page: 1, PageSize: count}
+	reviews, _, err := t.TourReviewRepository.GetReviewsPage(tourId, query)
+	return reviews, err
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tours-service/internal/models"
+)
+
+func TestReviewEligibility(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	purchased := now.Add(-30 * 24 * time.Hour)
+	walked := now.Add(-10 * 24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		purchasedAt *time.Time
+		walkedAt    *time.Time
+		visitDate   time.Time
+		wantErr     string // empty means eligible
+		notAllowed  bool
+	}{
+		{name: "neither purchased nor walked", visitDate: now.Add(-time.Hour), notAllowed: true},
+		{name: "purchased, visited after", purchasedAt: &purchased, visitDate: purchased.Add(time.Hour)},
+		{name: "purchased, visited at the purchase", purchasedAt: &purchased, visitDate: purchased},
+		{name: "purchased, visited before", purchasedAt: &purchased, visitDate: purchased.Add(-time.Hour), wantErr: "visit date can't be before the tour was purchased"},
+		{name: "walked, visited after", walkedAt: &walked, visitDate: walked.Add(time.Hour)},
+		{name: "walked, visited before", walkedAt: &walked, visitDate: walked.Add(-time.Hour), wantErr: "visit date can't be before the tour was started"},
+		{name: "purchase wins over a later walk", purchasedAt: &purchased, walkedAt: &walked, visitDate: purchased.Add(time.Hour)},
+		{name: "visit in the future", purchasedAt: &purchased, visitDate: now.Add(time.Minute), wantErr: "visit date can't be in the future"},
+		{name: "visit right now", walkedAt: &walked, visitDate: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := reviewEligibility(tt.purchasedAt, tt.walkedAt, tt.visitDate, now)
+			switch {
+			case tt.notAllowed:
+				if !errors.Is(err, ErrReviewNotAllowed) {
+					t.Errorf("reviewEligibility() error = %v, want %v", err, ErrReviewNotAllowed)
+				}
+			case tt.wantErr != "":
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("reviewEligibility() error = %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("reviewEligibility() error = %v, want eligible", err)
+			}
+		})
+	}
+}
+
+func TestEarliestQualifyingStart(t *testing.T) {
+	day := func(d int) *time.Time {
+		at := time.Date(2026, 6, d, 9, 0, 0, 0, time.UTC)
+		return &at
+	}
+	finished := func(n int) []models.FinishedKeyPoint {
+		return make([]models.FinishedKeyPoint, n)
+	}
+
+	tests := []struct {
+		name          string
+		executions    []*models.TourExecution
+		keypointCount int
+		want          *time.Time
+	}{
+		{name: "no executions", keypointCount: 4},
+		{
+			name: "completed execution qualifies",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusCompleted, StartedAt: day(3), FinishedKeypoints: finished(4)},
+			},
+			keypointCount: 4,
+			want:          day(3),
+		},
+		{
+			name: "half of the keypoints qualifies",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusAborted, StartedAt: day(5), FinishedKeypoints: finished(2)},
+			},
+			keypointCount: 4,
+			want:          day(5),
+		},
+		{
+			name: "less than half does not qualify",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusAborted, StartedAt: day(5), FinishedKeypoints: finished(2)},
+			},
+			keypointCount: 5,
+		},
+		{
+			name: "earliest qualifying start wins",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusCompleted, StartedAt: day(9)},
+				{Status: models.ExecutionStatusInProgress, StartedAt: day(2), FinishedKeypoints: finished(1)},
+				{Status: models.ExecutionStatusFailed, StartedAt: day(4), FinishedKeypoints: finished(3)},
+			},
+			keypointCount: 5,
+			want:          day(4),
+		},
+		{
+			name: "at least one keypoint is required",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusAborted, StartedAt: day(1)},
+			},
+			keypointCount: 0,
+		},
+		{
+			name: "executions that never started are ignored",
+			executions: []*models.TourExecution{
+				{Status: models.ExecutionStatusCompleted},
+			},
+			keypointCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := earliestQualifyingStart(tt.executions, tt.keypointCount)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("earliestQualifyingStart() = %v, want nil", *got)
+			case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+				t.Errorf("earliestQualifyingStart() = %v, want %v", got, *tt.want)
+			}
+		})
+	}
+}
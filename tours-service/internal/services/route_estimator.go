@@ -0,0 +1,32 @@
+package services
+
+import (
+	"tours-service/internal/models"
+)
+
+// detourFactor scales the straight-line distance to approximate the length of the
+// actual road or path between two points.
+const detourFactor = 1.3
+
+// averageSpeeds are the speeds in meters per second used when map-service is unavailable.
+var averageSpeeds = map[string]float64{
+	models.ProfileDriving: 50 / 3.6,
+	models.ProfileWalking: 5 / 3.6,
+	models.ProfileCycling: 15 / 3.6,
+}
+
+// estimateLeg approximates a leg for every profile from the haversine distance between
+// its keypoints. The leg has no geometry, so it is drawn as a straight line.
+func estimateLeg(origin, dest models.Keypoint) map[string]models.RouteLeg {
+	distance := haversineDistance(origin.Latitude, origin.Longitude, dest.Latitude, dest.Longitude) * detourFactor
+
+	leg := make(map[string]models.RouteLeg, len(routeProfiles))
+	for _, profile := range routeProfiles {
+		leg[profile] = models.RouteLeg{
+			Distance:  distance,
+			Duration:  distance / averageSpeeds[profile],
+			Estimated: true,
+		}
+	}
+	return leg
+}
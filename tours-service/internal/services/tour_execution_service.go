@@ -4,31 +4,48 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 	"tours-service/internal/models"
 	"tours-service/internal/repositories"
-
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type TourExecutionService struct {
 	TourExecutionRepository *repositories.TourExecutionRepository
 	TourService             *TourService
 	KeyPointsService        *KeypointService
+	TrailService            *PositionTrailService
+	EventService            *ExecutionEventService
+	IdleTimeout             time.Duration // in-progress executions idle this long are abandoned
+	ResumeGracePeriod       time.Duration // abandoned executions can be resumed for this long
+	DwellFixes              int           // consecutive in-range position fixes needed to complete a keypoint
 }
 
-func NewTourExecutionService(tourExRepository *repositories.TourExecutionRepository, tourService *TourService, keyPointService *KeypointService) *TourExecutionService {
+// maxFixAccuracy is the worst horizontal GPS accuracy in meters a position fix may have
+// to count towards completing a keypoint.
+const maxFixAccuracy = 100.0
+
+func NewTourExecutionService(tourExRepository *repositories.TourExecutionRepository, tourService *TourService, keyPointService *KeypointService, trailService *PositionTrailService, eventService *ExecutionEventService, idleTimeout, resumeGracePeriod time.Duration, dwellFixes int) *TourExecutionService {
 	return &TourExecutionService{
 		TourExecutionRepository: tourExRepository,
 		TourService:             tourService,
 		KeyPointsService:        keyPointService,
+		TrailService:            trailService,
+		EventService:            eventService,
+		IdleTimeout:             idleTimeout,
+		ResumeGracePeriod:       resumeGracePeriod,
+		DwellFixes:              dwellFixes,
 	}
 }
 
 func (tes *TourExecutionService) StartTour(userId int, tourId int) (*int, int, error) {
-	tourExecution, err := tes.TourExecutionRepository.FindByUserAndTourId(userId, tourId)
-	if tourExecution != nil || err == nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("tour already started")
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("tour already in progress")
 	}
 	tour, err := tes.TourService.GetTourByID(tourId)
 	if err != nil {
@@ -41,13 +58,16 @@ func (tes *TourExecutionService) StartTour(userId int, tourId int) (*int, int, e
 	newTourExecution := models.TourExecution{TourID: tourId, UserID: userId, StartedAt: &now, LastActivity: &now, Status: models.ExecutionStatusInProgress}
 	error := tes.TourExecutionRepository.CreateExecution(&newTourExecution)
 	if error != nil {
+		if strings.Contains(error.Error(), "already in progress") {
+			return nil, http.StatusBadRequest, error
+		}
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed to create tour execution")
 	}
 	return &newTourExecution.ID, http.StatusCreated, nil
 }
 
 func (tes *TourExecutionService) AbortExecution(tourId, userId int) (int, error) {
-	tourExecution, err := tes.TourExecutionRepository.FindByUserAndTourId(userId, tourId)
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
 	if err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("error finding tour execution: %w", err)
 	}
@@ -75,78 +95,459 @@ func (tes *TourExecutionService) GetExecutionsByUser(userId int) ([]*models.Tour
 	return executions, http.StatusOK, nil
 }
 
+// GetMyExecutionByTourID returns the execution of the tour the user is walking right now.
 func (tes *TourExecutionService) GetMyExecutionByTourID(userId, tourId int) (*models.TourExecution, int, error) {
-	tourExecution, err := tes.TourExecutionRepository.FindByUserAndTourId(userId, tourId)
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
 	if err != nil {
 		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
 	}
+	if tourExecution == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no active tour execution found")
+	}
 	return tourExecution, http.StatusOK, nil
 }
 
-func (tes *TourExecutionService) CheckIsKeyPointReached(tourId, userId int, long, lat float64) (int, *models.Keypoint, bool, error) {
-	tourExecution, err := tes.TourExecutionRepository.FindByUserAndTourId(userId, tourId)
+// GetMyExecutionHistoryByTourID returns every execution of the tour by the user, newest first.
+func (tes *TourExecutionService) GetMyExecutionHistoryByTourID(userId, tourId int) ([]*models.TourExecution, int, error) {
+	executions, err := tes.TourExecutionRepository.FindHistoryByUserAndTourId(userId, tourId)
 	if err != nil {
-		return http.StatusInternalServerError, nil, false, fmt.Errorf("database error: %w", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	return executions, http.StatusOK, nil
+}
+
+// CheckIsKeyPointReached completes keypoints at the tourist's position. Ordered tours
+// only look at the next keypoint by ordinal, free-roam tours complete every uncompleted
+// keypoint in range. A keypoint is completed once DwellFixes consecutive position fixes
+// were in range of it; fixes with poor accuracy are ignored. A keypoint with a challenge
+// also needs it solved through SubmitChallengeAnswer while the tourist is still in range.
+// It returns the keypoints completed by this check.
+func (tes *TourExecutionService) CheckIsKeyPointReached(tourId, userId int, fix *models.PositionFix) (int, []models.Keypoint, bool, error) {
+	tourExecution, tour, keypoints, httpStatus, err := tes.activeExecution(tourId, userId)
+	if err != nil {
+		return httpStatus, nil, false, err
+	}
+	return tes.reachKeypoints(tourExecution, tour, keypoints, fix)
+}
+
+// activeExecution loads the in-progress execution of the tourist on a tour, together
+// with the tour and its keypoints sorted by ordinal.
+func (tes *TourExecutionService) activeExecution(tourId, userId int) (*models.TourExecution, *models.Tour, []models.Keypoint, int, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
+	if err != nil {
+		return nil, nil, nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
 	}
 	if tourExecution == nil {
-		return http.StatusNotFound, nil, false, fmt.Errorf("no tour execution found")
+		return nil, nil, nil, http.StatusNotFound, fmt.Errorf("no tour execution found")
 	}
 	if tourExecution.Status != models.ExecutionStatusInProgress {
-		return http.StatusNotFound, nil, false, fmt.Errorf("tour execution not in progress")
+		return nil, nil, nil, http.StatusNotFound, fmt.Errorf("tour execution not in progress")
 	}
 	if userId != tourExecution.UserID {
-		return http.StatusUnauthorized, nil, false, fmt.Errorf("user not authorized to change this tour execution")
+		return nil, nil, nil, http.StatusUnauthorized, fmt.Errorf("user not authorized to change this tour execution")
 	}
-	var completedKeyPointsIds []int
-	for _, kp := range tourExecution.FinishedKeypoints {
-		completedKeyPointsIds = append(completedKeyPointsIds, kp.KeypointID)
+	tour, err := tes.TourService.GetTourByID(tourId)
+	if err != nil {
+		return nil, nil, nil, http.StatusNotFound, fmt.Errorf("unable to find tour with id %d", tourId)
 	}
-	keyPoint, err := tes.KeyPointsService.KeypointRepo.GetUncompletedKeyPointsByTourId(tourId, completedKeyPointsIds)
+	keypoints, err := tes.KeyPointsService.GetKeypointsByTourID(tourId)
 	if err != nil {
-		return http.StatusNotFound, nil, false, err
+		return nil, nil, nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	return tourExecution, tour, keypoints, http.StatusOK, nil
+}
+
+// reachKeypoints is CheckIsKeyPointReached on an execution that is already loaded. The
+// fix goes on the trail first, and tourExecution is updated with the keypoints completed.
+func (tes *TourExecutionService) reachKeypoints(tourExecution *models.TourExecution, tour *models.Tour, keypoints []models.Keypoint, fix *models.PositionFix) (int, []models.Keypoint, bool, error) {
+	checkedAt := time.Now()
+	if err := tes.TourExecutionRepository.TouchExecution(tourExecution.ID, &checkedAt); err != nil {
+		fmt.Printf("Warning: Failed to record activity on execution %d: %v\n", tourExecution.ID, err)
+	}
+	tes.recordTrail(tourExecution, fix)
+
+	completed := make(map[int]bool, len(tourExecution.FinishedKeypoints))
+	for _, kp := range tourExecution.FinishedKeypoints {
+		completed[kp.KeypointID] = true
+	}
+	var uncompleted []models.Keypoint
+	for _, keypoint := range keypoints {
+		if !completed[keypoint.ID] {
+			uncompleted = append(uncompleted, keypoint)
+		}
 	}
-	if keyPoint == nil {
+	if len(uncompleted) == 0 {
 		return http.StatusNotFound, nil, false, fmt.Errorf("no key point found")
 	}
-	fmt.Println("Next uncompleted key point is " + keyPoint.Name)
 
-	if !tes.checkDistance(long, lat, keyPoint.Longitude, keyPoint.Latitude, 50.0) {
+	// Keypoints come sorted by ordinal, so in ordered mode only the first one counts
+	candidates := uncompleted[:1]
+	if tour.CompletionMode == models.CompletionFreeRoam {
+		candidates = uncompleted
+	}
+
+	if fix.Accuracy != nil && *fix.Accuracy > maxFixAccuracy {
+		return http.StatusOK, nil, false, fmt.Errorf("position accuracy too low (%.0f m), wait for a better GPS fix", *fix.Accuracy)
+	}
+
+	// Polling again before a new position was reported must not count the same fix twice
+	newFix := fix.RecordedAt.IsZero() || tourExecution.LastFixAt == nil || fix.RecordedAt.After(*tourExecution.LastFixAt)
+	dwell := make(map[int]int, len(tourExecution.Dwell))
+	for _, counter := range tourExecution.Dwell {
+		dwell[counter.KeypointID] = counter.Count
+	}
+
+	// Keypoints out of range drop out of the counters, so the fixes have to be consecutive
+	var reached []models.Keypoint
+	var pending []models.Keypoint
+	var awaiting []models.Keypoint
+	counters := []models.DwellCounter{}
+	for _, keypoint := range candidates {
+		if !tes.checkDistance(fix.Longitude, fix.Latitude, keypoint.Longitude, keypoint.Latitude, completionRadius(tour, keypoint, fix.Accuracy)) {
+			continue
+		}
+		count := dwell[keypoint.ID]
+		if newFix {
+			count++
+		}
+		if count >= tes.DwellFixes {
+			if challengeSolved(tourExecution, keypoint) || challengeFailed(tourExecution, keypoint) {
+				reached = append(reached, keypoint)
+				continue
+			}
+			// Reached, but the challenge still has to be answered while in range
+			awaiting = append(awaiting, keypoint)
+			counters = append(counters, models.DwellCounter{KeypointID: keypoint.ID, Count: tes.DwellFixes})
+			continue
+		}
+		pending = append(pending, keypoint)
+		counters = append(counters, models.DwellCounter{KeypointID: keypoint.ID, Count: count})
+	}
+
+	if newFix {
+		fixAt := fix.RecordedAt
+		if fixAt.IsZero() {
+			fixAt = checkedAt
+		}
+		if err := tes.TourExecutionRepository.SetDwell(tourExecution.ID, counters, &fixAt); err != nil {
+			return http.StatusInternalServerError, nil, false, fmt.Errorf("unable to update execution")
+		}
+	}
+
+	if len(reached) == 0 {
+		if len(awaiting) > 0 {
+			return http.StatusOK, nil, false, fmt.Errorf("solve the %s challenge of key point %s to complete it",
+				awaiting[0].Challenge.Type, awaiting[0].Name)
+		}
+		if len(pending) > 0 {
+			return http.StatusOK, nil, false, fmt.Errorf("stay near key point %s to complete it (%d/%d position fixes)",
+				pending[0].Name, dwellCount(counters, pending[0].ID), tes.DwellFixes)
+		}
 		return http.StatusOK, nil, false, fmt.Errorf("you are not close enough to complete key point")
 	}
 
+	completedNow, finished, err := tes.completeKeypoints(tourExecution, reached, keypoints)
+	if err != nil {
+		return http.StatusInternalServerError, nil, false, err
+	}
+	if len(completedNow) == 0 {
+		return http.StatusOK, nil, false, fmt.Errorf("key point %s was already completed", reached[0].Name)
+	}
+	return http.StatusOK, completedNow, finished, nil
+}
+
+// completeKeypoints marks keypoints of an execution as completed and finishes the tour
+// once every keypoint of the tour is, judged from the execution as stored after the
+// update. Keypoints a concurrent request completed first are skipped. It returns the
+// keypoints this call completed and whether it finished the tour.
+func (tes *TourExecutionService) completeKeypoints(tourExecution *models.TourExecution, reached []models.Keypoint, keypoints []models.Keypoint) ([]models.Keypoint, bool, error) {
 	now := time.Now()
-	newFinishedKp := &models.FinishedKeyPoint{KeypointID: keyPoint.ID, CompletedAt: &now}
+	var updated *models.TourExecution
+	var completedNow []models.Keypoint
+	for _, keypoint := range reached {
+		stored, err := tes.TourExecutionRepository.CompleateKeyPoint(tourExecution.ID, models.FinishedKeyPoint{
+			KeypointID:      keypoint.ID,
+			CompletedAt:     &now,
+			ChallengeFailed: challengeFailed(tourExecution, keypoint),
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("unable to update execution")
+		}
+		if stored == nil {
+			continue
+		}
+		updated = stored
+		completedNow = append(completedNow, keypoint)
+		tes.EventService.PublishOrWarn(models.ExecutionEvent{
+			ExecutionID:  tourExecution.ID,
+			Type:         models.EventKeypointReached,
+			KeypointID:   keypoint.ID,
+			KeypointName: keypoint.Name,
+		})
+	}
+	if updated == nil {
+		return nil, false, nil
+	}
+	tourExecution.FinishedKeypoints = updated.FinishedKeypoints
 
-	completedKeyPoints := append(tourExecution.FinishedKeypoints, *newFinishedKp)
-	_, err = tes.TourExecutionRepository.CompleateKeyPoint(tourExecution.ID, completedKeyPoints, &now)
+	completed := make(map[int]bool, len(updated.FinishedKeypoints))
+	for _, kp := range updated.FinishedKeypoints {
+		completed[kp.KeypointID] = true
+	}
+	for _, keypoint := range keypoints {
+		if !completed[keypoint.ID] {
+			return completedNow, false, nil
+		}
+	}
+
+	finished, err := tes.TourExecutionRepository.CompleateTour(tourExecution.ID, &now)
 	if err != nil {
-		return http.StatusInternalServerError, nil, false, fmt.Errorf("unable to update execution")
+		return nil, false, err
 	}
+	if finished {
+		tes.EventService.PublishOrWarn(models.ExecutionEvent{ExecutionID: tourExecution.ID, Type: models.EventTourCompleted})
+	}
+	return completedNow, finished, nil
+}
 
-	tourExecution, err = tes.TourExecutionRepository.FindByUserAndTourId(userId, tourId)
-	if err != nil || tourExecution == nil {
-		return http.StatusInternalServerError, nil, false, fmt.Errorf("database error: %w", err)
+// dwellCount returns the counter of a keypoint, 0 when it has none.
+func dwellCount(counters []models.DwellCounter, keypointID int) int {
+	for _, counter := range counters {
+		if counter.KeypointID == keypointID {
+			return counter.Count
+		}
+	}
+	return 0
+}
+
+// ResumeExecution continues an execution the sweeper abandoned, as long as its grace
+// period has not run out.
+func (tes *TourExecutionService) ResumeExecution(tourId, userId int) (*models.TourExecution, int, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindLatestByUserAndTourId(userId, tourId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("tour execution not found")
+	}
+	if tourExecution.Status != models.ExecutionStatusFailed || tourExecution.EndReason != models.ExecutionEndReasonAbandoned {
+		return nil, http.StatusBadRequest, fmt.Errorf("only abandoned tour executions can be resumed")
+	}
+
+	now := time.Now()
+	resumed, err := tes.TourExecutionRepository.ResumeExecution(tourExecution.ID, &now)
+	if err != nil {
+		if strings.Contains(err.Error(), "already in progress") {
+			return nil, http.StatusConflict, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	if !resumed {
+		return nil, http.StatusGone, fmt.Errorf("grace period for resuming this tour execution has expired")
+	}
+
+	tourExecution, err = tes.TourExecutionRepository.FindById(tourExecution.ID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	return tourExecution, http.StatusOK, nil
+}
+
+// AbandonIdleExecutions is the sweeper: it fails executions idle for longer than
+// IdleTimeout and leaves them resumable for ResumeGracePeriod.
+func (tes *TourExecutionService) AbandonIdleExecutions() (int64, error) {
+	now := time.Now()
+	abandoned, err := tes.TourExecutionRepository.AbandonIdleExecutions(now.Add(-tes.IdleTimeout), now, now.Add(tes.ResumeGracePeriod))
+	for _, executionId := range abandoned {
+		tes.EventService.PublishOrWarn(models.ExecutionEvent{
+			ExecutionID: executionId,
+			Type:        models.EventExecutionAbandoned,
+			Message:     fmt.Sprintf("tour execution abandoned after %s without activity", tes.IdleTimeout),
+		})
+	}
+	return int64(len(abandoned)), err
+}
+
+// StreamExecution resolves the execution a live stream of the tour follows: the one in
+// progress, or otherwise the latest, so a client reconnecting after the execution ended
+// still receives its last events.
+func (tes *TourExecutionService) StreamExecution(tourId, userId int) (*models.TourExecution, int, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
+	if err == nil && tourExecution == nil {
+		tourExecution, err = tes.TourExecutionRepository.FindLatestByUserAndTourId(userId, tourId)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("no tour execution found")
+	}
+	return tourExecution, http.StatusOK, nil
+}
+
+// ProcessPosition handles a position reported over a live stream or the position
+// endpoint: it completes keypoints in range, then publishes and returns the distance to
+// the next keypoint. A nil event means the tour is finished.
+func (tes *TourExecutionService) ProcessPosition(tourId, userId int, fix *models.PositionFix) (*models.ExecutionEvent, int, error) {
+	tourExecution, tour, keypoints, httpStatus, err := tes.activeExecution(tourId, userId)
+	if err != nil {
+		return nil, httpStatus, err
 	}
 
-	completedKeyPointsIds = []int{}
+	httpStatus, _, finished, err := tes.reachKeypoints(tourExecution, tour, keypoints, fix)
+	if httpStatus != http.StatusOK {
+		return nil, httpStatus, err
+	}
+	if finished {
+		return nil, http.StatusOK, nil
+	}
+
+	// Not being close enough, or still dwelling, is reported as the event message
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	next, distance := nextKeypoint(tour, keypoints, tourExecution, fix)
+	if next == nil {
+		return nil, http.StatusOK, nil
+	}
+	event := models.ExecutionEvent{
+		ExecutionID:  tourExecution.ID,
+		Type:         models.EventDistance,
+		KeypointID:   next.ID,
+		KeypointName: next.Name,
+		Distance:     distance,
+		Message:      message,
+	}
+	tes.EventService.PublishLive(event)
+	return &event, http.StatusOK, nil
+}
+
+// nextKeypoint returns the keypoint the tourist is heading to and its distance in
+// meters: the first uncompleted one by ordinal, or the closest one in free-roam mode.
+func nextKeypoint(tour *models.Tour, keypoints []models.Keypoint, tourExecution *models.TourExecution, fix *models.PositionFix) (*models.Keypoint, float64) {
+	completed := make(map[int]bool, len(tourExecution.FinishedKeypoints))
 	for _, kp := range tourExecution.FinishedKeypoints {
-		completedKeyPointsIds = append(completedKeyPointsIds, kp.KeypointID)
+		completed[kp.KeypointID] = true
 	}
-	_, err = tes.KeyPointsService.KeypointRepo.GetUncompletedKeyPointsByTourId(tourId, completedKeyPointsIds)
+
+	var next *models.Keypoint
+	best := math.Inf(1)
+	for i := range keypoints {
+		if completed[keypoints[i].ID] {
+			continue
+		}
+		distance := haversineDistance(fix.Latitude, fix.Longitude, keypoints[i].Latitude, keypoints[i].Longitude)
+		if tour.CompletionMode != models.CompletionFreeRoam {
+			return &keypoints[i], distance
+		}
+		if distance < best {
+			next, best = &keypoints[i], distance
+		}
+	}
+	return next, best
+}
+
+// onRouteThreshold is how far in meters a tourist may stray from the route line.
+const onRouteThreshold = 50.0
+
+// CheckOnRoute reports whether the tourist is on the walking route of the tour they are
+// executing, together with their distance from it in meters.
+func (tes *TourExecutionService) CheckOnRoute(tourId, userId int, fix *models.PositionFix) (int, bool, float64, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindActiveByUserAndTourId(userId, tourId)
 	if err != nil {
-		if err != mongo.ErrNoDocuments {
-			return http.StatusNotFound, nil, false, err
-		} else {
-			fmt.Println("Tour completed successfully")
-			_, err = tes.TourExecutionRepository.CompleateTour(tourExecution.ID, &now)
-			if err != nil {
-				return http.StatusInternalServerError, nil, false, err
-			}
-			return http.StatusOK, keyPoint, true, nil
+		return http.StatusInternalServerError, false, 0, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution == nil {
+		return http.StatusNotFound, false, 0, fmt.Errorf("no tour execution found")
+	}
+	if tourExecution.Status != models.ExecutionStatusInProgress {
+		return http.StatusNotFound, false, 0, fmt.Errorf("tour execution not in progress")
+	}
+	tour, err := tes.TourService.GetTourByID(tourId)
+	if err != nil {
+		return http.StatusNotFound, false, 0, fmt.Errorf("unable to find tour with id %d", tourId)
+	}
+	keypoints, err := tes.KeyPointsService.GetKeypointsByTourID(tourId)
+	if err != nil {
+		return http.StatusInternalServerError, false, 0, fmt.Errorf("database error: %w", err)
+	}
+
+	tes.recordTrail(tourExecution, fix)
+
+	distance := distanceToLine(fix.Latitude, fix.Longitude, tes.TourService.RouteLine(tour, keypoints, models.ProfileWalking))
+	return http.StatusOK, distance <= onRouteThreshold, distance, nil
+}
+
+// recordTrail appends the fix to the execution trail. The trail is a by-product of the
+// check, so failing to record it only logs a warning.
+func (tes *TourExecutionService) recordTrail(tourExecution *models.TourExecution, fix *models.PositionFix) {
+	if err := tes.TrailService.RecordFix(tourExecution, fix); err != nil {
+		fmt.Printf("Warning: Failed to record position trail of execution %d: %v\n", tourExecution.ID, err)
+	}
+}
+
+// GetExecutionsByTour lists the executions of a tour for its author.
+func (tes *TourExecutionService) GetExecutionsByTour(tourId, guideId int) ([]*models.TourExecution, int, error) {
+	tour, err := tes.TourService.GetTourByID(tourId)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("unable to find tour with id %d", tourId)
+	}
+	if tour.AuthorID != guideId {
+		return nil, http.StatusForbidden, fmt.Errorf("only the tour author can view its executions")
+	}
+	executions, err := tes.TourExecutionRepository.FindByTourId(tourId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	return executions, http.StatusOK, nil
+}
+
+// GetExecutionTrail returns the position trail of an execution for replay. Tourists can
+// replay their own executions, guides the executions of their tours.
+func (tes *TourExecutionService) GetExecutionTrail(executionId, userId int, asGuide bool) (*models.PositionTrail, int, error) {
+	tourExecution, err := tes.TourExecutionRepository.FindById(executionId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	if tourExecution == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("tour execution not found")
+	}
+	if asGuide {
+		tour, err := tes.TourService.GetTourByID(tourExecution.TourID)
+		if err != nil {
+			return nil, http.StatusNotFound, fmt.Errorf("unable to find tour with id %d", tourExecution.TourID)
+		}
+		if tour.AuthorID != userId {
+			return nil, http.StatusForbidden, fmt.Errorf("only the tour author can view this trail")
 		}
+	} else if tourExecution.UserID != userId {
+		return nil, http.StatusForbidden, fmt.Errorf("user not authorized to view this trail")
 	}
 
-	return http.StatusOK, keyPoint, false, nil
+	trail, err := tes.TrailService.GetTrail(tourExecution)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return trail, http.StatusOK, nil
+}
+
+// completionRadius is the distance in meters within which a fix completes a keypoint:
+// the keypoint radius, or the tour default, widened by the fix accuracy. The widening is
+// capped at the radius itself so a vague fix can't complete keypoints far away.
+func completionRadius(tour *models.Tour, keypoint models.Keypoint, accuracy *float64) float64 {
+	radius := keypoint.Radius
+	if radius <= 0 {
+		radius = tour.DefaultRadius
+	}
+	if radius <= 0 {
+		radius = models.DefaultKeypointRadius
+	}
+	if accuracy != nil && *accuracy > 0 {
+		return radius + math.Min(*accuracy, radius)
+	}
+	return radius
 }
 
 func (tes *TourExecutionService) checkDistance(first_lon, first_lat, second_lon, second_lat, radius float64) bool {
@@ -170,3 +571,124 @@ func (tes *TourExecutionService) checkDistance(first_lon, first_lat, second_lon,
 
 	return distance <= radius
 }
+
+// GetTourAnalytics summarizes how a tour performs for its author, over the executions
+// started within [from, to). Nil bounds are open.
+func (tes *TourExecutionService) GetTourAnalytics(tourId, guideId int, from, to *time.Time) (*models.TourAnalytics, int, error) {
+	tour, err := tes.TourService.GetTourByID(tourId)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("unable to find tour with id %d", tourId)
+	}
+	if tour.AuthorID != guideId {
+		return nil, http.StatusForbidden, fmt.Errorf("only the tour author can view its analytics")
+	}
+	keypoints, err := tes.KeyPointsService.GetKeypointsByTourID(tourId)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+	summary, err := tes.TourExecutionRepository.GetExecutionSummary(tourId, from, to)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("database error: %w", err)
+	}
+
+	analytics := summarizeExecutions(tour, keypoints, summary)
+	analytics.From = from
+	analytics.To = to
+	return analytics, http.StatusOK, nil
+}
+
+// summarizeExecutions maps the aggregated executions of a tour onto its keypoints,
+// which come sorted by ordinal.
+func summarizeExecutions(tour *models.Tour, keypoints []models.Keypoint, summary *models.ExecutionSummary) *models.TourAnalytics {
+	names := make(map[int]string, len(keypoints))
+	ordinals := make(map[int]int, len(keypoints))
+	for i, keypoint := range keypoints {
+		names[keypoint.ID] = keypoint.Name
+		ordinals[keypoint.ID] = i
+	}
+
+	analytics := &models.TourAnalytics{
+		TourID:   tour.ID,
+		Segments: make([]models.SegmentAnalytics, 0, len(summary.Segments)),
+		DropOffs: []models.DropOffAnalytics{},
+	}
+
+	for _, status := range summary.Statuses {
+		analytics.Starts += status.Count
+		switch status.Status {
+		case models.ExecutionStatusCompleted:
+			analytics.Completed += status.Count
+		case models.ExecutionStatusAborted:
+			analytics.Aborted += status.Count
+		case models.ExecutionStatusFailed:
+			analytics.Abandoned += status.Count
+		case models.ExecutionStatusInProgress:
+			analytics.InProgress += status.Count
+		}
+	}
+	if analytics.Starts > 0 {
+		starts := float64(analytics.Starts)
+		analytics.CompletionRate = float64(analytics.Completed) / starts
+		analytics.AbortRate = float64(analytics.Aborted) / starts
+		analytics.AbandonRate = float64(analytics.Abandoned) / starts
+	}
+	if len(summary.Duration) > 0 {
+		analytics.MedianDuration = summary.Duration[0].Median
+	}
+
+	for _, segment := range summary.Segments {
+		analytics.Segments = append(analytics.Segments, models.SegmentAnalytics{
+			FromKeypointID:   segment.FromKeypointID,
+			FromKeypointName: names[segment.FromKeypointID],
+			ToKeypointID:     segment.ToKeypointID,
+			ToKeypointName:   names[segment.ToKeypointID],
+			Count:            segment.Count,
+			AverageDuration:  segment.AverageDuration,
+			MedianDuration:   segment.MedianDuration,
+		})
+	}
+	// Route order, starting from the start of the execution
+	segmentOrder := func(id int) int {
+		if id == 0 {
+			return -1
+		}
+		if ordinal, ok := ordinals[id]; ok {
+			return ordinal
+		}
+		return len(keypoints)
+	}
+	sort.Slice(analytics.Segments, func(i, j int) bool {
+		a, b := analytics.Segments[i], analytics.Segments[j]
+		if segmentOrder(a.FromKeypointID) != segmentOrder(b.FromKeypointID) {
+			return segmentOrder(a.FromKeypointID) < segmentOrder(b.FromKeypointID)
+		}
+		return segmentOrder(a.ToKeypointID) < segmentOrder(b.ToKeypointID)
+	})
+
+	dropOffs := map[int]int{}
+	for _, dropOff := range summary.DropOffs {
+		keypointID := dropOff.LastKeypointID
+		if tour.CompletionMode != models.CompletionFreeRoam {
+			// Ordered tours complete keypoints by ordinal, so the count tells which is next
+			if dropOff.CompletedCount >= len(keypoints) {
+				continue
+			}
+			keypointID = keypoints[dropOff.CompletedCount].ID
+		}
+		dropOffs[keypointID] += dropOff.Count
+	}
+	for keypointID, count := range dropOffs {
+		analytics.DropOffs = append(analytics.DropOffs, models.DropOffAnalytics{KeypointID: keypointID, KeypointName: names[keypointID], Count: count})
+	}
+	sort.Slice(analytics.DropOffs, func(i, j int) bool {
+		if analytics.DropOffs[i].Count != analytics.DropOffs[j].Count {
+			return analytics.DropOffs[i].Count > analytics.DropOffs[j].Count
+		}
+		return segmentOrder(analytics.DropOffs[i].KeypointID) < segmentOrder(analytics.DropOffs[j].KeypointID)
+	})
+	if len(analytics.DropOffs) > 0 {
+		analytics.TopDropOff = &analytics.DropOffs[0]
+	}
+
+	return analytics
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestSummarizeExecutions(t *testing.T) {
+	keypoints := []models.Keypoint{
+		{ID: 11, Name: "Fortress"},
+		{ID: 12, Name: "Bridge"},
+		{ID: 13, Name: "Square"},
+	}
+
+	tests := []struct {
+		name          string
+		mode          models.CompletionMode
+		summary       models.ExecutionSummary
+		wantCounts    [5]int // starts, completed, aborted, abandoned, in progress
+		wantRates     [3]float64
+		wantMedian    float64
+		wantSegments  []models.SegmentAnalytics
+		wantDropOffs  []models.DropOffAnalytics
+		wantTopDropID int // 0 means no top drop-off
+	}{
+		{
+			name:         "no executions",
+			wantSegments: []models.SegmentAnalytics{},
+			wantDropOffs: []models.DropOffAnalytics{},
+		},
+		{
+			name: "statuses and rates",
+			summary: models.ExecutionSummary{
+				Statuses: []models.StatusCount{
+					{Status: models.ExecutionStatusCompleted, Count: 5},
+					{Status: models.ExecutionStatusAborted, EndReason: "user", Count: 2},
+					{Status: models.ExecutionStatusFailed, EndReason: "idle", Count: 2},
+					{Status: models.ExecutionStatusInProgress, Count: 1},
+				},
+				Duration: []models.DurationMedian{{Median: 3600}},
+			},
+			wantCounts:   [5]int{10, 5, 2, 2, 1},
+			wantRates:    [3]float64{0.5, 0.2, 0.2},
+			wantMedian:   3600,
+			wantSegments: []models.SegmentAnalytics{},
+			wantDropOffs: []models.DropOffAnalytics{},
+		},
+		{
+			name: "segments follow the route from the start",
+			summary: models.ExecutionSummary{
+				Segments: []models.SegmentDurations{
+					{FromKeypointID: 12, ToKeypointID: 13, Count: 3, AverageDuration: 300, MedianDuration: 280},
+					{FromKeypointID: 0, ToKeypointID: 11, Count: 4, AverageDuration: 120, MedianDuration: 100},
+					{FromKeypointID: 11, ToKeypointID: 12, Count: 4, AverageDuration: 600, MedianDuration: 540},
+				},
+			},
+			wantSegments: []models.SegmentAnalytics{
+				{FromKeypointID: 0, ToKeypointID: 11, ToKeypointName: "Fortress", Count: 4, AverageDuration: 120, MedianDuration: 100},
+				{FromKeypointID: 11, FromKeypointName: "Fortress", ToKeypointID: 12, ToKeypointName: "Bridge", Count: 4, AverageDuration: 600, MedianDuration: 540},
+				{FromKeypointID: 12, FromKeypointName: "Bridge", ToKeypointID: 13, ToKeypointName: "Square", Count: 3, AverageDuration: 300, MedianDuration: 280},
+			},
+			wantDropOffs: []models.DropOffAnalytics{},
+		},
+		{
+			name: "ordered tours drop off at the next keypoint",
+			mode: models.CompletionOrdered,
+			summary: models.ExecutionSummary{
+				DropOffs: []models.DropOffCount{
+					{CompletedCount: 0, Count: 1},
+					{CompletedCount: 1, LastKeypointID: 11, Count: 4},
+					{CompletedCount: 2, LastKeypointID: 12, Count: 1},
+					{CompletedCount: 3, LastKeypointID: 13, Count: 7}, // nothing left to drop off at
+				},
+			},
+			wantSegments: []models.SegmentAnalytics{},
+			wantDropOffs: []models.DropOffAnalytics{
+				{KeypointID: 12, KeypointName: "Bridge", Count: 4},
+				{KeypointID: 11, KeypointName: "Fortress", Count: 1},
+				{KeypointID: 13, KeypointName: "Square", Count: 1},
+			},
+			wantTopDropID: 12,
+		},
+		{
+			name: "free-roam tours drop off at the last keypoint reached",
+			mode: models.CompletionFreeRoam,
+			summary: models.ExecutionSummary{
+				DropOffs: []models.DropOffCount{
+					{CompletedCount: 1, LastKeypointID: 13, Count: 2},
+					{CompletedCount: 2, LastKeypointID: 13, Count: 1},
+					{CompletedCount: 0, LastKeypointID: 0, Count: 2},
+				},
+			},
+			wantSegments: []models.SegmentAnalytics{},
+			wantDropOffs: []models.DropOffAnalytics{
+				{KeypointID: 13, KeypointName: "Square", Count: 3},
+				{KeypointID: 0, Count: 2},
+			},
+			wantTopDropID: 13,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := &models.Tour{ID: 7, CompletionMode: tt.mode}
+			got := summarizeExecutions(tour, keypoints, &tt.summary)
+
+			if got.TourID != tour.ID {
+				t.Errorf("TourID = %d, want %d", got.TourID, tour.ID)
+			}
+			counts := [5]int{got.Starts, got.Completed, got.Aborted, got.Abandoned, got.InProgress}
+			if counts != tt.wantCounts {
+				t.Errorf("counts = %v, want %v", counts, tt.wantCounts)
+			}
+			rates := [3]float64{got.CompletionRate, got.AbortRate, got.AbandonRate}
+			if rates != tt.wantRates {
+				t.Errorf("rates = %v, want %v", rates, tt.wantRates)
+			}
+			if got.MedianDuration != tt.wantMedian {
+				t.Errorf("MedianDuration = %v, want %v", got.MedianDuration, tt.wantMedian)
+			}
+			if !reflect.DeepEqual(got.Segments, tt.wantSegments) {
+				t.Errorf("Segments = %+v, want %+v", got.Segments, tt.wantSegments)
+			}
+			if !reflect.DeepEqual(got.DropOffs, tt.wantDropOffs) {
+				t.Errorf("DropOffs = %+v, want %+v", got.DropOffs, tt.wantDropOffs)
+			}
+			switch {
+			case tt.wantTopDropID == 0 && got.TopDropOff != nil:
+				t.Errorf("TopDropOff = %+v, want none", *got.TopDropOff)
+			case tt.wantTopDropID != 0 && (got.TopDropOff == nil || got.TopDropOff.KeypointID != tt.wantTopDropID):
+				t.Errorf("TopDropOff = %+v, want keypoint %d", got.TopDropOff, tt.wantTopDropID)
+			}
+		})
+	}
+}
+
+func TestCompletionRadius(t *testing.T) {
+	accuracy := func(meters float64) *float64 { return &meters }
+
+	tests := []struct {
+		name          string
+		defaultRadius float64
+		radius        float64
+		accuracy      *float64
+		want          float64
+	}{
+		{name: "service default", want: models.DefaultKeypointRadius},
+		{name: "tour default", defaultRadius: 30, want: 30},
+		{name: "keypoint radius wins", defaultRadius: 30, radius: 15, want: 15},
+		{name: "widened by the accuracy", radius: 20, accuracy: accuracy(8), want: 28},
+		{name: "widening capped at the radius", radius: 20, accuracy: accuracy(500), want: 40},
+		{name: "unknown accuracy", radius: 20, accuracy: accuracy(0), want: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := &models.Tour{DefaultRadius: tt.defaultRadius}
+			keypoint := models.Keypoint{ID: 1, Radius: tt.radius}
+			if got := completionRadius(tour, keypoint, tt.accuracy); got != tt.want {
+				t.Errorf("completionRadius() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDwellCount(t *testing.T) {
+	counters := []models.DwellCounter{{KeypointID: 3, Count: 2}, {KeypointID: 5, Count: 1}}
+
+	tests := []struct {
+		name       string
+		counters   []models.DwellCounter
+		keypointID int
+		want       int
+	}{
+		{name: "no counters", keypointID: 3, want: 0},
+		{name: "counted keypoint", counters: counters, keypointID: 3, want: 2},
+		{name: "another keypoint", counters: counters, keypointID: 5, want: 1},
+		{name: "keypoint without a counter", counters: counters, keypointID: 4, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dwellCount(tt.counters, tt.keypointID); got != tt.want {
+				t.Errorf("dwellCount(%d) = %d, want %d", tt.keypointID, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"tours-service/internal/models"
+)
+
+const (
+	FormatGPX     = "gpx"
+	FormatKML     = "kml"
+	FormatGeoJSON = "geojson"
+
+	MaxImportSize      = 5 << 20
+	maxImportKeypoints = 500
+	// A track without waypoints is sampled down to this many keypoints, ends included
+	maxTrackKeypoints = 20
+)
+
+// TourFileService converts tours to and from GPX, KML and GeoJSON files.
+type TourFileService struct {
+	TourService *TourService
+}
+
+func NewTourFileService(tourService *TourService) *TourFileService {
+	return &TourFileService{TourService: tourService}
+}
+
+// ImportTour creates a draft tour from a GPX or KML file. Waypoints become keypoints
+// in file order, GPX files without waypoints fall back to their route or track points.
+// A name given by the guide overrides the one in the file.
+func (s *TourFileService) ImportTour(authorID int, format string, data []byte, name string, difficulty models.TourDifficulty) (*models.Tour, error) {
+	if len(data) > MaxImportSize {
+		return nil, fmt.Errorf("invalid file: larger than %d bytes", MaxImportSize)
+	}
+
+	var parsed *importedTour
+	var err error
+	switch format {
+	case FormatGPX:
+		parsed, err = parseGPX(data)
+	case FormatKML:
+		parsed, err = parseKML(data)
+	default:
+		return nil, fmt.Errorf("invalid file: unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parsed.Keypoints) > maxImportKeypoints {
+		return nil, fmt.Errorf("invalid file: more than %d waypoints", maxImportKeypoints)
+	}
+	for i, keypoint := range parsed.Keypoints {
+		if !isFinite(keypoint.Latitude) || !isFinite(keypoint.Longitude) {
+			return nil, fmt.Errorf("invalid file: waypoint %d has coordinates that are not numbers", i+1)
+		}
+		if keypoint.Latitude < -90 || keypoint.Latitude > 90 || keypoint.Longitude < -180 || keypoint.Longitude > 180 {
+			return nil, fmt.Errorf("invalid file: waypoint %d has coordinates out of range", i+1)
+		}
+		if keypoint.Name == "" {
+			keypoint.Name = "Keypoint " + strconv.Itoa(i+1)
+		}
+	}
+
+	if name != "" {
+		parsed.Name = name
+	}
+	if parsed.Name == "" {
+		return nil, errors.New("invalid file: tour name is missing")
+	}
+	if difficulty == "" {
+		difficulty = models.DifficultyEasy
+	}
+
+	tour := &models.Tour{
+		AuthorID:    authorID,
+		Name:        parsed.Name,
+		Description: parsed.Description,
+		Difficulty:  difficulty,
+		Tags:        []string{},
+		Status:      models.StatusDraft,
+	}
+	if err := s.TourService.CreateTour(tour, parsed.Keypoints); err != nil {
+		return nil, err
+	}
+	return tour, nil
+}
+
+// ExportTour renders a tour in the given format and returns the file with its content type.
+// The track follows the stored route geometry of the given transport profile.
+func (s *TourFileService) ExportTour(tour *models.Tour, keypoints []models.Keypoint, format, profile string) ([]byte, string, error) {
+	route := s.TourService.RouteLine(tour, keypoints, profile)
+	switch format {
+	case FormatGPX:
+		data, err := writeGPX(tour, keypoints, route)
+		return data, "application/gpx+xml", err
+	case FormatKML:
+		data, err := writeKML(tour, keypoints, route)
+		return data, "application/vnd.google-earth.kml+xml", err
+	case FormatGeoJSON:
+		data, err := writeGeoJSON(tour, keypoints, route)
+		return data, "application/geo+json", err
+	}
+	return nil, "", fmt.Errorf("unsupported format %q", format)
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+type importedTour struct {
+	Name        string
+	Description string
+	Keypoints   []*models.Keypoint
+}
+
+// --- GPX ---
+
+type gpxFile struct {
+	XMLName  xml.Name   `xml:"gpx"`
+	Version  string     `xml:"version,attr"`
+	Creator  string     `xml:"creator,attr"`
+	Xmlns    string     `xml:"xmlns,attr,omitempty"`
+	Metadata *gpxMeta   `xml:"metadata"`
+	Wpts     []gpxPoint `xml:"wpt"`
+	Rtes     []gpxRoute `xml:"rte"`
+	Trks     []gpxTrack `xml:"trk"`
+}
+
+type gpxMeta struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Name string  `xml:"name,omitempty"`
+	Desc string  `xml:"desc,omitempty"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name,omitempty"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name,omitempty"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+// parseGPX reads waypoints, falling back to the first route when a file has none, then
+// to the first track, sampled down to maxTrackKeypoints points.
+func parseGPX(data []byte) (*importedTour, error) {
+	var file gpxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid file: malformed GPX: %w", err)
+	}
+
+	points := file.Wpts
+	result := &importedTour{}
+	if file.Metadata != nil {
+		result.Name = file.Metadata.Name
+		result.Description = file.Metadata.Desc
+	}
+	if len(points) == 0 && len(file.Rtes) > 0 {
+		points = file.Rtes[0].Points
+		if result.Name == "" {
+			result.Name = file.Rtes[0].Name
+		}
+	}
+	if len(points) == 0 && len(file.Trks) > 0 {
+		for _, segment := range file.Trks[0].Segments {
+			points = append(points, segment.Points...)
+		}
+		points = samplePoints(points, maxTrackKeypoints)
+		if result.Name == "" {
+			result.Name = file.Trks[0].Name
+		}
+	}
+
+	for _, point := range points {
+		result.Keypoints = append(result.Keypoints, &models.Keypoint{
+			Name:        strings.TrimSpace(point.Name),
+			Description: strings.TrimSpace(point.Desc),
+			Latitude:    point.Lat,
+			Longitude:   point.Lon,
+		})
+	}
+	return result, nil
+}
+
+// samplePoints keeps at most limit points, evenly spread and including both ends.
+func samplePoints(points []gpxPoint, limit int) []gpxPoint {
+	if len(points) <= limit {
+		return points
+	}
+	sampled := make([]gpxPoint, 0, limit)
+	for i := 0; i < limit; i++ {
+		sampled = append(sampled, points[i*(len(points)-1)/(limit-1)])
+	}
+	return sampled
+}
+
+func writeGPX(tour *models.Tour, keypoints []models.Keypoint, route [][]float64) ([]byte, error) {
+	file := gpxFile{
+		Version:  "1.1",
+		Creator:  "tours-service",
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		Metadata: &gpxMeta{Name: tour.Name, Desc: tour.Description},
+	}
+
+	var routePoints []gpxPoint
+	for _, keypoint := range keypoints {
+		point := gpxPoint{Lat: keypoint.Latitude, Lon: keypoint.Longitude, Name: keypoint.Name, Desc: keypoint.Description}
+		file.Wpts = append(file.Wpts, point)
+		routePoints = append(routePoints, point)
+	}
+	file.Rtes = []gpxRoute{{Name: tour.Name, Points: routePoints}}
+
+	var track []gpxPoint
+	for _, coordinate := range route {
+		track = append(track, gpxPoint{Lat: coordinate[1], Lon: coordinate[0]})
+	}
+	file.Trks = []gpxTrack{{Name: tour.Name, Segments: []gpxSegment{{Points: track}}}}
+
+	return marshalXML(file)
+}
+
+// --- KML ---
+
+type kmlFile struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr,omitempty"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Placemarks  []kmlPlacemark `xml:"Placemark"`
+	Folders     []kmlFolder    `xml:"Folder"`
+}
+
+type kmlFolder struct {
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Point       *kmlPoint      `xml:"Point"`
+	LineString  *kmlLineString `xml:"LineString"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate  int    `xml:"tessellate,omitempty"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// parseKML reads Point placemarks from the document and its top-level folders.
+func parseKML(data []byte) (*importedTour, error) {
+	var file kmlFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid file: malformed KML: %w", err)
+	}
+
+	result := &importedTour{
+		Name:        strings.TrimSpace(file.Document.Name),
+		Description: strings.TrimSpace(file.Document.Description),
+	}
+
+	placemarks := file.Document.Placemarks
+	for _, folder := range file.Document.Folders {
+		placemarks = append(placemarks, folder.Placemarks...)
+	}
+
+	for _, placemark := range placemarks {
+		if placemark.Point == nil {
+			continue
+		}
+		parts := strings.Split(strings.TrimSpace(placemark.Point.Coordinates), ",")
+		if len(parts) < 2 {
+			return nil, errors.New("invalid file: malformed placemark coordinates")
+		}
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if lonErr != nil || latErr != nil {
+			return nil, errors.New("invalid file: malformed placemark coordinates")
+		}
+		result.Keypoints = append(result.Keypoints, &models.Keypoint{
+			Name:        strings.TrimSpace(placemark.Name),
+			Description: strings.TrimSpace(placemark.Description),
+			Latitude:    lat,
+			Longitude:   lon,
+		})
+	}
+	return result, nil
+}
+
+func writeKML(tour *models.Tour, keypoints []models.Keypoint, route [][]float64) ([]byte, error) {
+	document := kmlDocument{Name: tour.Name, Description: tour.Description}
+
+	for _, keypoint := range keypoints {
+		document.Placemarks = append(document.Placemarks, kmlPlacemark{
+			Name:        keypoint.Name,
+			Description: keypoint.Description,
+			Point:       &kmlPoint{Coordinates: formatKMLCoordinate(keypoint.Longitude, keypoint.Latitude)},
+		})
+	}
+
+	coordinates := make([]string, 0, len(route))
+	for _, coordinate := range route {
+		coordinates = append(coordinates, formatKMLCoordinate(coordinate[0], coordinate[1]))
+	}
+	document.Placemarks = append(document.Placemarks, kmlPlacemark{
+		Name:       tour.Name,
+		LineString: &kmlLineString{Tessellate: 1, Coordinates: strings.Join(coordinates, " ")},
+	})
+
+	return marshalXML(kmlFile{Xmlns: "http://www.opengis.net/kml/2.2", Document: document})
+}
+
+func formatKMLCoordinate(lon, lat float64) string {
+	return strconv.FormatFloat(lon, 'f', -1, 64) + "," + strconv.FormatFloat(lat, 'f', -1, 64)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// --- GeoJSON ---
+
+func writeGeoJSON(tour *models.Tour, keypoints []models.Keypoint, route [][]float64) ([]byte, error) {
+	features := make([]map[string]interface{}, 0, len(keypoints)+1)
+	for _, keypoint := range keypoints {
+		features = append(features, map[string]interface{}{
+			"type": "Feature",
+			"geometry": map[string]interface{}{
+				"type":        "Point",
+				"coordinates": []float64{keypoint.Longitude, keypoint.Latitude},
+			},
+			"properties": map[string]interface{}{
+				"id":          keypoint.ID,
+				"name":        keypoint.Name,
+				"description": keypoint.Description,
+				"ordinal":     keypoint.Ordinal,
+			},
+		})
+	}
+	features = append(features, map[string]interface{}{
+		"type": "Feature",
+		"geometry": map[string]interface{}{
+			"type":        "LineString",
+			"coordinates": route,
+		},
+		"properties": map[string]interface{}{
+			"name": tour.Name,
+		},
+	})
+
+	collection := map[string]interface{}{
+		"type":     "FeatureCollection",
+		"features": features,
+		"properties": map[string]interface{}{
+			"id":          tour.ID,
+			"name":        tour.Name,
+			"description": tour.Description,
+		},
+	}
+	return json.Marshal(collection)
+}
+
+// RouteGeoJSON renders a route as a GeoJSON LineString feature.
+func RouteGeoJSON(tour *models.Tour, profile string, route [][]float64) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type": "Feature",
+		"geometry": map[string]interface{}{
+			"type":        "LineString",
+			"coordinates": route,
+		},
+		"properties": map[string]interface{}{
+			"tourId":  tour.ID,
+			"name":    tour.Name,
+			"profile": profile,
+		},
+	})
+}
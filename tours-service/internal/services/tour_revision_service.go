@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"tours-service/internal/models"
+	"tours-service/internal/repositories"
+)
+
+type TourRevisionService struct {
+	RevisionRepo *repositories.TourRevisionRepository
+	TourRepo     *repositories.TourRepository
+	KeypointRepo *repositories.KeypointRepository
+}
+
+func NewTourRevisionService(revisionRepo *repositories.TourRevisionRepository, tourRepo *repositories.TourRepository, keypointRepo *repositories.KeypointRepository) *TourRevisionService {
+	return &TourRevisionService{
+		RevisionRepo: revisionRepo,
+		TourRepo:     tourRepo,
+		KeypointRepo: keypointRepo,
+	}
+}
+
+// Record snapshots the current state of a tour and stores it with a diff against
+// the previous revision. Nothing is stored if the content didn't actually change.
+func (s *TourRevisionService) Record(tourID, authorID int, reason models.RevisionReason) (*models.TourRevision, error) {
+	snapshot, err := s.currentSnapshot(tourID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.RevisionRepo.GetLatestRevision(tourID)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &models.TourRevision{
+		TourID:    tourID,
+		AuthorID:  authorID,
+		CreatedAt: time.Now(),
+		Reason:    reason,
+		Changes:   []models.RevisionChange{},
+		Snapshot:  snapshot,
+	}
+	if previous != nil {
+		revision.Changes = diffSnapshots(previous.Snapshot, snapshot)
+		if len(revision.Changes) == 0 {
+			return previous, nil
+		}
+	}
+
+	if err := s.RevisionRepo.CreateRevision(revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// RecordOrWarn is used after a change has already been committed, when failing
+// the request because the history couldn't be written would be worse.
+func (s *TourRevisionService) RecordOrWarn(tourID, authorID int, reason models.RevisionReason) {
+	if _, err := s.Record(tourID, authorID, reason); err != nil {
+		fmt.Printf("Warning: Failed to record revision for tour %d: %v\n", tourID, err)
+	}
+}
+
+func (s *TourRevisionService) currentSnapshot(tourID int) (*models.Tour, error) {
+	tour, err := s.TourRepo.GetTourByID(tourID)
+	if err != nil {
+		return nil, err
+	}
+	keypoints, err := s.KeypointRepo.GetKeypointsByTourID(tourID)
+	if err != nil {
+		return nil, err
+	}
+	tour.Keypoints = keypoints
+	tour.Reviews = nil
+	return tour, nil
+}
+
+func (s *TourRevisionService) GetRevisions(tourID int) ([]models.TourRevision, error) {
+	return s.RevisionRepo.GetRevisionsByTourID(tourID)
+}
+
+func (s *TourRevisionService) GetRevision(tourID, number int) (*models.TourRevision, error) {
+	return s.RevisionRepo.GetRevision(tourID, number)
+}
+
+func (s *TourRevisionService) Diff(tourID, from, to int) (*models.RevisionDiff, error) {
+	fromRevision, err := s.RevisionRepo.GetRevision(tourID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.RevisionRepo.GetRevision(tourID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RevisionDiff{
+		TourID:  tourID,
+		From:    from,
+		To:      to,
+		Changes: diffSnapshots(fromRevision.Snapshot, toRevision.Snapshot),
+	}, nil
+}
+
+// Restore brings back the content of an old revision as a new draft, if the tour is
+// still at expectedVersion. A published tour leaves the listing until the guide publishes
+// it again, tourists who bought it keep seeing the revision they are pinned to.
+func (s *TourRevisionService) Restore(tourID, number, authorID, expectedVersion int) (*models.TourRevision, error) {
+	revision, err := s.RevisionRepo.GetRevision(tourID, number)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Snapshot == nil {
+		return nil, errors.New("revision has no snapshot")
+	}
+
+	if _, err := s.TourRepo.RestoreTourContent(tourID, expectedVersion, revision.Snapshot); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.Record(tourID, authorID, models.RevisionRestored)
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// GetRevisionForTourist returns the revision a tourist is pinned to, pinning them to
+// the revision that was current when they bought the tour on first access.
+// It returns nil, nil for tours that have no history yet.
+func (s *TourRevisionService) GetRevisionForTourist(tourID, touristID int, purchasedAt time.Time) (*models.TourRevision, error) {
+	pin, err := s.RevisionRepo.GetPin(tourID, touristID)
+	if err != nil {
+		return nil, err
+	}
+	if pin != nil {
+		return s.RevisionRepo.GetRevision(tourID, pin.Revision)
+	}
+
+	revision, err := s.RevisionRepo.GetRevisionAt(tourID, purchasedAt)
+	if err != nil || revision == nil {
+		return nil, err
+	}
+
+	err = s.RevisionRepo.SetPin(&models.RevisionPin{
+		TourID:    tourID,
+		TouristID: touristID,
+		Revision:  revision.Number,
+		PinnedAt:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// UpgradeTourist moves a tourist's pin to the latest revision of the tour.
+func (s *TourRevisionService) UpgradeTourist(tourID, touristID int) (*models.TourRevision, error) {
+	latest, err := s.RevisionRepo.GetLatestRevision(tourID)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, errors.New("revision not found")
+	}
+
+	err = s.RevisionRepo.SetPin(&models.RevisionPin{
+		TourID:    tourID,
+		TouristID: touristID,
+		Revision:  latest.Number,
+		PinnedAt:  time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+func (s *TourRevisionService) GetLatestRevision(tourID int) (*models.TourRevision, error) {
+	return s.RevisionRepo.GetLatestRevision(tourID)
+}
+
+// diffSnapshots compares the guide-visible content of two snapshots. Versions and
+// timestamps are left out since they change on every write.
+func diffSnapshots(from, to *models.Tour) []models.RevisionChange {
+	changes := []models.RevisionChange{}
+	if from == nil {
+		from = &models.Tour{}
+	}
+	if to == nil {
+		to = &models.Tour{}
+	}
+
+	addChange := func(field string, old, new interface{}) {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, models.RevisionChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	addChange("name", from.Name, to.Name)
+	addChange("description", from.Description, to.Description)
+	addChange("language", from.Language, to.Language)
+	addChange("translations", from.Translations, to.Translations)
+	addChange("difficulty", from.Difficulty, to.Difficulty)
+	addChange("tags", from.Tags, to.Tags)
+	addChange("completionMode", from.CompletionMode, to.CompletionMode)
+	addChange("defaultRadius", from.DefaultRadius, to.DefaultRadius)
+	addChange("status", from.Status, to.Status)
+	addChange("price", from.Price, to.Price)
+	addChange("drivingStats", from.DrivingStats, to.DrivingStats)
+	addChange("walkingStats", from.WalkingStats, to.WalkingStats)
+	addChange("cyclingStats", from.CyclingStats, to.CyclingStats)
+
+	oldKeypoints := make(map[int]models.Keypoint, len(from.Keypoints))
+	for _, keypoint := range from.Keypoints {
+		oldKeypoints[keypoint.ID] = keypoint
+	}
+	newKeypoints := make(map[int]models.Keypoint, len(to.Keypoints))
+	for _, keypoint := range to.Keypoints {
+		newKeypoints[keypoint.ID] = keypoint
+	}
+
+	ids := make([]int, 0, len(oldKeypoints)+len(newKeypoints))
+	for id := range oldKeypoints {
+		ids = append(ids, id)
+	}
+	for id := range newKeypoints {
+		if _, ok := oldKeypoints[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		prefix := fmt.Sprintf("keypoints[%d]", id)
+		oldKeypoint, hadOld := oldKeypoints[id]
+		newKeypoint, hasNew := newKeypoints[id]
+		switch {
+		case !hadOld:
+			changes = append(changes, models.RevisionChange{Field: prefix, New: newKeypoint})
+		case !hasNew:
+			changes = append(changes, models.RevisionChange{Field: prefix, Old: oldKeypoint})
+		default:
+			addChange(prefix+".name", oldKeypoint.Name, newKeypoint.Name)
+			addChange(prefix+".description", oldKeypoint.Description, newKeypoint.Description)
+			addChange(prefix+".imageUrl", oldKeypoint.ImageURL, newKeypoint.ImageURL)
+			addChange(prefix+".latitude", oldKeypoint.Latitude, newKeypoint.Latitude)
+			addChange(prefix+".longitude", oldKeypoint.Longitude, newKeypoint.Longitude)
+			addChange(prefix+".ordinal", oldKeypoint.Ordinal, newKeypoint.Ordinal)
+			addChange(prefix+".translations", oldKeypoint.Translations, newKeypoint.Translations)
+			addChange(prefix+".media", oldKeypoint.Media, newKeypoint.Media)
+			addChange(prefix+".challenge", oldKeypoint.Challenge, newKeypoint.Challenge)
+		}
+	}
+
+	return changes
+}
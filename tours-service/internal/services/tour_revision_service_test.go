@@ -0,0 +1,124 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tours-service/internal/models"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	published := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	base := func() *models.Tour {
+		return &models.Tour{
+			ID:          1,
+			Name:        "Old town",
+			Description: "A walk",
+			Difficulty:  models.DifficultyEasy,
+			Tags:        []string{"history"},
+			Status:      models.StatusDraft,
+			Price:       10,
+			Version:     3,
+			Keypoints: []models.Keypoint{
+				{ID: 1, Name: "Gate", Latitude: 45.25, Longitude: 19.84, Ordinal: 1},
+				{ID: 2, Name: "Square", Latitude: 45.26, Longitude: 19.85, Ordinal: 2},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		from   *models.Tour
+		change func(tour *models.Tour)
+		want   []models.RevisionChange
+	}{
+		{
+			name:   "identical snapshots",
+			from:   base(),
+			change: func(tour *models.Tour) {},
+			want:   []models.RevisionChange{},
+		},
+		{
+			name: "versions and timestamps are ignored",
+			from: base(),
+			change: func(tour *models.Tour) {
+				tour.Version = 9
+				tour.TimePublished = &published
+				tour.Keypoints[0].Version = 4
+			},
+			want: []models.RevisionChange{},
+		},
+		{
+			name: "tour fields",
+			from: base(),
+			change: func(tour *models.Tour) {
+				tour.Name = "Old town at night"
+				tour.Tags = []string{"history", "night"}
+				tour.Status = models.StatusPublished
+				tour.Price = 12
+			},
+			want: []models.RevisionChange{
+				{Field: "name", Old: "Old town", New: "Old town at night"},
+				{Field: "tags", Old: []string{"history"}, New: []string{"history", "night"}},
+				{Field: "status", Old: models.StatusDraft, New: models.StatusPublished},
+				{Field: "price", Old: 10.0, New: 12.0},
+			},
+		},
+		{
+			name: "keypoint fields",
+			from: base(),
+			change: func(tour *models.Tour) {
+				tour.Keypoints[1].Name = "Main square"
+				tour.Keypoints[1].Latitude = 45.2551
+			},
+			want: []models.RevisionChange{
+				{Field: "keypoints[2].name", Old: "Square", New: "Main square"},
+				{Field: "keypoints[2].latitude", Old: 45.26, New: 45.2551},
+			},
+		},
+		{
+			name: "added and removed keypoints by ID",
+			from: base(),
+			change: func(tour *models.Tour) {
+				tour.Keypoints = []models.Keypoint{
+					tour.Keypoints[1],
+					{ID: 3, Name: "Bridge", Ordinal: 2},
+				}
+				tour.Keypoints[0].Ordinal = 1
+			},
+			want: []models.RevisionChange{
+				{Field: "keypoints[1]", Old: models.Keypoint{ID: 1, Name: "Gate", Latitude: 45.25, Longitude: 19.84, Ordinal: 1}},
+				{Field: "keypoints[2].ordinal", Old: 2, New: 1},
+				{Field: "keypoints[3]", New: models.Keypoint{ID: 3, Name: "Bridge", Ordinal: 2}},
+			},
+		},
+		{
+			name: "first revision diffs against an empty tour",
+			from: nil,
+			change: func(tour *models.Tour) {
+				tour.Keypoints = tour.Keypoints[:1]
+				tour.Tags = nil
+				tour.Description = ""
+				tour.Difficulty = ""
+				tour.Status = ""
+				tour.Price = 0
+			},
+			want: []models.RevisionChange{
+				{Field: "name", Old: "", New: "Old town"},
+				{Field: "keypoints[1]", New: models.Keypoint{ID: 1, Name: "Gate", Latitude: 45.25, Longitude: 19.84, Ordinal: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to := base()
+			tt.change(to)
+			got := diffSnapshots(tt.from, to)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSnapshots() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,21 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 	"tours-service/internal/models"
 	"tours-service/internal/repositories"
 )
 
 type TourService struct {
-	TourRepo     *repositories.TourRepository
-	KeypointRepo *repositories.KeypointRepository
-	MapService   *MapService
+	TourRepo        *repositories.TourRepository
+	KeypointRepo    *repositories.KeypointRepository
+	MapService      *MapService
+	RevisionService *TourRevisionService
 }
 
-func NewTourService(tourRepo *repositories.TourRepository, keypointRepo *repositories.KeypointRepository, mapService *MapService) *TourService {
+func NewTourService(tourRepo *repositories.TourRepository, keypointRepo *repositories.KeypointRepository, mapService *MapService, revisionService *TourRevisionService) *TourService {
 	return &TourService{
-		TourRepo:     tourRepo,
-		KeypointRepo: keypointRepo,
-		MapService:   mapService,
+		TourRepo:        tourRepo,
+		KeypointRepo:    keypointRepo,
+		MapService:      mapService,
+		RevisionService: revisionService,
 	}
 }
 
@@ -28,41 +31,30 @@ func (s *TourService) CreateTour(tour *models.Tour, keypoints []*models.Keypoint
 		return errors.New("a tour must have at least two keypoints")
 	}
 
-	totalStats := map[string]models.DistanceAndDuration{
-		"driving-car":     {},
-		"foot-walking":    {},
-		"cycling-regular": {},
+	ordered := make([]models.Keypoint, 0, len(keypoints))
+	for _, keypoint := range keypoints {
+		ordered = append(ordered, *keypoint)
 	}
 
-	for i := 0; i < len(keypoints)-1; i++ {
-		origin := *keypoints[i]
-		dest := *keypoints[i+1]
-
-		segmentStats, err := s.MapService.GetDistanceBetweenTwoKeypoints(context.Background(), origin, dest)
-		if err != nil {
-			fmt.Printf("Warning: Failed to get segment distance for tour creation: %v\n", err)
-			break
-		}
-
-		for profile, stats := range segmentStats {
-			currentStats := totalStats[profile]
-			currentStats.Distance += stats.Distance
-			currentStats.Duration += stats.Duration
-			totalStats[profile] = currentStats
-		}
+	// Segments reference keypoint IDs, which only exist once the keypoints are stored
+	totalStats, legs, err := s.computeRoute(context.Background(), ordered)
+	if err != nil {
+		fmt.Printf("Warning: Map-service unavailable, estimating length of new tour: %v\n", err)
 	}
 
-	tour.DrivingStats = totalStats["driving-car"]
-	tour.WalkingStats = totalStats["foot-walking"]
-	tour.CyclingStats = totalStats["cycling-regular"]
+	tour.DrivingStats = totalStats[models.ProfileDriving]
+	tour.WalkingStats = totalStats[models.ProfileWalking]
+	tour.CyclingStats = totalStats[models.ProfileCycling]
 
-	err := s.TourRepo.CreateTour(tour)
+	err = s.TourRepo.CreateTour(tour)
 	if err != nil {
 		return fmt.Errorf("failed to create tour: %w", err)
 	}
 
-	for _, keypoint := range keypoints {
+	// List order is the route order, ordinals sent by the client are ignored
+	for i, keypoint := range keypoints {
 		keypoint.TourID = tour.ID
+		keypoint.Ordinal = i + 1
 	}
 
 	for _, keypoint := range keypoints {
@@ -74,16 +66,77 @@ func (s *TourService) CreateTour(tour *models.Tour, keypoints []*models.Keypoint
 		}
 	}
 
+	if len(legs) > 0 {
+		segments := buildSegments(ordered, legs, keypoints)
+		if err := s.TourRepo.UpdateTourLength(tour.ID, tour.DrivingStats, tour.WalkingStats, tour.CyclingStats, segments); err != nil {
+			fmt.Printf("Warning: Failed to store route geometry for tour %d: %v\n", tour.ID, err)
+		} else {
+			tour.RouteSegments = segments
+		}
+	}
+
+	s.RevisionService.RecordOrWarn(tour.ID, tour.AuthorID, models.RevisionTourCreated)
+
 	return nil
 }
 
-func (s *TourService) DeleteTour(tourID int) error {
-	err := s.KeypointRepo.DeleteKeypointsByTourID(tourID)
+// CloneTour copies a tour and its keypoints into a new draft of the same author. Images
+// and media are shared by URL. Price, reviews, ratings and executions start over, route
+// stats are computed anew. An empty name becomes the source name with a " (copy)" suffix.
+func (s *TourService) CloneTour(sourceID int, name string) (*models.Tour, error) {
+	source, err := s.TourRepo.GetTourByID(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get tour to clone: %w", err)
+	}
+	sourceKeypoints, err := s.KeypointRepo.GetKeypointsByTourID(sourceID)
 	if err != nil {
-		return fmt.Errorf("service failed to delete keypoints for tour %d: %w", tourID, err)
+		return nil, fmt.Errorf("service failed to get keypoints to clone: %w", err)
 	}
 
-	err = s.TourRepo.DeleteTour(tourID)
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+	tour := &models.Tour{
+		AuthorID:       source.AuthorID,
+		Name:           name,
+		Description:    source.Description,
+		Language:       source.Language,
+		Translations:   source.Translations,
+		Difficulty:     source.Difficulty,
+		Tags:           append([]string{}, source.Tags...),
+		CompletionMode: source.CompletionMode,
+		DefaultRadius:  source.DefaultRadius,
+		ClonedFrom:     source.ID,
+	}
+
+	keypoints := make([]*models.Keypoint, 0, len(sourceKeypoints))
+	for _, keypoint := range sourceKeypoints {
+		keypoints = append(keypoints, &models.Keypoint{
+			Name:         keypoint.Name,
+			Description:  keypoint.Description,
+			ImageURL:     keypoint.ImageURL,
+			Latitude:     keypoint.Latitude,
+			Longitude:    keypoint.Longitude,
+			Radius:       keypoint.Radius,
+			Translations: keypoint.Translations,
+			Media:        keypoint.Media,
+			Challenge:    keypoint.Challenge,
+		})
+	}
+
+	if err := s.CreateTour(tour, keypoints); err != nil {
+		return nil, err
+	}
+	for _, keypoint := range keypoints {
+		tour.Keypoints = append(tour.Keypoints, *keypoint)
+	}
+	return tour, nil
+}
+
+// DeleteTour soft deletes a tour, PurgeDeletedTours removes it for good later. Callers
+// make sure nobody purchased it.
+func (s *TourService) DeleteTour(tourID int) error {
+	err := s.TourRepo.SoftDeleteTour(tourID)
 	if err != nil {
 		return fmt.Errorf("service failed to delete tour %d: %w", tourID, err)
 	}
@@ -91,8 +144,51 @@ func (s *TourService) DeleteTour(tourID int) error {
 	return nil
 }
 
-func (s *TourService) GetPublishedToursWithFirstKeypoint() ([]models.TourWithFirstKeypoint, error) {
-	tours, err := s.TourRepo.GetPublishedTours()
+func (s *TourService) GetDeletedTourByID(tourID int) (*models.Tour, error) {
+	tour, err := s.TourRepo.GetDeletedTourByID(tourID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get deleted tour: %w", err)
+	}
+	return tour, nil
+}
+
+func (s *TourService) GetDeletedToursByAuthorID(authorID int) ([]models.Tour, error) {
+	tours, err := s.TourRepo.GetDeletedToursByAuthorID(authorID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get deleted tours: %w", err)
+	}
+	return tours, nil
+}
+
+func (s *TourService) RestoreDeletedTour(tourID int) (*models.Tour, error) {
+	tour, err := s.TourRepo.RestoreDeletedTour(tourID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to restore tour %d: %w", tourID, err)
+	}
+	return tour, nil
+}
+
+// PurgeDeletedTours removes tours soft deleted longer than retention ago, with their keypoints.
+func (s *TourService) PurgeDeletedTours(ctx context.Context, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+	tourIDs, err := s.TourRepo.GetTourIDsDeletedBefore(cutoff)
+	if err != nil {
+		return err
+	}
+
+	for _, tourID := range tourIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := s.TourRepo.PurgeTour(tourID, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TourService) GetPublishedToursWithFirstKeypoint(query models.TourListQuery) ([]models.TourWithFirstKeypoint, error) {
+	tours, err := s.TourRepo.GetPublishedTours(query)
 	if err != nil {
 		return nil, fmt.Errorf("service failed to get published tours: %w", err)
 	}
@@ -132,14 +228,16 @@ func (s *TourService) GetTourByID(tourID int) (*models.Tour, error) {
 	return tour, nil
 }
 
-func (s *TourService) UpdateTour(tour *models.Tour) error {
-	err := s.TourRepo.UpdateTour(tour)
+func (s *TourService) UpdateTour(tourID, expectedVersion int, update *models.TourUpdateRequest) (*models.Tour, error) {
+	tour, err := s.TourRepo.UpdateTour(tourID, expectedVersion, update)
 	if err != nil {
-		return fmt.Errorf("service failed to update tour: %w", err)
+		return nil, fmt.Errorf("service failed to update tour: %w", err)
 	}
-	return nil
+	s.RevisionService.RecordOrWarn(tourID, tour.AuthorID, models.RevisionTourUpdated)
+	return tour, nil
 }
 
+// RecalculateTourLength refreshes the stats and route geometry of a tour from its keypoints.
 func (s *TourService) RecalculateTourLength(ctx context.Context, tourID int) error {
 	keypoints, err := s.KeypointRepo.GetKeypointsByTourID(tourID)
 	if err != nil {
@@ -150,58 +248,258 @@ func (s *TourService) RecalculateTourLength(ctx context.Context, tourID int) err
 		return s.TourRepo.UpdateTourLength(tourID,
 			models.DistanceAndDuration{},
 			models.DistanceAndDuration{},
-			models.DistanceAndDuration{})
+			models.DistanceAndDuration{},
+			[]models.RouteSegment{})
 	}
 
 	sort.Slice(keypoints, func(i, j int) bool {
 		return keypoints[i].Ordinal < keypoints[j].Ordinal
 	})
 
-	var drivingTotal, walkingTotal, cyclingTotal models.DistanceAndDuration
+	totals, legs, err := s.computeRoute(ctx, keypoints)
+	if err != nil {
+		fmt.Printf("Warning: Map-service unavailable, estimating length of tour %d: %v\n", tourID, err)
+	}
+
+	return s.TourRepo.UpdateTourLength(tourID,
+		totals[models.ProfileDriving],
+		totals[models.ProfileWalking],
+		totals[models.ProfileCycling],
+		buildSegments(keypoints, legs, nil))
+}
 
-	for i := 0; i < len(keypoints)-1; i++ {
-		distMap, err := s.MapService.GetDistanceBetweenTwoKeypoints(ctx, keypoints[i], keypoints[i+1])
-		if err != nil {
-			return err
+// computeRoute sums the route legs of consecutive keypoints per profile. Legs come from
+// the distance cache or from a single batched map-service request. Legs map-service
+// could not resolve are estimated, so the totals are always complete; the error only
+// reports that the fallback was used.
+func (s *TourService) computeRoute(ctx context.Context, keypoints []models.Keypoint) (map[string]models.DistanceAndDuration, []map[string]models.RouteLeg, error) {
+	totals := map[string]models.DistanceAndDuration{
+		models.ProfileDriving: {Source: models.StatsRouted},
+		models.ProfileWalking: {Source: models.StatsRouted},
+		models.ProfileCycling: {Source: models.StatsRouted},
+	}
+
+	legs, err := s.MapService.GetRouteLegs(ctx, keypoints)
+	if legs == nil {
+		legs = make([]map[string]models.RouteLeg, len(keypoints)-1)
+	}
+
+	for i, segment := range legs {
+		if segment == nil {
+			segment = estimateLeg(keypoints[i], keypoints[i+1])
+			legs[i] = segment
+		}
+		for profile, leg := range segment {
+			current := totals[profile]
+			current.Distance += leg.Distance
+			current.Duration += leg.Duration
+			if leg.Estimated {
+				current.Source = models.StatsEstimated
+			}
+			totals[profile] = current
 		}
+	}
+
+	return totals, legs, err
+}
+
+// buildSegments pairs route legs with the keypoints they connect. created, when given,
+// holds the stored keypoints whose IDs were assigned after the legs were computed.
+func buildSegments(keypoints []models.Keypoint, legs []map[string]models.RouteLeg, created []*models.Keypoint) []models.RouteSegment {
+	id := func(i int) int {
+		if created != nil {
+			return created[i].ID
+		}
+		return keypoints[i].ID
+	}
 
-		if stats, ok := distMap["driving-car"]; ok {
-			drivingTotal.Distance += stats.Distance
-			drivingTotal.Duration += stats.Duration
+	segments := make([]models.RouteSegment, 0, len(legs))
+	for i, leg := range legs {
+		polylines := make(map[string]string, len(leg))
+		for profile, route := range leg {
+			if route.Geometry != "" {
+				polylines[profile] = route.Geometry
+			}
 		}
-		if stats, ok := distMap["foot-walking"]; ok {
-			walkingTotal.Distance += stats.Distance
-			walkingTotal.Duration += stats.Duration
+		segment := models.RouteSegment{
+			FromKeypointID: id(i),
+			ToKeypointID:   id(i + 1),
+			Polylines:      polylines,
 		}
-		if stats, ok := distMap["cycling-regular"]; ok {
-			cyclingTotal.Distance += stats.Distance
-			cyclingTotal.Duration += stats.Duration
+		for _, route := range leg {
+			segment.Estimated = segment.Estimated || route.Estimated
 		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// RouteLine returns the route of a tour for one profile as [lon, lat] pairs. Segments
+// without stored geometry fall back to a straight line between their keypoints.
+func (s *TourService) RouteLine(tour *models.Tour, keypoints []models.Keypoint, profile string) [][]float64 {
+	geometry := make(map[[2]int]string, len(tour.RouteSegments))
+	for _, segment := range tour.RouteSegments {
+		geometry[[2]int{segment.FromKeypointID, segment.ToKeypointID}] = segment.Polylines[profile]
 	}
 
-	return s.TourRepo.UpdateTourLength(tourID, drivingTotal, walkingTotal, cyclingTotal)
+	var line [][]float64
+	for i, keypoint := range keypoints {
+		start := []float64{keypoint.Longitude, keypoint.Latitude}
+		if i == 0 {
+			line = append(line, start)
+		}
+		if i == len(keypoints)-1 {
+			break
+		}
+		next := keypoints[i+1]
+		if encoded := geometry[[2]int{keypoint.ID, next.ID}]; encoded != "" {
+			if points, err := decodePolyline(encoded); err == nil && len(points) > 0 {
+				line = append(line, points[1:]...)
+				continue
+			}
+		}
+		line = append(line, []float64{next.Longitude, next.Latitude})
+	}
+	return line
 }
 
-func (s *TourService) PublishTour(tourID int) error {
+// RouteForProfile returns the whole route and each segment of it as encoded polylines.
+func (s *TourService) RouteForProfile(tour *models.Tour, keypoints []models.Keypoint, profile string) *models.RouteResponse {
+	response := &models.RouteResponse{
+		TourID:   tour.ID,
+		Profile:  profile,
+		Polyline: encodePolyline(s.RouteLine(tour, keypoints, profile)),
+		Segments: make([]models.RouteSegmentPolyline, 0, len(keypoints)),
+	}
+	for i := 0; i < len(keypoints)-1; i++ {
+		response.Segments = append(response.Segments, models.RouteSegmentPolyline{
+			FromKeypointID: keypoints[i].ID,
+			ToKeypointID:   keypoints[i+1].ID,
+			Polyline:       encodePolyline(s.RouteLine(tour, keypoints[i:i+2], profile)),
+		})
+	}
+	return response
+}
+
+// EstimatedStatsTourIDs lists tours whose stats were estimated while map-service was
+// down. It returns nothing until map-service is reachable again, so the recalculations
+// queued for them are not spent against an unavailable service.
+func (s *TourService) EstimatedStatsTourIDs(ctx context.Context) ([]int, error) {
+	tourIDs, err := s.TourRepo.GetTourIDsWithEstimatedStats()
+	if err != nil {
+		return nil, err
+	}
+	if len(tourIDs) == 0 || !s.MapService.Healthy(ctx) {
+		return nil, nil
+	}
+	return tourIDs, nil
+}
+
+func IsRouteProfile(profile string) bool {
+	for _, known := range routeProfiles {
+		if profile == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *TourService) PublishTour(tourID, authorID int) error {
 	err := s.TourRepo.PublishTour(tourID)
 	if err != nil {
 		return fmt.Errorf("service failed to publish tour: %w", err)
 	}
+	s.RevisionService.RecordOrWarn(tourID, authorID, models.RevisionStatusChanged)
+	return nil
+}
+
+// ErrInvalidSchedule wraps every rejected publish or archive schedule.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ScheduleTour replaces the automatic publish and archive times of a tour.
+func (s *TourService) ScheduleTour(tour *models.Tour, publishAt, archiveAt *time.Time) (*models.Tour, error) {
+	if err := validateSchedule(tour, publishAt, archiveAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.TourRepo.SetSchedule(tour.ID, publishAt, archiveAt)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to schedule tour: %w", err)
+	}
+	return updated, nil
+}
+
+// validateSchedule checks publish and archive times against the tour. Both must lie in
+// the future, the archive time after the publish time, and an archive time needs a tour
+// that is published or scheduled to be.
+func validateSchedule(tour *models.Tour, publishAt, archiveAt *time.Time, now time.Time) error {
+	if publishAt != nil {
+		if tour.Status == models.StatusPublished {
+			return fmt.Errorf("%w: tour is already published, only an archive time can be set", ErrInvalidSchedule)
+		}
+		if !publishAt.After(now) {
+			return fmt.Errorf("%w: publish time must be in the future", ErrInvalidSchedule)
+		}
+	}
+	if archiveAt != nil {
+		if !archiveAt.After(now) {
+			return fmt.Errorf("%w: archive time must be in the future", ErrInvalidSchedule)
+		}
+		if publishAt != nil && !archiveAt.After(*publishAt) {
+			return fmt.Errorf("%w: archive time must be after the publish time", ErrInvalidSchedule)
+		}
+		if publishAt == nil && tour.Status != models.StatusPublished {
+			return fmt.Errorf("%w: an archive time needs a published tour or a publish time", ErrInvalidSchedule)
+		}
+	}
+	return nil
+}
+
+// ApplyTourSchedules carries out the publish and archive times that have passed. Runs
+// of the periodic job are idempotent, so nothing is lost across restarts.
+func (s *TourService) ApplyTourSchedules(ctx context.Context) error {
+	now := time.Now()
+	tours, err := s.TourRepo.GetToursWithDueSchedule(now)
+	if err != nil {
+		return err
+	}
+
+	for _, tour := range tours {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		published, err := s.TourRepo.PublishScheduledTour(tour.ID, now)
+		if err != nil {
+			return err
+		}
+		if published {
+			s.RevisionService.RecordOrWarn(tour.ID, tour.AuthorID, models.RevisionStatusChanged)
+		}
+		archived, err := s.TourRepo.ArchiveScheduledTour(tour.ID, now)
+		if err != nil {
+			return err
+		}
+		if archived {
+			s.RevisionService.RecordOrWarn(tour.ID, tour.AuthorID, models.RevisionStatusChanged)
+		}
+	}
 	return nil
 }
 
-func (s *TourService) ArchiveTour(tourID int) error {
+func (s *TourService) ArchiveTour(tourID, authorID int) error {
 	err := s.TourRepo.ArchiveTour(tourID)
 	if err != nil {
 		return fmt.Errorf("service failed to archive tour: %w", err)
 	}
+	s.RevisionService.RecordOrWarn(tourID, authorID, models.RevisionStatusChanged)
 	return nil
 }
 
-func (s *TourService) SetTourPrice(tourID int, newPrice float64, authorID int) error {
-	err := s.TourRepo.SetTourPrice(tourID, newPrice, authorID)
+func (s *TourService) SetTourPrice(tourID int, newPrice float64, authorID int, expectedVersion *int) error {
+	err := s.TourRepo.SetTourPrice(tourID, newPrice, authorID, expectedVersion)
 	if err != nil {
 		return fmt.Errorf("service failed to set tour price: %w", err)
 	}
+	s.RevisionService.RecordOrWarn(tourID, authorID, models.RevisionPriceChanged)
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"tours-service/internal/models"
+)
+
+func TestBuildSegments(t *testing.T) {
+	keypoints := []models.Keypoint{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	tests := []struct {
+		name    string
+		legs    []map[string]models.RouteLeg
+		created []*models.Keypoint
+		want    []models.RouteSegment
+	}{
+		{
+			name: "no legs",
+			want: []models.RouteSegment{},
+		},
+		{
+			name: "one segment per leg",
+			legs: []map[string]models.RouteLeg{
+				{models.ProfileWalking: {Geometry: "_p~iF~ps|U"}, models.ProfileDriving: {Geometry: "??"}},
+				{models.ProfileWalking: {Geometry: "_ulLnnqC"}},
+			},
+			want: []models.RouteSegment{
+				{FromKeypointID: 1, ToKeypointID: 2, Polylines: map[string]string{models.ProfileWalking: "_p~iF~ps|U", models.ProfileDriving: "??"}},
+				{FromKeypointID: 2, ToKeypointID: 3, Polylines: map[string]string{models.ProfileWalking: "_ulLnnqC"}},
+			},
+		},
+		{
+			name: "legs without geometry are left out",
+			legs: []map[string]models.RouteLeg{
+				{models.ProfileWalking: {Distance: 100}, models.ProfileCycling: {Geometry: "_p~iF~ps|U"}},
+			},
+			want: []models.RouteSegment{
+				{FromKeypointID: 1, ToKeypointID: 2, Polylines: map[string]string{models.ProfileCycling: "_p~iF~ps|U"}},
+			},
+		},
+		{
+			name: "IDs of keypoints created with the tour",
+			legs: []map[string]models.RouteLeg{
+				{models.ProfileWalking: {Geometry: "??"}},
+				{models.ProfileWalking: {Geometry: "??"}},
+			},
+			created: []*models.Keypoint{{ID: 41}, {ID: 42}, {ID: 43}},
+			want: []models.RouteSegment{
+				{FromKeypointID: 41, ToKeypointID: 42, Polylines: map[string]string{models.ProfileWalking: "??"}},
+				{FromKeypointID: 42, ToKeypointID: 43, Polylines: map[string]string{models.ProfileWalking: "??"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildSegments(keypoints, tt.legs, tt.created)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSegments() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteLine(t *testing.T) {
+	keypoints := []models.Keypoint{
+		{ID: 1, Longitude: -120.2, Latitude: 38.5},
+		{ID: 2, Longitude: -120.95, Latitude: 40.7},
+		{ID: 3, Longitude: -126.453, Latitude: 43.252},
+	}
+
+	tests := []struct {
+		name      string
+		segments  []models.RouteSegment
+		keypoints []models.Keypoint
+		want      [][]float64
+	}{
+		{
+			name:      "no keypoints",
+			keypoints: nil,
+			want:      nil,
+		},
+		{
+			name:      "straight lines without stored geometry",
+			keypoints: keypoints,
+			want:      [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name: "stored geometry replaces the straight line",
+			segments: []models.RouteSegment{
+				// Passes through (-121, 39) on the way from 1 to 2
+				{FromKeypointID: 1, ToKeypointID: 2, Polylines: map[string]string{models.ProfileWalking: encodePolyline([][]float64{{-120.2, 38.5}, {-121, 39}, {-120.95, 40.7}})}},
+			},
+			keypoints: keypoints,
+			want:      [][]float64{{-120.2, 38.5}, {-121, 39}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name: "geometry of another profile is not used",
+			segments: []models.RouteSegment{
+				{FromKeypointID: 1, ToKeypointID: 2, Polylines: map[string]string{models.ProfileDriving: encodePolyline([][]float64{{-120.2, 38.5}, {-121, 39}, {-120.95, 40.7}})}},
+			},
+			keypoints: keypoints,
+			want:      [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name: "malformed geometry falls back to a straight line",
+			segments: []models.RouteSegment{
+				{FromKeypointID: 2, ToKeypointID: 3, Polylines: map[string]string{models.ProfileWalking: "_p~iF"}},
+			},
+			keypoints: keypoints,
+			want:      [][]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}},
+		},
+		{
+			name: "segments of a previous order are not used",
+			segments: []models.RouteSegment{
+				{FromKeypointID: 2, ToKeypointID: 1, Polylines: map[string]string{models.ProfileWalking: encodePolyline([][]float64{{-120.95, 40.7}, {-121, 39}, {-120.2, 38.5}})}},
+			},
+			keypoints: keypoints[:2],
+			want:      [][]float64{{-120.2, 38.5}, {-120.95, 40.7}},
+		},
+	}
+
+	s := &TourService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := &models.Tour{RouteSegments: tt.segments}
+			got := s.RouteLine(tour, tt.keypoints, models.ProfileWalking)
+			if len(got) != len(tt.want) {
+				t.Fatalf("RouteLine() = %v, want %v", got, tt.want)
+			}
+			assertCoordinates(t, got, tt.want)
+		})
+	}
+}
+
+func TestEstimateLeg(t *testing.T) {
+	// One degree along the equator, 111194.93 m, lengthened by the detour factor
+	const distance = 111194.93 * detourFactor
+
+	leg := estimateLeg(models.Keypoint{Latitude: 0, Longitude: 0}, models.Keypoint{Latitude: 0, Longitude: 1})
+
+	tests := []struct {
+		profile  string
+		duration float64
+	}{
+		{profile: models.ProfileDriving, duration: distance / (50 / 3.6)},
+		{profile: models.ProfileWalking, duration: distance / (5 / 3.6)},
+		{profile: models.ProfileCycling, duration: distance / (15 / 3.6)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			got, ok := leg[tt.profile]
+			if !ok {
+				t.Fatalf("estimateLeg() has no %s leg", tt.profile)
+			}
+			if math.Abs(got.Distance-distance) > 0.1 || math.Abs(got.Duration-tt.duration) > 0.1 {
+				t.Errorf("estimateLeg() = %.2f m in %.2f s, want %.2f m in %.2f s", got.Distance, got.Duration, distance, tt.duration)
+			}
+			if !got.Estimated || got.Geometry != "" {
+				t.Errorf("estimateLeg() = %+v, want an estimated leg without geometry", got)
+			}
+		})
+	}
+}
+
+func TestComputeRoute(t *testing.T) {
+	// Keypoints one degree apart along the equator. fakeMapService routes a leg as long
+	// as the longitude of its origin, taking a second.
+	keypoints := []models.Keypoint{
+		{ID: 1, Latitude: 0, Longitude: 0},
+		{ID: 2, Latitude: 0, Longitude: 1},
+		{ID: 3, Latitude: 0, Longitude: 2},
+	}
+	estimated := estimateLeg(keypoints[0], keypoints[1])
+
+	tests := []struct {
+		name          string
+		status        int
+		cachedFirst   bool // first leg cached with 5 m in 1 s
+		wantErr       bool
+		wantDistance  map[string]float64
+		wantSource    string
+		wantEstimated []bool
+	}{
+		{
+			name:          "routed by map-service",
+			wantDistance:  map[string]float64{models.ProfileDriving: 1, models.ProfileWalking: 1, models.ProfileCycling: 1},
+			wantSource:    models.StatsRouted,
+			wantEstimated: []bool{false, false},
+		},
+		{
+			name:    "estimated while map-service is down",
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+			wantDistance: map[string]float64{
+				models.ProfileDriving: 2 * estimated[models.ProfileDriving].Distance,
+				models.ProfileWalking: 2 * estimated[models.ProfileWalking].Distance,
+				models.ProfileCycling: 2 * estimated[models.ProfileCycling].Distance,
+			},
+			wantSource:    models.StatsEstimated,
+			wantEstimated: []bool{true, true},
+		},
+		{
+			name:        "cached legs are kept, only the rest is estimated",
+			status:      http.StatusServiceUnavailable,
+			cachedFirst: true,
+			wantErr:     true,
+			wantDistance: map[string]float64{
+				models.ProfileDriving: 5 + estimated[models.ProfileDriving].Distance,
+				models.ProfileWalking: 5 + estimated[models.ProfileWalking].Distance,
+				models.ProfileCycling: 5 + estimated[models.ProfileCycling].Distance,
+			},
+			wantSource:    models.StatsEstimated,
+			wantEstimated: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&fakeMapService{status: tt.status})
+			defer server.Close()
+
+			cache := NewDistanceCache(100, nil)
+			if tt.cachedFirst {
+				var entries []models.DistanceCacheEntry
+				for _, profile := range routeProfiles {
+					entries = append(entries, models.DistanceCacheEntry{
+						Key:      distanceCacheKey(profile, keypoints[0], keypoints[1]),
+						Profile:  profile,
+						Distance: 5,
+						Duration: 1,
+					})
+				}
+				cache.Put(entries)
+			}
+
+			s := &TourService{MapService: NewMapService(server.URL, cache)}
+			totals, legs, err := s.computeRoute(context.Background(), keypoints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("computeRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for profile, want := range tt.wantDistance {
+				got := totals[profile]
+				if math.Abs(got.Distance-want) > 0.01 {
+					t.Errorf("%s distance = %.2f, want %.2f", profile, got.Distance, want)
+				}
+				if got.Source != tt.wantSource {
+					t.Errorf("%s source = %q, want %q", profile, got.Source, tt.wantSource)
+				}
+			}
+
+			if len(legs) != len(tt.wantEstimated) {
+				t.Fatalf("computeRoute() returned %d legs, want %d", len(legs), len(tt.wantEstimated))
+			}
+			for i, want := range tt.wantEstimated {
+				if got := legs[i][models.ProfileWalking].Estimated; got != want {
+					t.Errorf("leg %d estimated = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		status    models.TourStatus
+		publishAt *time.Time
+		archiveAt *time.Time
+		wantErr   string
+	}{
+		{name: "clearing the schedule", status: models.StatusDraft},
+		{name: "publish a draft later", status: models.StatusDraft, publishAt: at(time.Hour)},
+		{name: "publish and archive a draft later", status: models.StatusDraft, publishAt: at(time.Hour), archiveAt: at(48 * time.Hour)},
+		{name: "archive a published tour later", status: models.StatusPublished, archiveAt: at(time.Hour)},
+		{name: "publish an archived tour again", status: models.StatusArchived, publishAt: at(time.Hour)},
+		{name: "publishing a published tour", status: models.StatusPublished, publishAt: at(time.Hour), wantErr: "tour is already published"},
+		{name: "publish time now", status: models.StatusDraft, publishAt: at(0), wantErr: "publish time must be in the future"},
+		{name: "publish time passed", status: models.StatusDraft, publishAt: at(-time.Hour), wantErr: "publish time must be in the future"},
+		{name: "archive time passed", status: models.StatusPublished, archiveAt: at(-time.Minute), wantErr: "archive time must be in the future"},
+		{name: "archive with the publish", status: models.StatusDraft, publishAt: at(time.Hour), archiveAt: at(time.Hour), wantErr: "archive time must be after the publish time"},
+		{name: "archive before the publish", status: models.StatusDraft, publishAt: at(2 * time.Hour), archiveAt: at(time.Hour), wantErr: "archive time must be after the publish time"},
+		{name: "archive an unpublished draft", status: models.StatusDraft, archiveAt: at(time.Hour), wantErr: "needs a published tour or a publish time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(&models.Tour{ID: 1, Status: tt.status}, tt.publishAt, tt.archiveAt, now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateSchedule() error = %v, want none", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSchedule) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSchedule() error = %v, want ErrInvalidSchedule with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
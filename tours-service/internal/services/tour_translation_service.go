@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"tours-service/internal/models"
+)
+
+// ErrInvalidTranslation wraps every rejected translation.
+var ErrInvalidTranslation = errors.New("invalid translation")
+
+// NormalizeTranslations checks the languages of a translations map and lowercases them.
+// The default language lives in the tour's own fields, so it can't be a translation.
+func NormalizeTranslations(translations map[string]models.Translation, defaultLanguage string) (map[string]models.Translation, error) {
+	if len(translations) == 0 {
+		return nil, nil
+	}
+	normalized := make(map[string]models.Translation, len(translations))
+	for tag, translation := range translations {
+		language, ok := NormalizeLanguage(tag)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not a valid language tag", ErrInvalidTranslation, tag)
+		}
+		if language == defaultLanguage {
+			return nil, fmt.Errorf("%w: %s is the default language of the tour", ErrInvalidTranslation, language)
+		}
+		normalized[language] = translation
+	}
+	return normalized, nil
+}
+
+// GetTranslations returns every language of a tour and its keypoints.
+func (s *TourService) GetTranslations(tour *models.Tour) (*models.TourTranslations, error) {
+	keypoints, err := s.KeypointRepo.GetKeypointsByTourID(tour.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service failed to get keypoints: %w", err)
+	}
+
+	result := &models.TourTranslations{
+		TourID:       tour.ID,
+		Language:     TourLanguage(tour),
+		Translations: tour.Translations,
+		Keypoints:    make([]models.KeypointTranslations, 0, len(keypoints)),
+	}
+	if result.Translations == nil {
+		result.Translations = map[string]models.Translation{}
+	}
+	for _, keypoint := range keypoints {
+		translations := keypoint.Translations
+		if translations == nil {
+			translations = map[string]models.Translation{}
+		}
+		result.Keypoints = append(result.Keypoints, models.KeypointTranslations{
+			KeypointID:   keypoint.ID,
+			Translations: translations,
+		})
+	}
+	return result, nil
+}
+
+// SetTranslation adds or replaces one language of a tour, together with the translations
+// of the keypoints listed in the request. Keypoints left out keep what they had.
+func (s *TourService) SetTranslation(tour *models.Tour, tag string, request *models.TourTranslationRequest) (*models.TourTranslations, error) {
+	language, ok := NormalizeLanguage(tag)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a valid language tag", ErrInvalidTranslation, tag)
+	}
+	if language == TourLanguage(tour) {
+		return nil, fmt.Errorf("%w: %s is the default language, update the tour itself", ErrInvalidTranslation, language)
+	}
+	if request.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidTranslation)
+	}
+
+	if len(request.Keypoints) > 0 {
+		keypoints, err := s.KeypointRepo.GetKeypointsByTourID(tour.ID)
+		if err != nil {
+			return nil, fmt.Errorf("service failed to get keypoints: %w", err)
+		}
+		ids := make(map[int]bool, len(keypoints))
+		for _, keypoint := range keypoints {
+			ids[keypoint.ID] = true
+		}
+		for keypointID := range request.Keypoints {
+			if !ids[keypointID] {
+				return nil, fmt.Errorf("%w: keypoint %d is not part of this tour", ErrInvalidTranslation, keypointID)
+			}
+		}
+	}
+
+	updated, err := s.TourRepo.SetTranslation(tour.ID, language, models.Translation{
+		Name:        request.Name,
+		Description: request.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("service failed to set tour translation: %w", err)
+	}
+	if err := s.KeypointRepo.SetTranslations(tour.ID, language, request.Keypoints); err != nil {
+		return nil, fmt.Errorf("service failed to set keypoint translations: %w", err)
+	}
+
+	s.RevisionService.RecordOrWarn(tour.ID, tour.AuthorID, models.RevisionTranslated)
+	return s.GetTranslations(updated)
+}
+
+// DeleteTranslation removes one language from a tour and all of its keypoints.
+func (s *TourService) DeleteTranslation(tour *models.Tour, tag string) error {
+	language, ok := NormalizeLanguage(tag)
+	if !ok {
+		return fmt.Errorf("%w: %q is not a valid language tag", ErrInvalidTranslation, tag)
+	}
+
+	deleted, err := s.TourRepo.DeleteTranslation(tour.ID, language)
+	if err != nil {
+		return fmt.Errorf("service failed to delete tour translation: %w", err)
+	}
+	if !deleted {
+		return errors.New("translation not found")
+	}
+	if err := s.KeypointRepo.DeleteTranslations(tour.ID, language); err != nil {
+		return fmt.Errorf("service failed to delete keypoint translations: %w", err)
+	}
+
+	s.RevisionService.RecordOrWarn(tour.ID, tour.AuthorID, models.RevisionTranslated)
+	return nil
+}
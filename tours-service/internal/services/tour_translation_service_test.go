@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tours-service/internal/models"
+)
+
+func TestNormalizeTranslations(t *testing.T) {
+	german := models.Translation{Name: "Altstadt", Description: "Ein Spaziergang"}
+	french := models.Translation{Name: "Vieille ville", Description: "Une promenade"}
+
+	tests := []struct {
+		name         string
+		translations map[string]models.Translation
+		want         map[string]models.Translation
+		wantErr      string
+	}{
+		{name: "no translations", translations: map[string]models.Translation{}, want: nil},
+		{
+			name:         "tags are normalized",
+			translations: map[string]models.Translation{"DE": german, "fr_CA": french},
+			want:         map[string]models.Translation{"de": german, "fr-ca": french},
+		},
+		{
+			name:         "malformed tag",
+			translations: map[string]models.Translation{"de": german, "fr ca": french},
+			wantErr:      `"fr ca" is not a valid language tag`,
+		},
+		{
+			name:         "default language",
+			translations: map[string]models.Translation{"SR": german},
+			wantErr:      "sr is the default language of the tour",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTranslations(tt.translations, "sr")
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidTranslation) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NormalizeTranslations() error = %v, want ErrInvalidTranslation with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeTranslations() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTranslations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
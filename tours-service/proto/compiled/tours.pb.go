@@ -286,21 +286,28 @@ func (x *DistanceAndDuration) GetDuration() float64 {
 }
 
 type TourResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	AuthorId      int32                  `protobuf:"varint,2,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
-	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
-	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
-	Difficulty    string                 `protobuf:"bytes,5,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
-	Tags          []string               `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
-	Status        string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
-	Price         float64                `protobuf:"fixed64,8,opt,name=price,proto3" json:"price,omitempty"`
-	DrivingStats  *DistanceAndDuration   `protobuf:"bytes,9,opt,name=driving_stats,json=drivingStats,proto3" json:"driving_stats,omitempty"`
-	WalkingStats  *DistanceAndDuration   `protobuf:"bytes,10,opt,name=walking_stats,json=walkingStats,proto3" json:"walking_stats,omitempty"`
-	CyclingStats  *DistanceAndDuration   `protobuf:"bytes,11,opt,name=cycling_stats,json=cyclingStats,proto3" json:"cycling_stats,omitempty"`
-	TimePublished string                 `protobuf:"bytes,12,opt,name=time_published,json=timePublished,proto3" json:"time_published,omitempty"`
-	TimeArchived  string                 `protobuf:"bytes,13,opt,name=time_archived,json=timeArchived,proto3" json:"time_archived,omitempty"`
-	TimeDrafted   string                 `protobuf:"bytes,14,opt,name=time_drafted,json=timeDrafted,proto3" json:"time_drafted,omitempty"`
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	AuthorId           int32                  `protobuf:"varint,2,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
+	Name               string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Description        string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Difficulty         string                 `protobuf:"bytes,5,opt,name=difficulty,proto3" json:"difficulty,omitempty"`
+	Tags               []string               `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	Status             string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	Price              float64                `protobuf:"fixed64,8,opt,name=price,proto3" json:"price,omitempty"`
+	DrivingStats       *DistanceAndDuration   `protobuf:"bytes,9,opt,name=driving_stats,json=drivingStats,proto3" json:"driving_stats,omitempty"`
+	WalkingStats       *DistanceAndDuration   `protobuf:"bytes,10,opt,name=walking_stats,json=walkingStats,proto3" json:"walking_stats,omitempty"`
+	CyclingStats       *DistanceAndDuration   `protobuf:"bytes,11,opt,name=cycling_stats,json=cyclingStats,proto3" json:"cycling_stats,omitempty"`
+	TimePublished      string                 `protobuf:"bytes,12,opt,name=time_published,json=timePublished,proto3" json:"time_published,omitempty"`
+	TimeArchived       string                 `protobuf:"bytes,13,opt,name=time_archived,json=timeArchived,proto3" json:"time_archived,omitempty"`
+	TimeDrafted        string                 `protobuf:"bytes,14,opt,name=time_drafted,json=timeDrafted,proto3" json:"time_drafted,omitempty"`
+	Version            int32                  `protobuf:"varint,15,opt,name=version,proto3" json:"version,omitempty"`
+	RatingAverage      float64                `protobuf:"fixed64,16,opt,name=rating_average,json=ratingAverage,proto3" json:"rating_average,omitempty"`
+	ReviewCount        int32                  `protobuf:"varint,17,opt,name=review_count,json=reviewCount,proto3" json:"review_count,omitempty"`
+	ScheduledPublishAt string                 `protobuf:"bytes,18,opt,name=scheduled_publish_at,json=scheduledPublishAt,proto3" json:"scheduled_publish_at,omitempty"`
+	ScheduledArchiveAt string                 `protobuf:"bytes,19,opt,name=scheduled_archive_at,json=scheduledArchiveAt,proto3" json:"scheduled_archive_at,omitempty"`
+	// Language of name and description, negotiated from the accept-language metadata
+	Language      string `protobuf:"bytes,20,opt,name=language,proto3" json:"language,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -433,6 +440,48 @@ func (x *TourResponse) GetTimeDrafted() string {
 	return ""
 }
 
+func (x *TourResponse) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *TourResponse) GetRatingAverage() float64 {
+	if x != nil {
+		return x.RatingAverage
+	}
+	return 0
+}
+
+func (x *TourResponse) GetReviewCount() int32 {
+	if x != nil {
+		return x.ReviewCount
+	}
+	return 0
+}
+
+func (x *TourResponse) GetScheduledPublishAt() string {
+	if x != nil {
+		return x.ScheduledPublishAt
+	}
+	return ""
+}
+
+func (x *TourResponse) GetScheduledArchiveAt() string {
+	if x != nil {
+		return x.ScheduledArchiveAt
+	}
+	return ""
+}
+
+func (x *TourResponse) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
 type GetToursByAuthorIDRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	UserId        int32                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -566,12 +615,14 @@ func (x *GetTourByIDRequest) GetTourId() int32 {
 }
 
 type SetTourPriceRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TourId        int32                  `protobuf:"varint,1,opt,name=tour_id,json=tourId,proto3" json:"tour_id,omitempty"`
-	Price         float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
-	UserId        int32                  `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TourId int32                  `protobuf:"varint,1,opt,name=tour_id,json=tourId,proto3" json:"tour_id,omitempty"`
+	Price  float64                `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	UserId int32                  `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// When set, the price is only changed if the tour is still at this version
+	ExpectedVersion *int32 `protobuf:"varint,4,opt,name=expected_version,json=expectedVersion,proto3,oneof" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *SetTourPriceRequest) Reset() {
@@ -625,6 +676,13 @@ func (x *SetTourPriceRequest) GetUserId() int32 {
 	return 0
 }
 
+func (x *SetTourPriceRequest) GetExpectedVersion() int32 {
+	if x != nil && x.ExpectedVersion != nil {
+		return *x.ExpectedVersion
+	}
+	return 0
+}
+
 type SetTourPriceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
@@ -669,6 +727,333 @@ func (x *SetTourPriceResponse) GetMessage() string {
 	return ""
 }
 
+// The first message of an execution stream subscribes to the execution of a tour, every
+// following one reports a position.
+type ExecutionStreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ExecutionStreamRequest_Subscribe
+	//	*ExecutionStreamRequest_Position
+	Payload       isExecutionStreamRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecutionStreamRequest) Reset() {
+	*x = ExecutionStreamRequest{}
+	mi := &file_proto_tours_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionStreamRequest) ProtoMessage() {}
+
+func (x *ExecutionStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_tours_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionStreamRequest.ProtoReflect.Descriptor instead.
+func (*ExecutionStreamRequest) Descriptor() ([]byte, []int) {
+	return file_proto_tours_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ExecutionStreamRequest) GetPayload() isExecutionStreamRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ExecutionStreamRequest) GetSubscribe() *ExecutionSubscribe {
+	if x != nil {
+		if x, ok := x.Payload.(*ExecutionStreamRequest_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return nil
+}
+
+func (x *ExecutionStreamRequest) GetPosition() *PositionUpdate {
+	if x != nil {
+		if x, ok := x.Payload.(*ExecutionStreamRequest_Position); ok {
+			return x.Position
+		}
+	}
+	return nil
+}
+
+type isExecutionStreamRequest_Payload interface {
+	isExecutionStreamRequest_Payload()
+}
+
+type ExecutionStreamRequest_Subscribe struct {
+	Subscribe *ExecutionSubscribe `protobuf:"bytes,1,opt,name=subscribe,proto3,oneof"`
+}
+
+type ExecutionStreamRequest_Position struct {
+	Position *PositionUpdate `protobuf:"bytes,2,opt,name=position,proto3,oneof"`
+}
+
+func (*ExecutionStreamRequest_Subscribe) isExecutionStreamRequest_Payload() {}
+
+func (*ExecutionStreamRequest_Position) isExecutionStreamRequest_Payload() {}
+
+type ExecutionSubscribe struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	TourId int32                  `protobuf:"varint,1,opt,name=tour_id,json=tourId,proto3" json:"tour_id,omitempty"`
+	UserId int32                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Resume after this event of this execution; events of any other execution are replayed from the start
+	LastExecutionId int32 `protobuf:"varint,3,opt,name=last_execution_id,json=lastExecutionId,proto3" json:"last_execution_id,omitempty"`
+	LastEventId     int64 `protobuf:"varint,4,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ExecutionSubscribe) Reset() {
+	*x = ExecutionSubscribe{}
+	mi := &file_proto_tours_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionSubscribe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionSubscribe) ProtoMessage() {}
+
+func (x *ExecutionSubscribe) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_tours_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionSubscribe.ProtoReflect.Descriptor instead.
+func (*ExecutionSubscribe) Descriptor() ([]byte, []int) {
+	return file_proto_tours_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ExecutionSubscribe) GetTourId() int32 {
+	if x != nil {
+		return x.TourId
+	}
+	return 0
+}
+
+func (x *ExecutionSubscribe) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *ExecutionSubscribe) GetLastExecutionId() int32 {
+	if x != nil {
+		return x.LastExecutionId
+	}
+	return 0
+}
+
+func (x *ExecutionSubscribe) GetLastEventId() int64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+type PositionUpdate struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Longitude float64                `protobuf:"fixed64,1,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude  float64                `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	// Horizontal accuracy in meters
+	Accuracy *float64 `protobuf:"fixed64,3,opt,name=accuracy,proto3,oneof" json:"accuracy,omitempty"`
+	// Unix time in milliseconds
+	RecordedAt    int64 `protobuf:"varint,4,opt,name=recorded_at,json=recordedAt,proto3" json:"recorded_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PositionUpdate) Reset() {
+	*x = PositionUpdate{}
+	mi := &file_proto_tours_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PositionUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PositionUpdate) ProtoMessage() {}
+
+func (x *PositionUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_tours_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PositionUpdate.ProtoReflect.Descriptor instead.
+func (*PositionUpdate) Descriptor() ([]byte, []int) {
+	return file_proto_tours_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PositionUpdate) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *PositionUpdate) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *PositionUpdate) GetAccuracy() float64 {
+	if x != nil && x.Accuracy != nil {
+		return *x.Accuracy
+	}
+	return 0
+}
+
+func (x *PositionUpdate) GetRecordedAt() int64 {
+	if x != nil {
+		return x.RecordedAt
+	}
+	return 0
+}
+
+type ExecutionEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Sequence number within the execution, 0 for live-only events such as distance
+	Id          int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ExecutionId int32 `protobuf:"varint,2,opt,name=execution_id,json=executionId,proto3" json:"execution_id,omitempty"`
+	// distance, keypoint_reached, tour_completed, execution_abandoned or error
+	Type         string `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	KeypointId   int32  `protobuf:"varint,4,opt,name=keypoint_id,json=keypointId,proto3" json:"keypoint_id,omitempty"`
+	KeypointName string `protobuf:"bytes,5,opt,name=keypoint_name,json=keypointName,proto3" json:"keypoint_name,omitempty"`
+	// Meters to the next keypoint, for distance events
+	Distance float64 `protobuf:"fixed64,6,opt,name=distance,proto3" json:"distance,omitempty"`
+	Message  string  `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	// Unix time in milliseconds
+	CreatedAt     int64 `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecutionEvent) Reset() {
+	*x = ExecutionEvent{}
+	mi := &file_proto_tours_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionEvent) ProtoMessage() {}
+
+func (x *ExecutionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_tours_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionEvent.ProtoReflect.Descriptor instead.
+func (*ExecutionEvent) Descriptor() ([]byte, []int) {
+	return file_proto_tours_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ExecutionEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *ExecutionEvent) GetExecutionId() int32 {
+	if x != nil {
+		return x.ExecutionId
+	}
+	return 0
+}
+
+func (x *ExecutionEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ExecutionEvent) GetKeypointId() int32 {
+	if x != nil {
+		return x.KeypointId
+	}
+	return 0
+}
+
+func (x *ExecutionEvent) GetKeypointName() string {
+	if x != nil {
+		return x.KeypointName
+	}
+	return ""
+}
+
+func (x *ExecutionEvent) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *ExecutionEvent) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ExecutionEvent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
 var File_proto_tours_proto protoreflect.FileDescriptor
 
 const file_proto_tours_proto_rawDesc = "" +
@@ -694,7 +1079,7 @@ const file_proto_tours_proto_rawDesc = "" +
 	"\auser_id\x18\x03 \x01(\x05R\x06userId\"M\n" +
 	"\x13DistanceAndDuration\x12\x1a\n" +
 	"\bdistance\x18\x01 \x01(\x01R\bdistance\x12\x1a\n" +
-	"\bduration\x18\x02 \x01(\x01R\bduration\"\x85\x04\n" +
+	"\bduration\x18\x02 \x01(\x01R\bduration\"\xe9\x05\n" +
 	"\fTourResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1b\n" +
 	"\tauthor_id\x18\x02 \x01(\x05R\bauthorId\x12\x12\n" +
@@ -712,25 +1097,61 @@ const file_proto_tours_proto_rawDesc = "" +
 	"\rcycling_stats\x18\v \x01(\v2\x1a.tours.DistanceAndDurationR\fcyclingStats\x12%\n" +
 	"\x0etime_published\x18\f \x01(\tR\rtimePublished\x12#\n" +
 	"\rtime_archived\x18\r \x01(\tR\ftimeArchived\x12!\n" +
-	"\ftime_drafted\x18\x0e \x01(\tR\vtimeDrafted\"4\n" +
+	"\ftime_drafted\x18\x0e \x01(\tR\vtimeDrafted\x12\x18\n" +
+	"\aversion\x18\x0f \x01(\x05R\aversion\x12%\n" +
+	"\x0erating_average\x18\x10 \x01(\x01R\rratingAverage\x12!\n" +
+	"\freview_count\x18\x11 \x01(\x05R\vreviewCount\x120\n" +
+	"\x14scheduled_publish_at\x18\x12 \x01(\tR\x12scheduledPublishAt\x120\n" +
+	"\x14scheduled_archive_at\x18\x13 \x01(\tR\x12scheduledArchiveAt\x12\x1a\n" +
+	"\blanguage\x18\x14 \x01(\tR\blanguage\"4\n" +
 	"\x19GetToursByAuthorIDRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\x05R\x06userId\"G\n" +
 	"\x1aGetToursByAuthorIDResponse\x12)\n" +
 	"\x05tours\x18\x01 \x03(\v2\x13.tours.TourResponseR\x05tours\"-\n" +
 	"\x12GetTourByIDRequest\x12\x17\n" +
-	"\atour_id\x18\x01 \x01(\x05R\x06tourId\"]\n" +
+	"\atour_id\x18\x01 \x01(\x05R\x06tourId\"\xa2\x01\n" +
 	"\x13SetTourPriceRequest\x12\x17\n" +
 	"\atour_id\x18\x01 \x01(\x05R\x06tourId\x12\x14\n" +
 	"\x05price\x18\x02 \x01(\x01R\x05price\x12\x17\n" +
-	"\auser_id\x18\x03 \x01(\x05R\x06userId\"0\n" +
+	"\auser_id\x18\x03 \x01(\x05R\x06userId\x12.\n" +
+	"\x10expected_version\x18\x04 \x01(\x05H\x00R\x0fexpectedVersion\x88\x01\x01B\x13\n" +
+	"\x11_expected_version\"0\n" +
 	"\x14SetTourPriceResponse\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage2\xad\x02\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"\x93\x01\n" +
+	"\x16ExecutionStreamRequest\x129\n" +
+	"\tsubscribe\x18\x01 \x01(\v2\x19.tours.ExecutionSubscribeH\x00R\tsubscribe\x123\n" +
+	"\bposition\x18\x02 \x01(\v2\x15.tours.PositionUpdateH\x00R\bpositionB\t\n" +
+	"\apayload\"\x96\x01\n" +
+	"\x12ExecutionSubscribe\x12\x17\n" +
+	"\atour_id\x18\x01 \x01(\x05R\x06tourId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x05R\x06userId\x12*\n" +
+	"\x11last_execution_id\x18\x03 \x01(\x05R\x0flastExecutionId\x12\"\n" +
+	"\rlast_event_id\x18\x04 \x01(\x03R\vlastEventId\"\x99\x01\n" +
+	"\x0ePositionUpdate\x12\x1c\n" +
+	"\tlongitude\x18\x01 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\x12\x1f\n" +
+	"\baccuracy\x18\x03 \x01(\x01H\x00R\baccuracy\x88\x01\x01\x12\x1f\n" +
+	"\vrecorded_at\x18\x04 \x01(\x03R\n" +
+	"recordedAtB\v\n" +
+	"\t_accuracy\"\xf2\x01\n" +
+	"\x0eExecutionEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12!\n" +
+	"\fexecution_id\x18\x02 \x01(\x05R\vexecutionId\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1f\n" +
+	"\vkeypoint_id\x18\x04 \x01(\x05R\n" +
+	"keypointId\x12#\n" +
+	"\rkeypoint_name\x18\x05 \x01(\tR\fkeypointName\x12\x1a\n" +
+	"\bdistance\x18\x06 \x01(\x01R\bdistance\x12\x18\n" +
+	"\amessage\x18\a \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt2\xfa\x02\n" +
 	"\vTourService\x12;\n" +
 	"\n" +
 	"CreateTour\x12\x18.tours.CreateTourRequest\x1a\x13.tours.TourResponse\x12Y\n" +
 	"\x12GetToursByAuthorID\x12 .tours.GetToursByAuthorIDRequest\x1a!.tours.GetToursByAuthorIDResponse\x12=\n" +
 	"\vGetTourByID\x12\x19.tours.GetTourByIDRequest\x1a\x13.tours.TourResponse\x12G\n" +
-	"\fSetTourPrice\x12\x1a.tours.SetTourPriceRequest\x1a\x1b.tours.SetTourPriceResponseB\x10Z\x0eproto/compiledb\x06proto3"
+	"\fSetTourPrice\x12\x1a.tours.SetTourPriceRequest\x1a\x1b.tours.SetTourPriceResponse\x12K\n" +
+	"\x0fStreamExecution\x12\x1d.tours.ExecutionStreamRequest\x1a\x15.tours.ExecutionEvent(\x010\x01B\x10Z\x0eproto/compiledb\x06proto3"
 
 var (
 	file_proto_tours_proto_rawDescOnce sync.Once
@@ -744,7 +1165,7 @@ func file_proto_tours_proto_rawDescGZIP() []byte {
 	return file_proto_tours_proto_rawDescData
 }
 
-var file_proto_tours_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_proto_tours_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_proto_tours_proto_goTypes = []any{
 	(*TourForCreation)(nil),            // 0: tours.TourForCreation
 	(*KeypointForCreation)(nil),        // 1: tours.KeypointForCreation
@@ -756,6 +1177,10 @@ var file_proto_tours_proto_goTypes = []any{
 	(*GetTourByIDRequest)(nil),         // 7: tours.GetTourByIDRequest
 	(*SetTourPriceRequest)(nil),        // 8: tours.SetTourPriceRequest
 	(*SetTourPriceResponse)(nil),       // 9: tours.SetTourPriceResponse
+	(*ExecutionStreamRequest)(nil),     // 10: tours.ExecutionStreamRequest
+	(*ExecutionSubscribe)(nil),         // 11: tours.ExecutionSubscribe
+	(*PositionUpdate)(nil),             // 12: tours.PositionUpdate
+	(*ExecutionEvent)(nil),             // 13: tours.ExecutionEvent
 }
 var file_proto_tours_proto_depIdxs = []int32{
 	0,  // 0: tours.CreateTourRequest.tour:type_name -> tours.TourForCreation
@@ -764,19 +1189,23 @@ var file_proto_tours_proto_depIdxs = []int32{
 	3,  // 3: tours.TourResponse.walking_stats:type_name -> tours.DistanceAndDuration
 	3,  // 4: tours.TourResponse.cycling_stats:type_name -> tours.DistanceAndDuration
 	4,  // 5: tours.GetToursByAuthorIDResponse.tours:type_name -> tours.TourResponse
-	2,  // 6: tours.TourService.CreateTour:input_type -> tours.CreateTourRequest
-	5,  // 7: tours.TourService.GetToursByAuthorID:input_type -> tours.GetToursByAuthorIDRequest
-	7,  // 8: tours.TourService.GetTourByID:input_type -> tours.GetTourByIDRequest
-	8,  // 9: tours.TourService.SetTourPrice:input_type -> tours.SetTourPriceRequest
-	4,  // 10: tours.TourService.CreateTour:output_type -> tours.TourResponse
-	6,  // 11: tours.TourService.GetToursByAuthorID:output_type -> tours.GetToursByAuthorIDResponse
-	4,  // 12: tours.TourService.GetTourByID:output_type -> tours.TourResponse
-	9,  // 13: tours.TourService.SetTourPrice:output_type -> tours.SetTourPriceResponse
-	10, // [10:14] is the sub-list for method output_type
-	6,  // [6:10] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	11, // 6: tours.ExecutionStreamRequest.subscribe:type_name -> tours.ExecutionSubscribe
+	12, // 7: tours.ExecutionStreamRequest.position:type_name -> tours.PositionUpdate
+	2,  // 8: tours.TourService.CreateTour:input_type -> tours.CreateTourRequest
+	5,  // 9: tours.TourService.GetToursByAuthorID:input_type -> tours.GetToursByAuthorIDRequest
+	7,  // 10: tours.TourService.GetTourByID:input_type -> tours.GetTourByIDRequest
+	8,  // 11: tours.TourService.SetTourPrice:input_type -> tours.SetTourPriceRequest
+	10, // 12: tours.TourService.StreamExecution:input_type -> tours.ExecutionStreamRequest
+	4,  // 13: tours.TourService.CreateTour:output_type -> tours.TourResponse
+	6,  // 14: tours.TourService.GetToursByAuthorID:output_type -> tours.GetToursByAuthorIDResponse
+	4,  // 15: tours.TourService.GetTourByID:output_type -> tours.TourResponse
+	9,  // 16: tours.TourService.SetTourPrice:output_type -> tours.SetTourPriceResponse
+	13, // 17: tours.TourService.StreamExecution:output_type -> tours.ExecutionEvent
+	13, // [13:18] is the sub-list for method output_type
+	8,  // [8:13] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_proto_tours_proto_init() }
@@ -784,13 +1213,19 @@ func file_proto_tours_proto_init() {
 	if File_proto_tours_proto != nil {
 		return
 	}
+	file_proto_tours_proto_msgTypes[8].OneofWrappers = []any{}
+	file_proto_tours_proto_msgTypes[10].OneofWrappers = []any{
+		(*ExecutionStreamRequest_Subscribe)(nil),
+		(*ExecutionStreamRequest_Position)(nil),
+	}
+	file_proto_tours_proto_msgTypes[12].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_tours_proto_rawDesc), len(file_proto_tours_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -23,6 +23,7 @@ const (
 	TourService_GetToursByAuthorID_FullMethodName = "/tours.TourService/GetToursByAuthorID"
 	TourService_GetTourByID_FullMethodName        = "/tours.TourService/GetTourByID"
 	TourService_SetTourPrice_FullMethodName       = "/tours.TourService/SetTourPrice"
+	TourService_StreamExecution_FullMethodName    = "/tours.TourService/StreamExecution"
 )
 
 // TourServiceClient is the client API for TourService service.
@@ -33,6 +34,7 @@ type TourServiceClient interface {
 	GetToursByAuthorID(ctx context.Context, in *GetToursByAuthorIDRequest, opts ...grpc.CallOption) (*GetToursByAuthorIDResponse, error)
 	GetTourByID(ctx context.Context, in *GetTourByIDRequest, opts ...grpc.CallOption) (*TourResponse, error)
 	SetTourPrice(ctx context.Context, in *SetTourPriceRequest, opts ...grpc.CallOption) (*SetTourPriceResponse, error)
+	StreamExecution(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecutionStreamRequest, ExecutionEvent], error)
 }
 
 type tourServiceClient struct {
@@ -83,6 +85,19 @@ func (c *tourServiceClient) SetTourPrice(ctx context.Context, in *SetTourPriceRe
 	return out, nil
 }
 
+func (c *tourServiceClient) StreamExecution(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecutionStreamRequest, ExecutionEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &TourService_ServiceDesc.Streams[0], TourService_StreamExecution_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecutionStreamRequest, ExecutionEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TourService_StreamExecutionClient = grpc.BidiStreamingClient[ExecutionStreamRequest, ExecutionEvent]
+
 // TourServiceServer is the server API for TourService service.
 // All implementations must embed UnimplementedTourServiceServer
 // for forward compatibility.
@@ -91,6 +106,7 @@ type TourServiceServer interface {
 	GetToursByAuthorID(context.Context, *GetToursByAuthorIDRequest) (*GetToursByAuthorIDResponse, error)
 	GetTourByID(context.Context, *GetTourByIDRequest) (*TourResponse, error)
 	SetTourPrice(context.Context, *SetTourPriceRequest) (*SetTourPriceResponse, error)
+	StreamExecution(grpc.BidiStreamingServer[ExecutionStreamRequest, ExecutionEvent]) error
 	mustEmbedUnimplementedTourServiceServer()
 }
 
@@ -113,6 +129,9 @@ func (UnimplementedTourServiceServer) GetTourByID(context.Context, *GetTourByIDR
 func (UnimplementedTourServiceServer) SetTourPrice(context.Context, *SetTourPriceRequest) (*SetTourPriceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetTourPrice not implemented")
 }
+func (UnimplementedTourServiceServer) StreamExecution(grpc.BidiStreamingServer[ExecutionStreamRequest, ExecutionEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamExecution not implemented")
+}
 func (UnimplementedTourServiceServer) mustEmbedUnimplementedTourServiceServer() {}
 func (UnimplementedTourServiceServer) testEmbeddedByValue()                     {}
 
@@ -206,6 +225,13 @@ func _TourService_SetTourPrice_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TourService_StreamExecution_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TourServiceServer).StreamExecution(&grpc.GenericServerStream[ExecutionStreamRequest, ExecutionEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type TourService_StreamExecutionServer = grpc.BidiStreamingServer[ExecutionStreamRequest, ExecutionEvent]
+
 // TourService_ServiceDesc is the grpc.ServiceDesc for TourService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,6 +256,13 @@ var TourService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _TourService_SetTourPrice_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamExecution",
+			Handler:       _TourService_StreamExecution_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "proto/tours.proto",
 }